@@ -7,9 +7,15 @@ import (
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"github.com/cedana/cedana/pkg/client"
 	"github.com/cedana/cedana/pkg/config"
+	"github.com/cedana/cedana/pkg/flags"
 	"github.com/spf13/cobra"
 )
 
+func init() {
+	attachCmd.Flags().Bool(flags.ReadOnlyFlag.Full, false, "only observe output, without sending input")
+	attachCmd.Flags().Int(flags.TailFlag.Full, 0, "number of lines of earlier output to show first (0 for all, -1 for none)")
+}
+
 // Parent attach command
 var attachCmd = &cobra.Command{
 	Use:               "attach <PID>",
@@ -27,6 +33,13 @@ var attachCmd = &cobra.Command{
 			return fmt.Errorf("invalid pid: %v", err)
 		}
 
-		return client.Attach(cmd.Context(), &daemon.AttachReq{PID: uint32(pid)})
+		readOnly, _ := cmd.Flags().GetBool(flags.ReadOnlyFlag.Full)
+		tail, _ := cmd.Flags().GetInt(flags.TailFlag.Full)
+
+		return client.Attach(cmd.Context(), &daemon.AttachReq{
+			PID:      uint32(pid),
+			ReadOnly: readOnly,
+			Tail:     int32(tail),
+		})
 	},
 }
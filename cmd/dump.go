@@ -42,11 +42,16 @@ func init() {
 		String(flags.ResultFileFlag.Full, "", "write the dump result (DumpResp) as JSON to this file")
 	dumpCmd.MarkPersistentFlagDirname(flags.DirFlag.Full)
 	dumpCmd.PersistentFlags().
-		StringP(flags.CompressionFlag.Full, flags.CompressionFlag.Short, "", "compression algorithm (none, tar, gzip, lz4, zlib)")
+		StringP(flags.CompressionFlag.Full, flags.CompressionFlag.Short, "", "compression algorithm (none, tar, gzip, lz4, zlib, zstd)")
 	dumpCmd.PersistentFlags().
 		Int32P(flags.StreamsFlag.Full, flags.StreamsFlag.Short, 0, "number of streams to use for dump (0 for no streaming)")
 	dumpCmd.PersistentFlags().
 		StringP(flags.CriuOptsFlag.Full, flags.CriuOptsFlag.Short, "", "criu options JSON (overriddes individual CRIU flags)")
+	dumpCmd.PersistentFlags().
+		Int32P(flags.PreDumpFlag.Full, "", 0, "number of iterative pre-dumps (with memory tracking) to run before the final dump")
+	dumpCmd.PersistentFlags().Lookup(flags.PreDumpFlag.Full).NoOptDefVal = "1"
+	dumpCmd.PersistentFlags().
+		StringP(flags.ParentFlag.Full, "", "", "ID of a previous checkpoint to dump incrementally on top of")
 	dumpCmd.PersistentFlags().
 		BoolP(flags.LeaveRunningFlag.Full, flags.LeaveRunningFlag.Short, false, "leave the process running after dump")
 	dumpCmd.PersistentFlags().
@@ -96,6 +101,9 @@ var dumpCmd = &cobra.Command{
 		name, _ := cmd.Flags().GetString(flags.NameFlag.Full)
 		compression, _ := cmd.Flags().GetString(flags.CompressionFlag.Full)
 		streams, _ := cmd.Flags().GetInt32(flags.StreamsFlag.Full)
+		preDumps, _ := cmd.Flags().GetInt32(flags.PreDumpFlag.Full)
+		parentID, _ := cmd.Flags().GetString(flags.ParentFlag.Full)
+		noServer, _ := cmd.Flags().GetBool(flags.NoServerFlag.Full)
 
 		external, _ := cmd.Flags().GetStringSlice(flags.ExternalFlag.Full)
 		shellJob, _ := cmd.Flags().GetBool(flags.ShellJobFlag.Full)
@@ -122,6 +130,10 @@ var dumpCmd = &cobra.Command{
 			}
 		}
 
+		if noServer && parentID != "" {
+			return fmt.Errorf("`--%s` is not supported when using `--%s`", flags.ParentFlag.Full, flags.NoServerFlag.Full)
+		}
+
 		// Create half-baked request
 		req := &daemon.DumpReq{
 			Dir:         dir,
@@ -130,6 +142,8 @@ var dumpCmd = &cobra.Command{
 			Streams:     int32(streams),
 			Criu:        criuOpts,
 			Action:      daemon.DumpAction_DUMP,
+			PreDumps:    preDumps,
+			ParentID:    parentID,
 		}
 
 		ctx := context.WithValue(cmd.Context(), keys.DUMP_REQ_CONTEXT_KEY, req)
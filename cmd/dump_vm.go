@@ -12,13 +12,19 @@ import (
 	"github.com/cedana/cedana/pkg/keys"
 	"github.com/cedana/cedana/pkg/profiling"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/proto"
 )
 
 func init() {
+	dumpVMCmd.AddCommand(jobDumpVMCmd)
+
 	// Add common flags
 	dumpVMCmd.PersistentFlags().
 		StringP(flags.DirFlag.Full, flags.DirFlag.Short, "", "directory to dump into")
 	dumpVMCmd.MarkPersistentFlagDirname(flags.DirFlag.Full)
+	dumpVMCmd.PersistentFlags().
+		StringP(flags.JidFlag.Full, flags.JidFlag.Short, "", "job id to manage the VM as (created if it does not exist)")
 
 	///////////////////////////////////////////
 	// Add subcommands from supported plugins
@@ -28,17 +34,17 @@ func init() {
 		func(name string, pluginCmd *cobra.Command) error {
 			dumpVMCmd.AddCommand(pluginCmd)
 
-			// TODO: Uncomment below once jobDumpVMCmd is defined
-
 			// Apply all the flags from the plugin command to job subcommand (as optional flags),
-			// since the job subcommand can be used to dump any managed entity (even from plugins, like runc),
+			// since the job subcommand can be used to dump any managed VM (from plugins, like cloud-hypervisor),
 			// thus it could have specific CLI overrides from plugins.
 
-			// (*pluginCmd).Flags().VisitAll(func(f *pflag.Flag) {
-			// 	newFlag := *f
-			// 	jobDumpVMCmd.Flags().AddFlag(&newFlag)
-			// 	newFlag.Usage = fmt.Sprintf("(%s) %s", name, f.Usage) // Add plugin name to usage
-			// })
+			(*pluginCmd).Flags().VisitAll(func(f *pflag.Flag) {
+				newFlag := *f
+				if jobDumpVMCmd.Flags().Lookup(newFlag.Name) == nil {
+					jobDumpVMCmd.Flags().AddFlag(&newFlag)
+				}
+				newFlag.Usage = fmt.Sprintf("(%s) %s", name, f.Usage) // Add plugin name to usage
+			})
 			return nil
 		},
 	)
@@ -50,9 +56,13 @@ var dumpVMCmd = &cobra.Command{
 	Args:  cobra.ArbitraryArgs,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		dir, _ := cmd.Flags().GetString(flags.DirFlag.Full)
+		jid, _ := cmd.Flags().GetString(flags.JidFlag.Full)
 
 		// Create half-baked request
 		req := &daemon.DumpVMReq{Dir: dir}
+		if jid != "" {
+			req.Details = &daemon.Details{JID: proto.String(jid)}
+		}
 
 		ctx := context.WithValue(cmd.Context(), keys.DUMP_REQ_CONTEXT_KEY, req)
 		cmd.SetContext(ctx)
@@ -102,3 +112,52 @@ var dumpVMCmd = &cobra.Command{
 		return nil
 	},
 }
+
+////////////////////
+/// Subcommands  ///
+////////////////////
+
+var jobDumpVMCmd = &cobra.Command{
+	Use:               "job <JID>",
+	Short:             "Dump a managed VM (job)",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: RunningJIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+
+		jid := args[0]
+
+		// Get the job type, so we can call the plugin command to override request details
+		resp, err := client.Get(cmd.Context(), &daemon.GetReq{JID: jid})
+		if err != nil {
+			return err
+		}
+		jobType := resp.GetJob().GetType()
+
+		err = features.DumpVMCmd.IfAvailable(
+			func(name string, pluginCmd *cobra.Command) error {
+				// Call the plugin command to override request details
+				return pluginCmd.RunE(cmd, nil) // don't pass any args
+			}, jobType,
+		)
+		if err != nil {
+			return err
+		}
+
+		// Since the request details have been modified by the plugin command, we need to fetch it
+		req, ok := cmd.Context().Value(keys.DUMP_REQ_CONTEXT_KEY).(*daemon.DumpVMReq)
+		if !ok {
+			return fmt.Errorf("invalid dump request in context")
+		}
+
+		if req.Details == nil {
+			req.Details = &daemon.Details{}
+		}
+		req.Details.JID = proto.String(jid)
+
+		return nil
+	},
+}
@@ -2,14 +2,22 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/xeonx/timeago"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/internal/cedana/inspect"
+	"github.com/cedana/cedana/internal/cedana/job"
 	"github.com/cedana/cedana/pkg/client"
 	"github.com/cedana/cedana/pkg/config"
 	"github.com/cedana/cedana/pkg/features"
@@ -17,10 +25,10 @@ import (
 	"github.com/cedana/cedana/pkg/keys"
 	"github.com/cedana/cedana/pkg/style"
 	"github.com/cedana/cedana/pkg/utils"
-	"github.com/cedana/go-criu/v7/crit"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"gopkg.in/yaml.v3"
 )
@@ -32,15 +40,57 @@ func init() {
 	jobCmd.AddCommand(attachJobCmd)
 	jobCmd.AddCommand(inspectJobCmd)
 	jobCmd.AddCommand(jobCheckpointCmd)
+	jobCmd.AddCommand(scheduleJobCmd)
+	jobCmd.AddCommand(unscheduleJobCmd)
+	jobCmd.AddCommand(listJobScheduleCmd)
+	jobCmd.AddCommand(eventsJobCmd)
 
 	jobCheckpointCmd.AddCommand(listJobCheckpointCmd)
 	jobCheckpointCmd.AddCommand(inspectJobCheckpointCmd)
+	jobCheckpointCmd.AddCommand(diffJobCheckpointCmd)
+	jobCheckpointCmd.AddCommand(gcJobCheckpointCmd)
+	jobCheckpointCmd.AddCommand(exportJobCheckpointCmd)
+	jobCheckpointCmd.AddCommand(importJobCheckpointCmd)
 
 	// Add subcommand flags
 	listJobCmd.Flags().BoolP(flags.AllFlag.Full, flags.AllFlag.Short, false, "include jobs from remote hosts")
 	deleteJobCmd.Flags().BoolP(flags.AllFlag.Full, flags.AllFlag.Short, false, "delete all jobs")
 	killJobCmd.Flags().BoolP(flags.AllFlag.Full, flags.AllFlag.Short, false, "kill all jobs")
-	inspectJobCheckpointCmd.Flags().StringP(flags.TypeFlag.Full, flags.TypeFlag.Short, "", "specify image file {ps|fd|mem|rss|sk|gpu}")
+	killJobCmd.Flags().
+		StringP(flags.SignalFlag.Full, flags.SignalFlag.Short, "", "signal to send instead of the default (checkpoints jobs that asked for it first)")
+	attachJobCmd.Flags().Bool(flags.ReadOnlyFlag.Full, false, "only observe output, without sending input")
+	attachJobCmd.Flags().Int(flags.TailFlag.Full, 0, "number of lines of earlier output to show first (0 for all, -1 for none)")
+	inspectJobCheckpointCmd.Flags().StringP(flags.TypeFlag.Full, flags.TypeFlag.Short, "", "only inspect the specified view {ps|fd|mem|rss|sk|gpu}")
+	inspectJobCheckpointCmd.Flags().BoolP(flags.JsonFlag.Full, "", false, "output as JSON")
+	inspectJobCheckpointCmd.Flags().BoolP(flags.YamlFlag.Full, "", false, "output as YAML")
+	inspectJobCheckpointCmd.MarkFlagsMutuallyExclusive(flags.JsonFlag.Full, flags.YamlFlag.Full)
+	diffJobCheckpointCmd.Flags().BoolP(flags.JsonFlag.Full, "", false, "output as JSON")
+	diffJobCheckpointCmd.Flags().BoolP(flags.YamlFlag.Full, "", false, "output as YAML")
+	diffJobCheckpointCmd.MarkFlagsMutuallyExclusive(flags.JsonFlag.Full, flags.YamlFlag.Full)
+	scheduleJobCmd.Flags().DurationP(flags.EveryFlag.Full, "", 0, "interval between checkpoints (e.g. 10m)")
+	scheduleJobCmd.Flags().Int32P(flags.KeepFlag.Full, "", 0, "number of latest scheduled checkpoints to keep (0 keeps all)")
+	scheduleJobCmd.Flags().StringP(flags.DirFlag.Full, flags.DirFlag.Short, "", "directory to dump into")
+	scheduleJobCmd.Flags().StringP(flags.CompressionFlag.Full, "", "", "compression algorithm (none, tar, gzip, lz4, zlib, zstd)")
+	scheduleJobCmd.MarkFlagRequired(flags.EveryFlag.Full)
+	unscheduleJobCmd.Flags().BoolP(flags.AllFlag.Full, flags.AllFlag.Short, false, "unschedule all jobs")
+	eventsJobCmd.Flags().BoolP(flags.FollowFlag.Full, flags.FollowFlag.Short, false, "keep streaming new events")
+	eventsJobCmd.Flags().BoolP(flags.JsonFlag.Full, "", false, "output each event as a line of JSON")
+	eventsJobCmd.Flags().
+		StringSliceP(flags.TypeFlag.Full, flags.TypeFlag.Short, nil, "only show events of the given types (created, started, exited, restored, deleted, checkpoint-started, checkpoint-completed, checkpoint-failed)")
+	gcJobCheckpointCmd.Flags().BoolP(flags.DryRunFlag.Full, "", false, "only list checkpoints that would be collected")
+	gcJobCheckpointCmd.Flags().Int32P(flags.MaxCountFlag.Full, "", 0, "max number of checkpoints to keep per job (overrides config)")
+	gcJobCheckpointCmd.Flags().DurationP(flags.MaxAgeFlag.Full, "", 0, "max age of a checkpoint, e.g. 72h (overrides config)")
+	gcJobCheckpointCmd.Flags().Int64P(flags.MaxBytesFlag.Full, "", 0, "max total bytes of checkpoints per storage dir (overrides config)")
+	exportJobCheckpointCmd.Flags().StringP(flags.OCIFlag.Full, "", "", "registry reference, or path of an OCI image layout directory, to export to")
+	exportJobCheckpointCmd.Flags().StringP(flags.UsernameFlag.Full, "", "", "registry username")
+	exportJobCheckpointCmd.Flags().StringP(flags.SecretFlag.Full, "", "", "registry password or token")
+	exportJobCheckpointCmd.MarkFlagRequired(flags.OCIFlag.Full)
+	importJobCheckpointCmd.Flags().StringP(flags.OCIFlag.Full, "", "", "registry reference, or path of an OCI image layout directory, to import from")
+	importJobCheckpointCmd.Flags().StringP(flags.JidFlag.Full, flags.JidFlag.Short, "", "job to import the checkpoint for (default: original job)")
+	importJobCheckpointCmd.Flags().StringP(flags.DirFlag.Full, flags.DirFlag.Short, "", "directory to import into (default: configured checkpoint dir)")
+	importJobCheckpointCmd.Flags().StringP(flags.UsernameFlag.Full, "", "", "registry username")
+	importJobCheckpointCmd.Flags().StringP(flags.SecretFlag.Full, "", "", "registry password or token")
+	importJobCheckpointCmd.MarkFlagRequired(flags.OCIFlag.Full)
 
 	// Add aliases
 	jobCmd.AddCommand(utils.AliasOf(listJobCheckpointCmd, "checkpoints"))
@@ -50,6 +100,8 @@ func init() {
 	rootCmd.AddCommand(utils.AliasOf(killJobCmd))
 	rootCmd.AddCommand(utils.AliasOf(jobCheckpointCmd))
 	rootCmd.AddCommand(utils.AliasOf(listJobCheckpointCmd, "checkpoints"))
+	rootCmd.AddCommand(utils.AliasOf(listJobScheduleCmd))
+	rootCmd.AddCommand(utils.AliasOf(eventsJobCmd))
 }
 
 // Parent job command
@@ -216,6 +268,14 @@ var killJobCmd = &cobra.Command{
 
 		req := &daemon.KillReq{}
 
+		if signalStr, _ := cmd.Flags().GetString(flags.SignalFlag.Full); signalStr != "" {
+			signal, err := utils.ParseSignal(signalStr)
+			if err != nil {
+				return err
+			}
+			req.Signal = int32(signal)
+		}
+
 		if len(jids) > 0 {
 			req.JIDs = jids
 		} else {
@@ -297,7 +357,14 @@ var attachJobCmd = &cobra.Command{
 
 		pid := job.GetState().GetPID()
 
-		return client.Attach(cmd.Context(), &daemon.AttachReq{PID: pid})
+		readOnly, _ := cmd.Flags().GetBool(flags.ReadOnlyFlag.Full)
+		tail, _ := cmd.Flags().GetInt(flags.TailFlag.Full)
+
+		return client.Attach(cmd.Context(), &daemon.AttachReq{
+			PID:      pid,
+			ReadOnly: readOnly,
+			Tail:     int32(tail),
+		})
 	},
 }
 
@@ -332,6 +399,191 @@ var inspectJobCmd = &cobra.Command{
 	},
 }
 
+///////////////////////////
+//// Schedule Commands ////
+///////////////////////////
+
+var scheduleJobCmd = &cobra.Command{
+	Use:               "schedule <JID>",
+	Short:             "Periodically checkpoint a managed process/container (job)",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: RunningJIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+
+		every, _ := cmd.Flags().GetDuration(flags.EveryFlag.Full)
+		keep, _ := cmd.Flags().GetInt32(flags.KeepFlag.Full)
+		dir, _ := cmd.Flags().GetString(flags.DirFlag.Full)
+		compression, _ := cmd.Flags().GetString(flags.CompressionFlag.Full)
+
+		if every < time.Second {
+			return fmt.Errorf("Interval must be at least 1s")
+		}
+		if dir == "" {
+			dir = config.Global.Checkpoint.Dir
+		}
+		if compression == "" {
+			compression = config.Global.Checkpoint.Compression
+		}
+
+		resp, err := client.Schedule(cmd.Context(), &daemon.ScheduleReq{
+			Schedule: &daemon.Schedule{
+				JID:         args[0],
+				Interval:    every.Milliseconds(),
+				Keep:        keep,
+				Dir:         dir,
+				Compression: compression,
+			},
+		})
+
+		for _, message := range resp.GetMessages() {
+			fmt.Println(message)
+		}
+
+		return err
+	},
+}
+
+var unscheduleJobCmd = &cobra.Command{
+	Use:               "unschedule <JID>...",
+	Short:             "Stop periodically checkpointing a managed process/container (job)",
+	Args:              cobra.ArbitraryArgs,
+	ValidArgsFunction: ValidJIDs,
+	RunE: func(cmd *cobra.Command, jids []string) error {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+
+		req := &daemon.UnscheduleReq{}
+
+		if len(jids) > 0 {
+			req.JIDs = jids
+		} else {
+			// Check if the all flag is set
+			all, _ := cmd.Flags().GetBool(flags.AllFlag.Full)
+			if !all {
+				return fmt.Errorf("Please provide at least one JID or use the --all flag")
+			}
+		}
+
+		resp, err := client.Unschedule(cmd.Context(), req)
+
+		for _, message := range resp.GetMessages() {
+			fmt.Println(message)
+		}
+
+		return err
+	},
+}
+
+var listJobScheduleCmd = &cobra.Command{
+	Use:   "schedules",
+	Short: "List all checkpoint schedules",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+
+		resp, err := client.ListSchedules(cmd.Context(), &daemon.ListSchedulesReq{})
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Schedules) == 0 {
+			fmt.Println("No schedules found")
+			return nil
+		}
+
+		tableWriter := table.NewWriter()
+		tableWriter.SetStyle(style.TableStyle)
+		tableWriter.SetOutputMirror(os.Stdout)
+
+		tableWriter.AppendHeader(table.Row{
+			"Job",
+			"Every",
+			"Keep",
+			"Dir",
+			"Compression",
+		})
+
+		for _, schedule := range resp.GetSchedules() {
+			keep := "all"
+			if schedule.GetKeep() > 0 {
+				keep = fmt.Sprint(schedule.GetKeep())
+			}
+			row := table.Row{
+				schedule.GetJID(),
+				time.Duration(schedule.GetInterval()) * time.Millisecond,
+				keep,
+				schedule.GetDir(),
+				schedule.GetCompression(),
+			}
+			tableWriter.AppendRow(row)
+		}
+
+		tableWriter.Render()
+
+		return nil
+	},
+}
+
+////////////////////////
+//// Event Commands ////
+////////////////////////
+
+var eventsJobCmd = &cobra.Command{
+	Use:               "events [JID]...",
+	Short:             "Show recent job events, or follow new ones",
+	Long:              "Show recent job events (created, started, exited, restored, deleted, and checkpoints), optionally filtered by job. With --follow, keeps streaming new events as they happen.",
+	Args:              cobra.ArbitraryArgs,
+	ValidArgsFunction: ValidJIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+
+		follow, _ := cmd.Flags().GetBool(flags.FollowFlag.Full)
+		asJson, _ := cmd.Flags().GetBool(flags.JsonFlag.Full)
+		types, _ := cmd.Flags().GetStringSlice(flags.TypeFlag.Full)
+
+		stream, err := client.WatchEvents(cmd.Context(), &daemon.WatchEventsReq{
+			JIDs:   args,
+			Types:  types,
+			Follow: follow,
+		})
+		if err != nil {
+			return err
+		}
+
+		for {
+			event, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return utils.GRPCErrorColored(err)
+			}
+
+			if asJson {
+				data, err := protojson.Marshal(event)
+				if err != nil {
+					return fmt.Errorf("failed to marshal event: %w", err)
+				}
+				fmt.Println(string(data))
+				continue
+			}
+
+			printEvent(event)
+		}
+	},
+}
+
 /////////////////////////////
 //// Checkpoint Commands ////
 /////////////////////////////
@@ -373,6 +625,7 @@ var listJobCheckpointCmd = &cobra.Command{
 			"Time",
 			"Size",
 			"Path",
+			"Parent",
 		})
 
 		checkpoints := resp.GetCheckpoints()
@@ -387,6 +640,7 @@ var listJobCheckpointCmd = &cobra.Command{
 				timestamp.Format(time.DateTime),
 				utils.SizeStr(checkpoint.GetSize()),
 				checkpoint.GetPath(),
+				checkpoint.GetParentID(),
 			}
 			tableWriter.AppendRow(row)
 		}
@@ -405,6 +659,7 @@ var (
 	inspectJobCheckpointCmd    = &cobra.Command{
 		Use:   inspectJobCheckpointCmdUse,
 		Short: "Inspect a checkpoint",
+		Long:  "Inspect the images of a checkpoint, along with the saved process state. Works with compressed and remote checkpoints, as inspection is done by the daemon.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
@@ -413,28 +668,371 @@ var (
 			}
 
 			id := args[0]
+			t, _ := cmd.Flags().GetString(flags.TypeFlag.Full)
+			asJson, _ := cmd.Flags().GetBool(flags.JsonFlag.Full)
+			asYaml, _ := cmd.Flags().GetBool(flags.YamlFlag.Full)
 
-			resp, err := client.GetCheckpoint(cmd.Context(), &daemon.GetCheckpointReq{ID: proto.String(id)})
-			if err != nil {
-				return err
+			req := &daemon.InspectCheckpointReq{ID: id}
+			if t != "" {
+				req.Types = []string{t}
 			}
 
-			image := crit.New(nil, nil, resp.GetCheckpoint().GetPath(), true, true)
-
-			fds, err := image.ExploreFds()
+			resp, err := client.InspectCheckpoint(cmd.Context(), req)
 			if err != nil {
 				return err
 			}
 
-			for _, fd := range fds {
-				for _, file := range fd.Files {
-					fmt.Println(file.Path)
+			output := struct {
+				Checkpoint *daemon.Checkpoint   `json:"checkpoint" yaml:"checkpoint"`
+				State      *daemon.ProcessState `json:"state,omitempty" yaml:"state,omitempty"`
+				Views      map[string]any       `json:"views" yaml:"views"`
+			}{
+				Checkpoint: resp.GetCheckpoint(),
+				State:      resp.GetState(),
+				Views:      make(map[string]any),
+			}
+			for name, data := range resp.GetViews() {
+				var view any
+				if err := json.Unmarshal([]byte(data), &view); err != nil {
+					return fmt.Errorf("Error unmarshalling %s view: %v", name, err)
 				}
+				output.Views[name] = view
 			}
 
-			// TODO: Move checkpoint inespection to daemon, to allow inspecting compressed or even streamable checkpoints
+			switch {
+			case asJson:
+				bytes, err := json.MarshalIndent(output, "", "  ")
+				if err != nil {
+					return fmt.Errorf("Error marshalling checkpoint: %v", err)
+				}
+				fmt.Println(string(bytes))
+			case asYaml:
+				bytes, err := yaml.Marshal(output)
+				if err != nil {
+					return fmt.Errorf("Error marshalling checkpoint: %v", err)
+				}
+				fmt.Print(string(bytes))
+			default:
+				names := slices.Sorted(maps.Keys(output.Views))
+				for _, name := range names {
+					bytes, err := yaml.Marshal(output.Views[name])
+					if err != nil {
+						return fmt.Errorf("Error marshalling %s view: %v", name, err)
+					}
+					fmt.Println(style.InfoColors.Sprint(strings.ToUpper(name)))
+					fmt.Print(string(bytes))
+					fmt.Println()
+				}
+				for _, message := range resp.GetMessages() {
+					fmt.Println(style.DisabledColors.Sprint(message))
+				}
+			}
 
 			return nil
 		},
 	}
 )
+
+var diffJobCheckpointCmd = &cobra.Command{
+	Use:   "diff <checkpoint-id> <checkpoint-id>",
+	Short: "Compare two checkpoints of a job",
+	Long:  "Compare two checkpoints of the same job. Shows changes in the process tree (with cmdlines, if saved), open files, sockets, memory mappings, resident pages per VMA, and GPU image sizes.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+
+		asJson, _ := cmd.Flags().GetBool(flags.JsonFlag.Full)
+		asYaml, _ := cmd.Flags().GetBool(flags.YamlFlag.Full)
+
+		var checkpoints [2]*daemon.Checkpoint
+		var views [2]*inspect.Views
+
+		for i, id := range args {
+			resp, err := client.InspectCheckpoint(cmd.Context(), &daemon.InspectCheckpointReq{ID: id})
+			if err != nil {
+				return err
+			}
+			for _, message := range resp.GetMessages() {
+				fmt.Fprintln(os.Stderr, style.DisabledColors.Sprintf("%s: %s", id, message))
+			}
+			checkpoints[i] = resp.GetCheckpoint()
+			views[i], err = inspect.ParseViews(resp.GetViews(), resp.GetState())
+			if err != nil {
+				return fmt.Errorf("Error parsing checkpoint %s: %v", id, err)
+			}
+		}
+
+		if checkpoints[0].GetJID() != checkpoints[1].GetJID() {
+			return fmt.Errorf("Checkpoints belong to different jobs (%s and %s)", checkpoints[0].GetJID(), checkpoints[1].GetJID())
+		}
+
+		diff := inspect.Compare(views[0], views[1])
+
+		switch {
+		case asJson:
+			bytes, err := json.MarshalIndent(diff, "", "  ")
+			if err != nil {
+				return fmt.Errorf("Error marshalling diff: %v", err)
+			}
+			fmt.Println(string(bytes))
+			return nil
+		case asYaml:
+			bytes, err := yaml.Marshal(diff)
+			if err != nil {
+				return fmt.Errorf("Error marshalling diff: %v", err)
+			}
+			fmt.Print(string(bytes))
+			return nil
+		}
+
+		printChanges("PROCESSES", diff.Processes)
+		printChanges("FILES", diff.Files)
+		printChanges("SOCKETS", diff.Sockets)
+		printChanges("MAPPINGS", diff.Mappings)
+		printCountChanges("PAGES", diff.Pages, func(n int64) string { return fmt.Sprintf("%d", n) })
+		printCountChanges("GPU", diff.GPU, sizeStr)
+
+		fmt.Println(style.InfoColors.Sprint("TOTAL"))
+		fmt.Printf("size: %s → %s\n", sizeStr(checkpoints[0].GetSize()), sizeStr(checkpoints[1].GetSize()))
+		fmt.Printf("pages: %d → %d\n", diff.TotalPages.Before, diff.TotalPages.After)
+		if diff.TotalGPU.Before != 0 || diff.TotalGPU.After != 0 {
+			fmt.Printf("gpu: %s → %s\n", sizeStr(diff.TotalGPU.Before), sizeStr(diff.TotalGPU.After))
+		}
+
+		return nil
+	},
+}
+
+var gcJobCheckpointCmd = &cobra.Command{
+	Use:               "gc [JID]...",
+	Short:             "Delete checkpoints based on retention policies",
+	Long:              "Delete checkpoints based on retention policies, from both the daemon and the underlying storage. Policies not specified are taken from the config. Applies to all jobs if none are specified.",
+	Args:              cobra.ArbitraryArgs,
+	ValidArgsFunction: ValidJIDs,
+	RunE: func(cmd *cobra.Command, jids []string) error {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+
+		dryRun, _ := cmd.Flags().GetBool(flags.DryRunFlag.Full)
+		maxCount, _ := cmd.Flags().GetInt32(flags.MaxCountFlag.Full)
+		maxAge, _ := cmd.Flags().GetDuration(flags.MaxAgeFlag.Full)
+		maxBytes, _ := cmd.Flags().GetInt64(flags.MaxBytesFlag.Full)
+
+		resp, err := client.GC(cmd.Context(), &daemon.GCReq{
+			JIDs:     jids,
+			DryRun:   dryRun,
+			MaxCount: maxCount,
+			MaxAge:   maxAge.Milliseconds(),
+			MaxBytes: maxBytes,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Checkpoints) > 0 {
+			tableWriter := table.NewWriter()
+			tableWriter.SetStyle(style.TableStyle)
+			tableWriter.SetOutputMirror(os.Stdout)
+
+			tableWriter.AppendHeader(table.Row{
+				"Job",
+				"ID",
+				"Time",
+				"Size",
+				"Path",
+			})
+
+			for _, checkpoint := range resp.GetCheckpoints() {
+				timestamp := time.UnixMilli(checkpoint.GetTime())
+				row := table.Row{
+					checkpoint.GetJID(),
+					checkpoint.GetID(),
+					timestamp.Format(time.DateTime),
+					utils.SizeStr(checkpoint.GetSize()),
+					checkpoint.GetPath(),
+				}
+				tableWriter.AppendRow(row)
+			}
+
+			tableWriter.Render()
+			fmt.Println()
+		}
+
+		for _, message := range resp.GetMessages() {
+			fmt.Println(message)
+		}
+
+		return nil
+	},
+}
+
+var exportJobCheckpointCmd = &cobra.Command{
+	Use:   "export <checkpoint-id>",
+	Short: "Export a checkpoint as an OCI artifact",
+	Long:  "Export a checkpoint as an OCI artifact, pushed to a registry or written to an OCI image layout directory. Includes the CRIU images, process state, and container rootfs diff (if dumped).",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+
+		ref, _ := cmd.Flags().GetString(flags.OCIFlag.Full)
+		username, _ := cmd.Flags().GetString(flags.UsernameFlag.Full)
+		secret, _ := cmd.Flags().GetString(flags.SecretFlag.Full)
+
+		ref, err := ociRef(ref)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.ExportCheckpoint(cmd.Context(), &daemon.ExportCheckpointReq{
+			ID:       args[0],
+			Ref:      ref,
+			Username: username,
+			Secret:   secret,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, message := range resp.GetMessages() {
+			fmt.Println(message)
+		}
+		fmt.Printf("Digest: %s\n", resp.GetDigest())
+
+		return nil
+	},
+}
+
+var importJobCheckpointCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import a checkpoint from an OCI artifact",
+	Long:  "Import a checkpoint from an OCI artifact, pulled from a registry or read from an OCI image layout directory. The job is created if it does not exist, so it can be restored with `cedana restore job`.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+
+		ref, _ := cmd.Flags().GetString(flags.OCIFlag.Full)
+		jid, _ := cmd.Flags().GetString(flags.JidFlag.Full)
+		dir, _ := cmd.Flags().GetString(flags.DirFlag.Full)
+		username, _ := cmd.Flags().GetString(flags.UsernameFlag.Full)
+		secret, _ := cmd.Flags().GetString(flags.SecretFlag.Full)
+
+		ref, err := ociRef(ref)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.ImportCheckpoint(cmd.Context(), &daemon.ImportCheckpointReq{
+			Ref:      ref,
+			JID:      jid,
+			Dir:      dir,
+			Username: username,
+			Secret:   secret,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, message := range resp.GetMessages() {
+			fmt.Println(message)
+		}
+		fmt.Printf("Checkpoint ID: %s\n", resp.GetCheckpoint().GetID())
+
+		return nil
+	},
+}
+
+////////////////////
+/// Helper Funcs ///
+////////////////////
+
+// Returns the OCI reference to send to the daemon, which treats absolute paths as
+// layout directories. So relative paths are made absolute.
+func ociRef(ref string) (string, error) {
+	if ref == "." || ref == ".." || strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../") {
+		return filepath.Abs(ref)
+	}
+	return ref, nil
+}
+
+func printChanges(title string, changes inspect.Changes) {
+	if changes.IsEmpty() {
+		return
+	}
+	fmt.Println(style.InfoColors.Sprint(title))
+	for _, key := range changes.Added {
+		fmt.Println(style.PositiveColors.Sprint("+ " + key))
+	}
+	for _, key := range changes.Removed {
+		fmt.Println(style.NegativeColors.Sprint("- " + key))
+	}
+	fmt.Println()
+}
+
+func printCountChanges(title string, changes []inspect.CountChange, format func(int64) string) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Println(style.InfoColors.Sprint(title))
+	for _, c := range changes {
+		colors := style.PositiveColors
+		if c.After < c.Before {
+			colors = style.NegativeColors
+		}
+		fmt.Printf("%s: %s\n", c.Key, colors.Sprintf("%s → %s", format(c.Before), format(c.After)))
+	}
+	fmt.Println()
+}
+
+func printEvent(event *daemon.Event) {
+	details := []string{}
+	if event.GetPID() != 0 {
+		details = append(details, fmt.Sprintf("PID %d", event.GetPID()))
+	}
+	if event.GetType() == job.EVENT_EXITED {
+		details = append(details, fmt.Sprintf("code %d", event.GetExitCode()))
+	}
+	if event.GetDuration() != 0 {
+		details = append(details, time.Duration(event.GetDuration()).Round(time.Millisecond).String())
+	}
+	if event.GetIO() != 0 {
+		details = append(details, utils.SizeStr(event.GetIO()))
+	}
+	details = append(details, event.GetPaths()...)
+
+	colors := style.InfoColors
+	switch event.GetType() {
+	case job.EVENT_CHECKPOINT_FAILED:
+		colors = style.NegativeColors
+		details = append(details, event.GetError())
+	case job.EVENT_CHECKPOINT_COMPLETED, job.EVENT_RESTORED:
+		colors = style.PositiveColors
+	case job.EVENT_EXITED, job.EVENT_DELETED:
+		colors = style.DisabledColors
+	}
+
+	fmt.Printf("%s  %s  %s  %s\n",
+		time.UnixMilli(event.GetTime()).Format(time.DateTime),
+		event.GetJID(),
+		colors.Sprint(event.GetType()),
+		strings.Join(details, ", "),
+	)
+}
+
+// Like utils.SizeStr, but shows zero sizes instead of omitting them
+func sizeStr(bytes int64) string {
+	if bytes <= 0 {
+		return "0"
+	}
+	return utils.SizeStr(bytes)
+}
@@ -30,6 +30,10 @@ func init() {
 		StringP(flags.GpuIdFlag.Full, flags.GpuIdFlag.Short, "", "specify existing GPU controller ID to attach (internal use only)")
 	manageCmd.PersistentFlags().
 		BoolP(flags.UpcomingFlag.Full, flags.UpcomingFlag.Short, false, "wait for upcoming process/container")
+	manageCmd.PersistentFlags().
+		String(flags.CheckpointOnSignalFlag.Full, "", "signal (e.g. SIGTERM) on which to checkpoint the job, before delivering it (if sent through the daemon)")
+	manageCmd.PersistentFlags().
+		StringP(flags.DirFlag.Full, flags.DirFlag.Short, "", "directory to checkpoint into on signal")
 
 	///////////////////////////////////////////
 	// Add subcommands from supported plugins
@@ -55,6 +59,11 @@ var manageCmd = &cobra.Command{
 		pidFile, _ := cmd.Flags().GetString(flags.PidFileFlag.Full)
 		upcoming, _ := cmd.Flags().GetBool(flags.UpcomingFlag.Full)
 
+		onSignal, err := checkpointOnSignal(cmd)
+		if err != nil {
+			return err
+		}
+
 		action := daemon.RunAction_MANAGE_EXISTING
 		if upcoming {
 			action = daemon.RunAction_MANAGE_UPCOMING
@@ -67,6 +76,8 @@ var manageCmd = &cobra.Command{
 			GPUTracing: gpuTracing,
 			PidFile:    pidFile,
 			Action:     action,
+
+			CheckpointOnSignal: onSignal,
 		}
 
 		ctx := context.WithValue(cmd.Context(), keys.RUN_REQ_CONTEXT_KEY, req)
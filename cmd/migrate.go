@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/pkg/client"
+	"github.com/cedana/cedana/pkg/config"
+	"github.com/cedana/cedana/pkg/flags"
+	"github.com/cedana/cedana/pkg/keys"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	migrateCmd.AddCommand(jobMigrateCmd)
+
+	// Add common flags
+	migrateCmd.PersistentFlags().
+		StringP(flags.ToFlag.Full, "", "", "address of the destination daemon (host:port for TCP, cid:port for VSOCK)")
+	migrateCmd.PersistentFlags().
+		StringP(flags.ToProtocolFlag.Full, "", "tcp", "protocol to use for the destination daemon (TCP, VSOCK)")
+	migrateCmd.PersistentFlags().
+		Int32P(flags.PreDumpFlag.Full, "", 0, "number of iterative pre-dumps to stream to the destination while the job runs, to reduce downtime")
+	migrateCmd.PersistentFlags().Lookup(flags.PreDumpFlag.Full).NoOptDefVal = "1"
+	migrateCmd.PersistentFlags().
+		StringP(flags.CompressionFlag.Full, "", "", "compression algorithm for the transfer (tar, gzip, lz4, zlib, zstd)")
+	migrateCmd.PersistentFlags().
+		BoolP(flags.LazyFlag.Full, "", false, "restore lazily on the destination, loading memory pages on demand")
+	migrateCmd.MarkPersistentFlagRequired(flags.ToFlag.Full)
+}
+
+// Parent migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate a managed process/container (job) to another host",
+	Args:  cobra.ArbitraryArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		to, _ := cmd.Flags().GetString(flags.ToFlag.Full)
+		toProtocol, _ := cmd.Flags().GetString(flags.ToProtocolFlag.Full)
+		preDumps, _ := cmd.Flags().GetInt32(flags.PreDumpFlag.Full)
+		compression, _ := cmd.Flags().GetString(flags.CompressionFlag.Full)
+		lazy, _ := cmd.Flags().GetBool(flags.LazyFlag.Full)
+
+		// Create half-baked request
+		req := &daemon.MigrateReq{
+			Address:     to,
+			Protocol:    toProtocol,
+			PreDumps:    preDumps,
+			Compression: compression,
+			Lazy:        lazy,
+		}
+
+		ctx := context.WithValue(cmd.Context(), keys.MIGRATE_REQ_CONTEXT_KEY, req)
+
+		client, err := client.New(config.Global.Address, config.Global.Protocol)
+		if err != nil {
+			return fmt.Errorf("Error creating client: %v", err)
+		}
+
+		ctx = context.WithValue(ctx, keys.CLIENT_CONTEXT_KEY, client)
+		cmd.SetContext(ctx)
+
+		return nil
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		client, ok := ctx.Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+		defer client.Close()
+
+		req, ok := ctx.Value(keys.MIGRATE_REQ_CONTEXT_KEY).(*daemon.MigrateReq)
+		if !ok {
+			return fmt.Errorf("invalid migrate request in context")
+		}
+
+		resp, err := client.Migrate(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		for _, message := range resp.GetMessages() {
+			fmt.Println(message)
+		}
+
+		return nil
+	},
+}
+
+////////////////////
+/// Subcommands  ///
+////////////////////
+
+var jobMigrateCmd = &cobra.Command{
+	Use:               "job <JID>",
+	Short:             "Migrate a job to the destination daemon",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: RunningJIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		req, ok := cmd.Context().Value(keys.MIGRATE_REQ_CONTEXT_KEY).(*daemon.MigrateReq)
+		if !ok {
+			return fmt.Errorf("invalid migrate request in context")
+		}
+
+		req.JID = args[0]
+
+		return nil
+	},
+}
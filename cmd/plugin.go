@@ -321,7 +321,9 @@ var pluginFeaturesCmd = &cobra.Command{
 		if len(pluginNames) > 0 {
 			tableWriter.AppendHeader(header)
 			tableWriter.AppendRow(featureRow(manager, features.DumpCmd, pluginNames, &errs))
+			tableWriter.AppendRow(featureRow(manager, features.DumpVMCmd, pluginNames, &errs))
 			tableWriter.AppendRow(featureRow(manager, features.RestoreCmd, pluginNames, &errs))
+			tableWriter.AppendRow(featureRow(manager, features.RestoreVMCmd, pluginNames, &errs))
 			tableWriter.AppendRow(featureRow(manager, features.RunCmd, pluginNames, &errs))
 			tableWriter.AppendRow(featureRow(manager, features.ManageCmd, pluginNames, &errs))
 			tableWriter.AppendRow(featureRow(manager, features.FreezeCmd, pluginNames, &errs))
@@ -357,6 +359,8 @@ var pluginFeaturesCmd = &cobra.Command{
 			tableWriter.AppendRow(featureRow(manager, features.GPUTracingRestore, pluginNames, &errs))
 			tableWriter.AppendSeparator()
 			tableWriter.AppendRow(featureRow(manager, features.Storage, pluginNames, &errs))
+			tableWriter.AppendRow(featureRow(manager, features.KeyProvider, pluginNames, &errs))
+			tableWriter.AppendRow(featureRow(manager, features.ExportRootfs, pluginNames, &errs))
 			tableWriter.AppendSeparator()
 			tableWriter.AppendRow(featureRow(manager, features.QueryHandler, pluginNames, &errs))
 			tableWriter.AppendSeparator()
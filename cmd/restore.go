@@ -61,6 +61,8 @@ func init() {
 		BoolP(flags.ShellJobFlag.Full, flags.ShellJobFlag.Short, false, "process is not session leader (shell job)")
 	restoreCmd.PersistentFlags().
 		BoolP(flags.LinkRemapFlag.Full, flags.LinkRemapFlag.Short, false, "remap links to invisible files during restore")
+	restoreCmd.PersistentFlags().
+		BoolP(flags.LazyFlag.Full, "", false, "restore lazily, loading memory pages on demand (from an uncompressed local checkpoint)")
 	restoreCmd.PersistentFlags().
 		StringP(flags.GpuIdFlag.Full, flags.GpuIdFlag.Short, "", "specify existing GPU controller ID to attach (internal use only)")
 	restoreCmd.MarkFlagsMutuallyExclusive(
@@ -113,6 +115,7 @@ var restoreCmd = &cobra.Command{
 		tcpClose, _ := cmd.Flags().GetBool(flags.TcpCloseFlag.Full)
 		leaveStopped, _ := cmd.Flags().GetBool(flags.LeaveStoppedFlag.Full)
 		fileLocks, _ := cmd.Flags().GetBool(flags.FileLocksFlag.Full)
+		lazy, _ := cmd.Flags().GetBool(flags.LazyFlag.Full)
 		criuOptsJSON, _ := cmd.Flags().GetString(flags.CriuOptsFlag.Full)
 
 		criuOpts := &criu.CriuOpts{
@@ -122,6 +125,7 @@ var restoreCmd = &cobra.Command{
 			FileLocks:      proto.Bool(fileLocks),
 			ShellJob:       proto.Bool(shellJob),
 			LinkRemap:      proto.Bool(linkRemap),
+			LazyPages:      proto.Bool(lazy),
 			External:       external,
 		}
 		if criuOptsJSON != "" {
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/pkg/client"
+	"github.com/cedana/cedana/pkg/config"
+	"github.com/cedana/cedana/pkg/features"
+	"github.com/cedana/cedana/pkg/flags"
+	"github.com/cedana/cedana/pkg/keys"
+	"github.com/cedana/cedana/pkg/profiling"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	restoreVMCmd.AddCommand(jobRestoreVMCmd)
+
+	// Add common flags
+	restoreVMCmd.PersistentFlags().
+		StringP(flags.PathFlag.Full, flags.PathFlag.Short, "", "path of dump")
+
+	///////////////////////////////////////////
+	// Add subcommands from supported plugins
+	///////////////////////////////////////////
+
+	features.RestoreVMCmd.IfAvailable(
+		func(name string, pluginCmd *cobra.Command) error {
+			restoreVMCmd.AddCommand(pluginCmd)
+
+			// Apply all the flags from the plugin command to job subcommand (as optional flags),
+			// since the job subcommand can be used to restore any managed VM (from plugins, like cloud-hypervisor),
+			// thus it could have specific CLI overrides from plugins.
+
+			(*pluginCmd).Flags().VisitAll(func(f *pflag.Flag) {
+				newFlag := *f
+				if jobRestoreVMCmd.Flags().Lookup(newFlag.Name) == nil {
+					jobRestoreVMCmd.Flags().AddFlag(&newFlag)
+				}
+				newFlag.Usage = fmt.Sprintf("(%s) %s", name, f.Usage) // Add plugin name to usage
+			})
+			return nil
+		},
+	)
+}
+
+var restoreVMCmd = &cobra.Command{
+	Use:   "restore-vm",
+	Short: "Restore a VM",
+	Args:  cobra.ArbitraryArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		path, _ := cmd.Flags().GetString(flags.PathFlag.Full)
+
+		// Create half-baked request
+		req := &daemon.RestoreVMReq{VMSnapshotPath: path}
+
+		ctx := context.WithValue(cmd.Context(), keys.RESTORE_REQ_CONTEXT_KEY, req)
+		cmd.SetContext(ctx)
+
+		client, err := client.New(config.Global.Address, config.Global.Protocol)
+		if err != nil {
+			return fmt.Errorf("Error creating client: %v", err)
+		}
+
+		ctx = context.WithValue(ctx, keys.CLIENT_CONTEXT_KEY, client)
+		cmd.SetContext(ctx)
+
+		return nil
+	},
+
+	//******************************************************************************************
+	// Let subcommands (incl. from plugins) add details to the request, in the `RunE` hook.
+	// Finally, we send the request to the server in the PersistentPostRun hook.
+	// The server will make sure to handle it appropriately using any required plugins.
+	//******************************************************************************************
+
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) (err error) {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+		defer client.Close()
+
+		// Assuming request is now ready to be sent to the server
+		req, ok := cmd.Context().Value(keys.RESTORE_REQ_CONTEXT_KEY).(*daemon.RestoreVMReq)
+		if !ok {
+			return fmt.Errorf("invalid request in context")
+		}
+
+		resp, data, err := client.RestoreVM(cmd.Context(), req)
+		if err != nil {
+			return err
+		}
+
+		if config.Global.Profiling.Enabled && data != nil {
+			profiling.Print(data, features.Theme())
+			if config.Global.Profiling.Path != "" {
+				profiling.WriteJSON(config.Global.Profiling.Path, data)
+			}
+		}
+
+		if resp.GetPID() != 0 {
+			fmt.Printf("Restored VM with PID %d\n", resp.GetPID())
+		}
+
+		return nil
+	},
+}
+
+////////////////////
+/// Subcommands  ///
+////////////////////
+
+var jobRestoreVMCmd = &cobra.Command{
+	Use:               "job <JID>",
+	Short:             "Restore a managed VM (job)",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: ValidJIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, ok := cmd.Context().Value(keys.CLIENT_CONTEXT_KEY).(*client.Client)
+		if !ok {
+			return fmt.Errorf("invalid client in context")
+		}
+
+		jid := args[0]
+
+		// Get the job type, so we can call the plugin command to override request details
+		resp, err := client.Get(cmd.Context(), &daemon.GetReq{JID: jid})
+		if err != nil {
+			return err
+		}
+		jobType := resp.GetJob().GetType()
+
+		err = features.RestoreVMCmd.IfAvailable(
+			func(name string, pluginCmd *cobra.Command) error {
+				// Call the plugin command to override request details
+				return pluginCmd.RunE(cmd, nil) // don't pass any args
+			}, jobType,
+		)
+		if err != nil {
+			return err
+		}
+
+		// Since the request details have been modified by the plugin command, we need to fetch it
+		req, ok := cmd.Context().Value(keys.RESTORE_REQ_CONTEXT_KEY).(*daemon.RestoreVMReq)
+		if !ok {
+			return fmt.Errorf("invalid restore request in context")
+		}
+
+		if req.Details == nil {
+			req.Details = &daemon.Details{}
+		}
+		req.Details.JID = proto.String(jid)
+
+		return nil
+	},
+}
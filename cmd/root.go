@@ -26,9 +26,11 @@ func init() {
 	rootCmd.AddCommand(queryCmd)
 	rootCmd.AddCommand(docGenCmd)
 	rootCmd.AddCommand(dumpVMCmd)
+	rootCmd.AddCommand(restoreVMCmd)
 	rootCmd.AddCommand(checkCmd)
 	rootCmd.AddCommand(freezeCmd)
 	rootCmd.AddCommand(unfreezeCmd)
+	rootCmd.AddCommand(migrateCmd)
 	rootCmd.AddCommand(versionCmd)
 
 	// Add helper cmds from plugins
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"syscall"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"github.com/cedana/cedana/internal/cedana"
+	"github.com/cedana/cedana/internal/cedana/job"
 	"github.com/cedana/cedana/pkg/client"
 	"github.com/cedana/cedana/pkg/config"
 	"github.com/cedana/cedana/pkg/features"
@@ -39,13 +41,33 @@ func init() {
 		BoolP(flags.AttachableFlag.Full, flags.AttachableFlag.Short, false, "make it attachable, but don't attach")
 	runCmd.PersistentFlags().
 		StringP(flags.OutFlag.Full, flags.OutFlag.Short, "", "file to forward stdout/err")
+	runCmd.PersistentFlags().
+		BoolP(flags.TtyFlag.Full, flags.TtyFlag.Short, false, "allocate a pseudo-terminal (makes it attachable)")
+	runCmd.PersistentFlags().
+		String(flags.CheckpointOnSignalFlag.Full, "", "signal (e.g. SIGTERM) on which to checkpoint the job, before delivering it (if sent through the daemon)")
+	runCmd.PersistentFlags().
+		StringP(flags.DirFlag.Full, flags.DirFlag.Short, "", "directory to checkpoint into on signal")
+	runCmd.PersistentFlags().
+		String(flags.RestartFlag.Full, "", "restart policy on exit (never, on-failure[:max], always)")
 	runCmd.MarkFlagsMutuallyExclusive(
 		flags.AttachFlag.Full,
 		flags.OutFlag.Full,
 	) // only one of these can be set
+	runCmd.MarkFlagsMutuallyExclusive(
+		flags.TtyFlag.Full,
+		flags.OutFlag.Full,
+	) // only one of these can be set
 
 	processRunCmd.PersistentFlags().
 		BoolP(flags.AsRootFlag.Full, flags.AsRootFlag.Short, false, "run as root")
+	processRunCmd.PersistentFlags().
+		Float64(flags.CpusFlag.Full, 0, "max number of CPUs the job can use, e.g. 1.5")
+	processRunCmd.PersistentFlags().
+		String(flags.MemoryFlag.Full, "", "max memory the job can use, e.g. 512M or 2G")
+	processRunCmd.PersistentFlags().
+		Int64(flags.PidsLimitFlag.Full, 0, "max number of processes/threads the job can have")
+	processRunCmd.PersistentFlags().
+		Uint32(flags.IOWeightFlag.Full, 0, "relative IO weight of the job (1-10000, default 100)")
 
 	// Add aliases
 	rootCmd.AddCommand(utils.AliasOf(processRunCmd, "exec"))
@@ -75,21 +97,33 @@ var runCmd = &cobra.Command{
 		out, _ := cmd.Flags().GetString(flags.OutFlag.Full)
 		attach, _ := cmd.Flags().GetBool(flags.AttachFlag.Full)
 		attachable, _ := cmd.Flags().GetBool(flags.AttachableFlag.Full)
+		tty, _ := cmd.Flags().GetBool(flags.TtyFlag.Full)
 		pidFile, _ := cmd.Flags().GetString(flags.PidFileFlag.Full)
 		noServer, _ := cmd.Flags().GetBool(flags.NoServerFlag.Full)
 
-		if noServer && (out != "" || attach || attachable) {
+		if noServer && (out != "" || attach || attachable || tty) {
 			fmt.Println(
 				style.WarningColors.Sprintf(
-					"When using `--%s`, flags `--%s`, `--%s`, and `--%s` are ignored as the standard output is copied to the caller.",
+					"When using `--%s`, flags `--%s`, `--%s`, `--%s`, and `--%s` are ignored as the standard output is copied to the caller.",
 					flags.NoServerFlag.Full,
 					flags.OutFlag.Full,
 					flags.AttachFlag.Full,
 					flags.AttachableFlag.Full,
+					flags.TtyFlag.Full,
 				),
 			)
 		}
 
+		onSignal, err := checkpointOnSignal(cmd)
+		if err != nil {
+			return err
+		}
+
+		restart, err := restartPolicy(cmd)
+		if err != nil {
+			return err
+		}
+
 		env := os.Environ()
 		user, err := utils.GetCredentials()
 		if err != nil {
@@ -105,7 +139,11 @@ var runCmd = &cobra.Command{
 			GPUTracing: gpuTracing,
 			GPUID:      gpuID,
 
-			Attachable:      attach || attachable,
+			CheckpointOnSignal: onSignal,
+			RestartPolicy:      restart,
+
+			TTY:             tty,
+			Attachable:      attach || attachable || tty,
 			WaitFirstMaster: attach,
 			Action:          daemon.RunAction_START_NEW,
 			Env:             env,
@@ -249,6 +287,102 @@ var processRunCmd = &cobra.Command{
 			req.Groups = user.Groups
 		}
 
+		req.Details.Resources, err = resources(cmd)
+		if err != nil {
+			return err
+		}
+
 		return nil
 	},
 }
+
+////////////////////
+/// Helper Funcs ///
+////////////////////
+
+// Returns the checkpoint-on-signal policy from the flags, or nil if not set
+func checkpointOnSignal(cmd *cobra.Command) (*daemon.CheckpointOnSignal, error) {
+	signalStr, _ := cmd.Flags().GetString(flags.CheckpointOnSignalFlag.Full)
+	dir, _ := cmd.Flags().GetString(flags.DirFlag.Full)
+
+	if signalStr == "" {
+		if dir != "" {
+			return nil, fmt.Errorf("`--%s` requires `--%s`", flags.DirFlag.Full, flags.CheckpointOnSignalFlag.Full)
+		}
+		return nil, nil
+	}
+
+	signal, err := utils.ParseSignal(signalStr)
+	if err != nil {
+		return nil, err
+	}
+	if signal == syscall.SIGKILL || signal == syscall.SIGSTOP {
+		return nil, fmt.Errorf("cannot checkpoint on %s, as it cannot be handled", signal)
+	}
+
+	return &daemon.CheckpointOnSignal{
+		Signal: int32(signal),
+		Dir:    dir,
+	}, nil
+}
+
+// Returns the restart policy from the flags, or nil if not set
+func restartPolicy(cmd *cobra.Command) (*daemon.RestartPolicy, error) {
+	policyStr, _ := cmd.Flags().GetString(flags.RestartFlag.Full)
+
+	if policyStr == "" {
+		return nil, nil
+	}
+
+	noServer, _ := cmd.Flags().GetBool(flags.NoServerFlag.Full)
+	if noServer {
+		return nil, fmt.Errorf("restart policies are not supported with `--%s`", flags.NoServerFlag.Full)
+	}
+
+	return job.ParseRestartPolicy(policyStr)
+}
+
+// Returns the resource limits from the flags, or nil if none are set
+func resources(cmd *cobra.Command) (*daemon.Resources, error) {
+	cpus, _ := cmd.Flags().GetFloat64(flags.CpusFlag.Full)
+	memoryStr, _ := cmd.Flags().GetString(flags.MemoryFlag.Full)
+	pidsLimit, _ := cmd.Flags().GetInt64(flags.PidsLimitFlag.Full)
+	ioWeight, _ := cmd.Flags().GetUint32(flags.IOWeightFlag.Full)
+
+	if cpus == 0 && memoryStr == "" && pidsLimit == 0 && ioWeight == 0 {
+		return nil, nil
+	}
+
+	noServer, _ := cmd.Flags().GetBool(flags.NoServerFlag.Full)
+	if noServer {
+		return nil, fmt.Errorf("resource limits are not supported with `--%s`", flags.NoServerFlag.Full)
+	}
+
+	if cpus < 0 {
+		return nil, fmt.Errorf("invalid number of CPUs %v", cpus)
+	}
+	var memory int64
+	if memoryStr != "" {
+		var err error
+		memory, err = utils.ParseSize(memoryStr)
+		if err != nil {
+			return nil, err
+		}
+		if memory == 0 {
+			return nil, fmt.Errorf("invalid memory '%s'", memoryStr)
+		}
+	}
+	if pidsLimit < 0 {
+		return nil, fmt.Errorf("invalid PIDs limit %d", pidsLimit)
+	}
+	if ioWeight > 10000 {
+		return nil, fmt.Errorf("invalid IO weight %d, must be 1-10000", ioWeight)
+	}
+
+	return &daemon.Resources{
+		CPUs:      cpus,
+		Memory:    memory,
+		PidsLimit: pidsLimit,
+		IOWeight:  ioWeight,
+	}, nil
+}
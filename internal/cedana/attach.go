@@ -32,7 +32,7 @@ func (s *Server) Attach(stream daemongrpc.Daemon_AttachServer) error {
 	ctx, cancel := context.WithTimeout(s.lifetime, ATTACH_TIMEOUT)
 	defer cancel()
 
-	err = slave.Attach(ctx, stream)
+	err = slave.Attach(ctx, stream, in.GetReadOnly(), scrollbackTail(in.GetTail()))
 	if err != nil {
 		if err == context.DeadlineExceeded {
 			return status.Errorf(codes.DeadlineExceeded, "likely another master IO attached")
@@ -43,3 +43,16 @@ func (s *Server) Attach(stream daemongrpc.Daemon_AttachServer) error {
 
 	return nil
 }
+
+// Returns the lines of scrollback to replay for the requested tail. As clients that don't
+// set it send 0, it means all of it, while a negative tail means none.
+func scrollbackTail(tail int32) int {
+	switch {
+	case tail == 0:
+		return -1
+	case tail < 0:
+		return 0
+	default:
+		return int(tail)
+	}
+}
@@ -132,6 +132,13 @@ func CheckOpts(ctx context.Context, criuInstance *criu.Criu, opts *criu_proto.Cr
 		}
 	}
 
+	if opts.GetLazyPages() {
+		out, err := criuInstance.Check(ctx, "--feature", "uffd-noncoop")
+		if err != nil {
+			return fmt.Errorf("lazy pages are not supported on this system: %s", strings.TrimSpace(out))
+		}
+	}
+
 	return nil
 }
 
@@ -2,11 +2,15 @@ package criu
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"path/filepath"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
 	"github.com/cedana/cedana/pkg/channel"
 	"github.com/cedana/cedana/pkg/config"
+	"github.com/cedana/cedana/pkg/keys"
 	"github.com/cedana/cedana/pkg/logging"
 	"github.com/cedana/cedana/pkg/profiling"
 	"github.com/cedana/cedana/pkg/types"
@@ -19,8 +23,11 @@ import (
 )
 
 const (
-	CRIU_DUMP_LOG_FILE  = "criu-dump.log"
-	GHOST_FILE_MAX_SIZE = 800 * utils.MEBIBYTE
+	CRIU_DUMP_LOG_FILE     = "criu-dump.log"
+	CRIU_PRE_DUMP_LOG_FILE = "criu-pre-dump.log"
+	PRE_DUMP_DIR_FORMATTER = "pre-%d"
+	PRE_DUMP_DIR_PERMS     = 0o755
+	GHOST_FILE_MAX_SIZE    = 800 * utils.MEBIBYTE
 )
 
 // Returns a CRIU dump handler for the server
@@ -56,6 +63,23 @@ func Dump(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daem
 		return nil, status.Errorf(codes.Internal, "failed to change ownership of dump directory: %v", err)
 	}
 
+	// Parent images are referenced through a symlink inside the images directory,
+	// so keep it relative to allow moving the whole chain around.
+	if parent := criuOpts.GetParentImg(); filepath.IsAbs(parent) {
+		rel, err := filepath.Rel(criuOpts.GetImagesDir(), parent)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to resolve parent images path: %v", err)
+		}
+		criuOpts.ParentImg = proto.String(rel)
+	}
+
+	if req.GetPreDumps() > 0 {
+		err = preDump(ctx, opts, criuOpts, int(req.GetPreDumps()), int(uids[0]), int(gids[0]))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	log.Info().Msg("CRIU dump starting")
 	log.Debug().Interface("opts", criuOpts).Msg("CRIU dump options")
 
@@ -81,3 +105,84 @@ func Dump(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daem
 
 	return channel.Broadcaster(utils.WaitForPidCtx(opts.Lifetime, resp.State.PID)), nil
 }
+
+// Runs the given number of iterative pre-dumps with memory tracking, each into its own
+// directory inside the images directory. Each iteration only dumps pages dirtied since the
+// previous one, and the final dump is made to reference the last iteration as its parent.
+// Each directory is sent on the pre-dump channel in the context (if any) once done.
+func preDump(ctx context.Context, opts types.Opts, criuOpts *criu_proto.CriuOpts, iterations, uid, gid int) error {
+	log := log.With().Str("plugin", "CRIU").Str("operation", "pre-dump").Int32("PID", criuOpts.GetPid()).Logger()
+
+	imagesDir := criuOpts.GetImagesDir()
+	parent := criuOpts.GetParentImg() // relative to the images dir, if set
+	if parent != "" {
+		parent = filepath.Join(imagesDir, parent)
+	}
+
+	criuOpts.TrackMem = proto.Bool(true)
+
+	for i := 1; i <= iterations; i++ {
+		dir := filepath.Join(imagesDir, fmt.Sprintf(PRE_DUMP_DIR_FORMATTER, i))
+
+		if err := os.Mkdir(dir, PRE_DUMP_DIR_PERMS); err != nil {
+			return status.Errorf(codes.Internal, "failed to create pre-dump dir: %v", err)
+		}
+		if err := utils.ChownAll(dir, uid, gid); err != nil {
+			return status.Errorf(codes.Internal, "failed to change ownership of pre-dump dir: %v", err)
+		}
+
+		f, err := os.Open(dir)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to open pre-dump dir: %v", err)
+		}
+
+		preDumpOpts := proto.CloneOf(criuOpts)
+		preDumpOpts.ImagesDir = proto.String(dir)
+		preDumpOpts.ImagesDirFd = proto.Int32(int32(f.Fd()))
+		preDumpOpts.LogFile = proto.String(CRIU_PRE_DUMP_LOG_FILE)
+		preDumpOpts.NotifyScripts = proto.Bool(false) // notify hooks are meant only for the final dump
+		preDumpOpts.ParentImg = nil
+		if parent != "" {
+			rel, err := filepath.Rel(dir, parent)
+			if err != nil {
+				f.Close()
+				return status.Errorf(codes.Internal, "failed to resolve parent images path: %v", err)
+			}
+			preDumpOpts.ParentImg = proto.String(rel)
+		}
+
+		log.Info().Int("iteration", i).Msg("CRIU pre-dump starting")
+
+		ctx, end := profiling.StartTimingCategory(ctx, "criu", opts.CRIU.PreDump)
+		err = opts.CRIU.PreDump(ctx, preDumpOpts, nil)
+		end()
+
+		f.Close()
+
+		logging.FromFile(
+			log.WithContext(ctx),
+			filepath.Join(dir, CRIU_PRE_DUMP_LOG_FILE),
+			zerolog.TraceLevel,
+		)
+
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed CRIU pre-dump (iteration %d): %v", i, err)
+		}
+
+		if preDumped, ok := ctx.Value(keys.PRE_DUMP_CHANNEL_CONTEXT_KEY).(chan<- string); ok {
+			preDumped <- dir
+		}
+
+		parent = dir
+	}
+
+	rel, err := filepath.Rel(imagesDir, parent)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to resolve parent images path: %v", err)
+	}
+	criuOpts.ParentImg = proto.String(rel)
+
+	log.Info().Int("iterations", iterations).Msg("CRIU pre-dump complete")
+
+	return nil
+}
@@ -2,11 +2,28 @@ package criu
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
+	"github.com/cedana/cedana/pkg/config"
+	"github.com/cedana/cedana/pkg/keys"
+	"github.com/cedana/cedana/pkg/logging"
 	"github.com/cedana/cedana/pkg/types"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	CRIU_LAZY_PAGES_LOG_FILE = "criu-lazy-pages.log"
+	LAZY_PAGES_SOCKET        = "lazy-pages.socket" // created by the lazy-pages daemon in the work dir
+	LAZY_PAGES_START_TIMEOUT = 10 * time.Second
 )
 
 // Should ideally be called after all other adapters have run
@@ -20,3 +37,104 @@ func CheckOptsForRestore(next types.Restore) types.Restore {
 		return next(ctx, opts, resp, req)
 	}
 }
+
+// Starts the lazy-pages daemon if a lazy restore is requested, so the restored process
+// becomes runnable before all of its memory pages are loaded. Pages are served on demand from
+// the images directory, or from a page server if one is specified in the CRIU options.
+// The daemon exits by itself once all pages have been transferred. Only uncompressed local
+// checkpoints can be restored lazily, as others would have to be fully staged first.
+func StartLazyPagesForRestore(next types.Restore) types.Restore {
+	return func(ctx context.Context, opts types.Opts, resp *daemon.RestoreResp, req *daemon.RestoreReq) (code func() <-chan int, err error) {
+		criuOpts := req.GetCriu()
+		if !criuOpts.GetLazyPages() {
+			return next(ctx, opts, resp, req)
+		}
+
+		log := log.With().Str("plugin", "CRIU").Str("operation", "lazy-pages").Logger()
+
+		// Signal whoever owns the images directory when we no longer need it
+		done, _ := ctx.Value(keys.LAZY_PAGES_DONE_CONTEXT_KEY).(chan struct{})
+		signalDone := func() {
+			if done != nil {
+				close(done)
+			}
+		}
+
+		imagesDir := criuOpts.GetImagesDir()
+		lazyOpts := &criu_proto.CriuOpts{
+			ImagesDir: proto.String(imagesDir),
+			LogFile:   proto.String(CRIU_LAZY_PAGES_LOG_FILE),
+			LogLevel:  proto.Int32(config.Global.CRIU.LogLevel),
+			Ps:        criuOpts.GetPs(),
+		}
+
+		// Use the lifetime context, as the daemon must outlive this request
+		daemonCmd, err := opts.CRIU.StartLazyPages(opts.Lifetime, lazyOpts, nil, nil)
+		if err != nil {
+			signalDone()
+			return nil, status.Errorf(codes.Internal, "failed to start lazy-pages daemon: %v", err)
+		}
+
+		log.Debug().Int("PID", daemonCmd.Process.Pid).Msg("lazy-pages daemon started")
+
+		exited := make(chan struct{})
+		opts.WG.Go(func() {
+			defer signalDone()
+			defer close(exited)
+
+			err := daemonCmd.Wait()
+
+			logging.FromFile(
+				log.WithContext(opts.Lifetime),
+				filepath.Join(imagesDir, CRIU_LAZY_PAGES_LOG_FILE),
+				zerolog.TraceLevel,
+			)
+
+			if err != nil {
+				log.Warn().Err(err).Msg("lazy-pages daemon exited with error")
+				return
+			}
+			log.Debug().Msg("lazy-pages daemon exited, all pages served")
+		})
+
+		err = waitForLazyPages(ctx, filepath.Join(imagesDir, LAZY_PAGES_SOCKET), exited)
+		if err != nil {
+			daemonCmd.Process.Kill()
+			return nil, status.Errorf(codes.Internal, "lazy-pages daemon failed to start: %v", err)
+		}
+
+		code, err = next(ctx, opts, resp, req)
+		if err != nil {
+			daemonCmd.Process.Kill()
+			return nil, err
+		}
+
+		return code, nil
+	}
+}
+
+//////////////////////////
+//// Helper functions ////
+//////////////////////////
+
+// Waits for the lazy-pages daemon to be ready to accept connections from the restorer
+func waitForLazyPages(ctx context.Context, socket string, exited <-chan struct{}) error {
+	ctx, cancel := context.WithTimeout(ctx, LAZY_PAGES_START_TIMEOUT)
+	defer cancel()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if _, err := os.Stat(socket); err == nil {
+			return nil
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("daemon exited prematurely")
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s: %v", socket, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
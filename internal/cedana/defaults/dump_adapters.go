@@ -28,8 +28,8 @@ func FillMissingDumpDefaults(next types.Dump) types.Dump {
 			req.Criu = &criu_proto.CriuOpts{}
 		}
 
-		// Only override if unset
-		if req.Criu.GetLeaveRunning() == false {
+		// Only override if unset, and not asked to leave stopped instead
+		if req.Criu.GetLeaveRunning() == false && req.Criu.GetLeaveStopped() == false {
 			req.Criu.LeaveRunning = proto.Bool(config.Global.CRIU.LeaveRunning)
 		}
 
@@ -3,6 +3,7 @@ package cedana
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
@@ -56,6 +57,10 @@ func (s *Server) Dump(ctx context.Context, req *daemon.DumpReq) (*daemon.DumpRes
 		dump = dump.With(job.ManageDump(s.jobs))
 	}
 
+	if req.GetParentID() != "" { // If dumping incrementally on top of a previous checkpoint
+		dump = dump.With(job.ResolveParentForDump(s.jobs))
+	}
+
 	dump = dump.With(criu.New[daemon.DumpReq, daemon.DumpResp](s.plugins))
 
 	opts := types.Opts{
@@ -167,20 +172,20 @@ func pluginDumpStorage(next types.Dump) types.Dump {
 	return func(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daemon.DumpReq) (code func() <-chan int, err error) {
 		dir := req.GetDir()
 
-		var storage io.Storage = &filesystem.Storage{}
-
-		if strings.Contains(dir, "://") {
-			pluginName := fmt.Sprintf("storage/%s", strings.Split(dir, "://")[0])
-			err := features.Storage.IfAvailable(func(name string, newPluginStorage func(ctx context.Context) (io.Storage, error)) (err error) {
-				if newPluginStorage == nil {
-					return fmt.Errorf("plugin '%s' does not implement '%s'", name, features.Storage)
-				}
-				storage, err = newPluginStorage(ctx)
-				return err
-			}, pluginName)
-			if err != nil {
-				return nil, status.Error(codes.Unavailable, err.Error())
-			}
+		storage, err := storageForPath(ctx, dir)
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+
+		key, err := checkpointKey(ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "failed to get encryption key: %v", err)
+		}
+		if key != nil {
+			storage = io.NewEncryptedStorage(storage, key)
+		}
+		if config.Global.Checkpoint.Dedup {
+			storage = io.NewDedupStorage(storage, dir)
 		}
 
 		opts.Storage = storage
@@ -194,6 +199,10 @@ func pluginDumpStorage(next types.Dump) types.Dump {
 			return nil, status.Error(codes.InvalidArgument, "A minimum of 2 streams are required for streaming. Specify 0 to disable streaming.")
 		}
 
+		if streams > 1 && (req.GetPreDumps() > 0 || req.GetParentID() != "") {
+			return nil, status.Error(codes.InvalidArgument, "Incremental dumps (pre-dump/parent) are not supported with streaming.")
+		}
+
 		filesystem := filesystem.DumpFilesystem
 		if streams > 1 {
 			filesystem = streamer.DumpFilesystem(streams)
@@ -230,3 +239,95 @@ func pluginDumpHandler() types.Dump {
 		return handler(ctx, opts, resp, req)
 	}
 }
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Returns the storage to use for the specified path. If path is prepended with "plugin://",
+// it will use the plugin storage if an available plugin is found and supports the storage feature.
+func storageForPath(ctx context.Context, path string) (io.Storage, error) {
+	var storage io.Storage = &filesystem.Storage{}
+
+	if strings.Contains(path, "://") {
+		pluginName := fmt.Sprintf("storage/%s", strings.Split(path, "://")[0])
+		err := features.Storage.IfAvailable(func(name string, newPluginStorage func(ctx context.Context) (io.Storage, error)) (err error) {
+			if newPluginStorage == nil {
+				return fmt.Errorf("plugin '%s' does not implement '%s'", name, features.Storage)
+			}
+			storage, err = newPluginStorage(ctx)
+			return err
+		}, pluginName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return storage, nil
+}
+
+// Returns the storage to read/delete existing checkpoints at the specified path, which
+// transparently handles encrypted and deduplicated checkpoints.
+func checkpointStorage(ctx context.Context, path string) (io.Storage, error) {
+	storage, err := storageForPath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := checkpointKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption key: %w", err)
+	}
+	return io.NewDedupStorage(io.NewEncryptedStorage(storage, key), ""), nil
+}
+
+// Returns the key to encrypt/decrypt checkpoints with, based on the configured key source.
+// Returns nil if encryption is not configured.
+func checkpointKey(ctx context.Context) ([]byte, error) {
+	source := config.Global.Checkpoint.EncryptionKey
+	if source == "" {
+		return nil, nil
+	}
+
+	scheme, id, found := strings.Cut(source, "://")
+	if !found {
+		return nil, fmt.Errorf("invalid key source '%s', expected <scheme>://<id>", source)
+	}
+
+	switch scheme {
+	case "file":
+		data, err := os.ReadFile(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		return io.ParseKey(data)
+	case "env":
+		data, ok := os.LookupEnv(id)
+		if !ok {
+			return nil, fmt.Errorf("key env var '%s' is not set", id)
+		}
+		return io.ParseKey([]byte(data))
+	}
+
+	var key []byte
+	pluginName := fmt.Sprintf("keys/%s", scheme)
+	err := features.KeyProvider.IfAvailable(func(name string, newKeyProvider func(ctx context.Context) (io.KeyProvider, error)) error {
+		if newKeyProvider == nil {
+			return fmt.Errorf("plugin '%s' does not implement '%s'", name, features.KeyProvider)
+		}
+		provider, err := newKeyProvider(ctx)
+		if err != nil {
+			return err
+		}
+		data, err := provider.Key(ctx, id)
+		if err != nil {
+			return err
+		}
+		key, err = io.ParseKey(data)
+		return err
+	}, pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"github.com/cedana/cedana/internal/cedana/defaults"
+	"github.com/cedana/cedana/internal/cedana/job"
 	"github.com/cedana/cedana/internal/cedana/validation"
 	"github.com/cedana/cedana/pkg/features"
 	"github.com/cedana/cedana/pkg/profiling"
@@ -26,6 +27,10 @@ func (s *Server) DumpVM(ctx context.Context, req *daemon.DumpVMReq) (*daemon.Dum
 
 	dump := pluginDumpVMHandler().With(middleware...)
 
+	if req.GetDetails().GetJID() != "" { // If using job dump
+		dump = dump.With(job.ManageDumpVM(s.jobs))
+	}
+
 	opts := types.Opts{
 		Lifetime: s.lifetime,
 		Plugins:  s.plugins,
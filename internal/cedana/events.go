@@ -0,0 +1,68 @@
+package cedana
+
+import (
+	"slices"
+
+	"buf.build/gen/go/cedana/cedana/grpc/go/daemon/daemongrpc"
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/internal/cedana/job"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WatchEvents streams recent job events, filtered by JID and type. If following, keeps
+// streaming new events until the client goes away or the daemon shuts down.
+func (s *Server) WatchEvents(req *daemon.WatchEventsReq, stream daemongrpc.Daemon_WatchEventsServer) error {
+	for _, t := range req.GetTypes() {
+		if !slices.Contains(job.EventTypes, t) {
+			return status.Errorf(codes.InvalidArgument, "unknown event type '%s'", t)
+		}
+	}
+
+	matches := func(event *daemon.Event) bool {
+		if len(req.GetJIDs()) > 0 && !slices.Contains(req.GetJIDs(), event.GetJID()) {
+			return false
+		}
+		if len(req.GetTypes()) > 0 && !slices.Contains(req.GetTypes(), event.GetType()) {
+			return false
+		}
+		return true
+	}
+
+	ctx := stream.Context()
+
+	recent, events := s.jobs.Watch(ctx)
+
+	for _, event := range recent {
+		if !matches(event) {
+			continue
+		}
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+	}
+
+	if !req.GetFollow() {
+		return nil
+	}
+
+	for {
+		select {
+		case <-s.lifetime.Done():
+			return status.Errorf(codes.Unavailable, "daemon is shutting down")
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return status.Errorf(codes.ResourceExhausted, "fell too far behind on events")
+			}
+			if !matches(event) {
+				continue
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package cedana
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/internal/cedana/filesystem"
+	"github.com/cedana/cedana/internal/cedana/oci"
+	"github.com/cedana/cedana/internal/cedana/process"
+	"github.com/cedana/cedana/internal/cedana/streamer"
+	"github.com/cedana/cedana/pkg/config"
+	"github.com/cedana/cedana/pkg/features"
+	"github.com/cedana/cedana/pkg/io"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	EXPORT_DIR_PATTERN = "export-*"
+	IMPORT_DIR_FORMAT  = "import-%d"
+)
+
+// ExportCheckpoint packages a checkpoint as an OCI artifact, and pushes it to a registry
+// or writes it to an OCI image layout directory.
+func (s *Server) ExportCheckpoint(ctx context.Context, req *daemon.ExportCheckpointReq) (*daemon.ExportCheckpointResp, error) {
+	if req.GetID() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing checkpoint ID")
+	}
+	if req.GetRef() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing destination reference")
+	}
+
+	checkpoint := s.jobs.GetCheckpoint(req.GetID())
+	if checkpoint == nil {
+		return nil, status.Errorf(codes.NotFound, "checkpoint %s not found", req.GetID())
+	}
+	if checkpoint.GetParentID() != "" {
+		return nil, status.Errorf(codes.Unimplemented, "exporting incremental checkpoints is not supported")
+	}
+
+	path := checkpoint.GetPath()
+
+	storage, err := checkpointStorage(ctx, path)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to get storage: %v", err)
+	}
+
+	streams, err := streamer.IsStreamable(ctx, storage, path)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to detect checkpoint format: %v", err)
+	}
+	if streams > 0 {
+		return nil, status.Errorf(codes.Unimplemented, "exporting streamed checkpoints is not supported")
+	}
+
+	isDir, err := storage.IsDir(ctx, path)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "path error: %s", path)
+	}
+
+	imagesDirectory := path
+
+	if storage.IsRemote() || !isDir {
+		imagesDirectory, err = os.MkdirTemp("", EXPORT_DIR_PATTERN)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create export dir: %v", err)
+		}
+		defer os.RemoveAll(imagesDirectory)
+
+		if err := decompressCheckpoint(ctx, storage, path, imagesDirectory, nil); err != nil {
+			return nil, status.Errorf(codes.Internal, "%v", err)
+		}
+	}
+
+	resp := &daemon.ExportCheckpointResp{}
+
+	err = filesystem.VerifyManifest(imagesDirectory)
+	if errors.Is(err, fs.ErrNotExist) {
+		resp.Messages = append(resp.Messages, "Checkpoint has no manifest, exporting without verification")
+	} else if err != nil {
+		return nil, status.Errorf(codes.DataLoss, "checkpoint verification failed: %v", err)
+	}
+
+	metadata := &oci.Metadata{
+		ID:   checkpoint.GetID(),
+		JID:  checkpoint.GetJID(),
+		Time: checkpoint.GetTime(),
+	}
+	if job := s.jobs.Get(ctx, checkpoint.GetJID()); job != nil {
+		metadata.Type = job.GetType()
+	}
+
+	// Rootfs diffs are not saved with the dump, so they are fetched from the plugin, if any
+
+	rootfsDirectory := ""
+	if available, _ := features.ExportRootfs.IsAvailable(metadata.Type); available {
+		rootfsDirectory, err = os.MkdirTemp("", EXPORT_DIR_PATTERN)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create rootfs export dir: %v", err)
+		}
+		defer os.RemoveAll(rootfsDirectory)
+
+		err = features.ExportRootfs.IfAvailable(func(_ string, exportRootfs func(context.Context, string, string) error) error {
+			return exportRootfs(ctx, imagesDirectory, rootfsDirectory)
+		}, metadata.Type)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to export rootfs: %v", err)
+		}
+	}
+	metadata.Manifest, _ = filesystem.ReadManifest(imagesDirectory)
+	metadata.State, _ = os.ReadFile(filepath.Join(imagesDirectory, process.STATE_FILE))
+
+	log := log.With().Str("checkpoint", checkpoint.GetID()).Str("ref", req.GetRef()).Logger()
+	log.Info().Msg("exporting checkpoint")
+
+	desc, err := oci.Export(ctx, imagesDirectory, rootfsDirectory, metadata, req.GetRef(), oci.Auth{
+		Username: req.GetUsername(),
+		Secret:   req.GetSecret(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to export checkpoint: %v", err)
+	}
+
+	log.Info().Str("digest", desc.Digest.String()).Msg("exported checkpoint")
+
+	resp.Digest = desc.Digest.String()
+	resp.Messages = append(resp.Messages, fmt.Sprintf("Exported checkpoint %s to %s", checkpoint.GetID(), req.GetRef()))
+
+	return resp, nil
+}
+
+// ImportCheckpoint pulls a checkpoint OCI artifact from a registry or reads it from an OCI
+// image layout directory, and adds it as a checkpoint of the job, creating it if required.
+func (s *Server) ImportCheckpoint(ctx context.Context, req *daemon.ImportCheckpointReq) (*daemon.ImportCheckpointResp, error) {
+	if req.GetRef() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing source reference")
+	}
+
+	dir := req.GetDir()
+	if dir == "" {
+		dir = config.Global.Checkpoint.Dir
+	}
+	if strings.Contains(dir, "://") {
+		return nil, status.Errorf(codes.Unimplemented, "importing to remote storage is not supported")
+	}
+
+	imagesDirectory := filepath.Join(dir, fmt.Sprintf(IMPORT_DIR_FORMAT, time.Now().UnixNano()))
+	if err := os.MkdirAll(imagesDirectory, filesystem.DUMP_DIR_PERMS); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create import dir: %v", err)
+	}
+
+	log := log.With().Str("ref", req.GetRef()).Str("dir", imagesDirectory).Logger()
+	log.Info().Msg("importing checkpoint")
+
+	metadata, err := oci.Import(ctx, req.GetRef(), imagesDirectory, oci.Auth{
+		Username: req.GetUsername(),
+		Secret:   req.GetSecret(),
+	})
+	if err != nil {
+		os.RemoveAll(imagesDirectory)
+		return nil, status.Errorf(codes.Internal, "failed to import checkpoint: %v", err)
+	}
+
+	resp := &daemon.ImportCheckpointResp{}
+
+	err = filesystem.VerifyManifest(imagesDirectory)
+	if errors.Is(err, fs.ErrNotExist) {
+		resp.Messages = append(resp.Messages, "Checkpoint has no manifest, imported without verification")
+	} else if err != nil {
+		os.RemoveAll(imagesDirectory)
+		return nil, status.Errorf(codes.DataLoss, "checkpoint verification failed: %v", err)
+	}
+
+	jid := req.GetJID()
+	if jid == "" {
+		jid = metadata.JID
+	}
+
+	if !s.jobs.Exists(jid) {
+		if _, err := s.jobs.New(jid, metadata.Type); err != nil {
+			os.RemoveAll(imagesDirectory)
+			return nil, status.Errorf(codes.InvalidArgument, "failed to create job: %v", err)
+		}
+		resp.Messages = append(resp.Messages, fmt.Sprintf("Created job %s", jid))
+	}
+
+	s.jobs.AddCheckpoint(jid, []string{imagesDirectory}, "")
+
+	log.Info().Str("JID", jid).Msg("imported checkpoint")
+
+	resp.Checkpoint = s.jobs.GetLatestCheckpoint(jid)
+	resp.Messages = append(resp.Messages, fmt.Sprintf("Imported checkpoint for job %s to %s", jid, imagesDirectory))
+
+	return resp, nil
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Decompresses the checkpoint tarball at the path in storage to the directory.
+// If filter is not nil, only the files for which it returns true are extracted.
+func decompressCheckpoint(ctx context.Context, storage io.Storage, path string, dir string, filter func(header *tar.Header) bool) (err error) {
+	compression, err := io.CompressionFromExt(path)
+	if err != nil {
+		return err
+	}
+
+	tarball, err := storage.Open(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, tarball.Close())
+	}()
+
+	if err := io.UntarFiltered(tarball, dir, compression, filter); err != nil {
+		return fmt.Errorf("failed to decompress checkpoint: %w", err)
+	}
+
+	return nil
+}
@@ -31,6 +31,7 @@ const DUMP_DIR_PERMS = 0o755
 //   - "tar" creates a tarball of the dump directory
 //   - "gzip" creates a gzipped tarball of the dump directory
 //   - "lz4" creates an lz4-compressed tarball of the dump directory
+//   - "zstd" creates a zstd-compressed tarball of the dump directory
 func DumpFilesystem(next types.Dump) types.Dump {
 	return func(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daemon.DumpReq) (code func() <-chan int, err error) {
 		storage := opts.Storage
@@ -45,6 +46,19 @@ func DumpFilesystem(next types.Dump) types.Dump {
 			return nil, status.Errorf(codes.Unimplemented, "unsupported compression format '%s'", compression)
 		}
 
+		// Encryption and deduplication only apply to files created through the storage, so
+		// the dump must be a tarball, instead of being left as plain files in the dir
+		if compression == "none" {
+			switch s := storage.(type) {
+			case *io.DedupStorage:
+				compression = "tar"
+			case *io.EncryptedStorage:
+				if s.IsEncrypting() {
+					compression = "tar"
+				}
+			}
+		}
+
 		async := (req.Async || config.Global.Checkpoint.Async) && storage.IsRemote()
 
 		// If remote storage, we instead use a temporary directory for CRIU
@@ -91,9 +105,21 @@ func DumpFilesystem(next types.Dump) types.Dump {
 		// to the dump directory
 		opts.DumpFs = afero.NewBasePathFs(afero.NewOsFs(), imagesDirectory)
 
+		// Every dump carries a manifest of its files, so it can be verified before restore
+		var criuVersion int
+		if opts.CRIU != nil {
+			criuVersion, _ = opts.CRIU.GetCriuVersion(ctx)
+		}
+
 		// If remote storage, or compression needs to be done, we do it in CRIU's post-dump hook
 		// so that if we fail compression/upload, CRIU can still resume the process (only if leave-running is not set)
 
+		// A parent is referenced through a relative symlink to its images directory, which
+		// would dangle once the dump is moved into a tarball or to remote storage.
+		if req.GetCriu().GetParentImg() != "" && (storage.IsRemote() || (compression != "" && compression != "none")) {
+			return nil, status.Errorf(codes.InvalidArgument, "dumps with a parent checkpoint must be uncompressed and local")
+		}
+
 		if storage.IsRemote() || (compression != "" && compression != "none") {
 			ext, err := io.ExtForCompression(compression)
 			if err != nil {
@@ -121,7 +147,7 @@ func DumpFilesystem(next types.Dump) types.Dump {
 
 				tarball = profiling.IOCategory(ctx, tarball, "storage", io.Tar, compression)
 
-				err = io.Tar(imagesDirectory, tarball, compression, isFuse)
+				err = TarWithManifest(ctx, imagesDirectory, tarball, compression, isFuse, criuVersion)
 				if err != nil {
 					storage.Delete(ctx, path)
 					os.RemoveAll(imagesDirectory)
@@ -186,6 +212,15 @@ func DumpFilesystem(next types.Dump) types.Dump {
 				size := utils.SizeFromPath(imagesDirectory)
 				profiling.AddIO(ctx, size)
 			}()
+			defer func() {
+				if err != nil {
+					return
+				}
+				// Process may already be gone, so don't fail a usable dump
+				if err := WriteManifest(ctx, imagesDirectory, criuVersion); err != nil {
+					log.Warn().Err(err).Str("path", imagesDirectory).Msg("failed to write manifest")
+				}
+			}()
 			resp.Paths = append(resp.Paths, imagesDirectory)
 		}
 
@@ -0,0 +1,219 @@
+package filesystem
+
+// A manifest is saved in every dump, so a corrupted or incomplete checkpoint
+// (e.g. from a failed upload) can be detected before handing it to CRIU.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	cedana_io "github.com/cedana/cedana/pkg/io"
+	"github.com/cedana/cedana/pkg/utils"
+	"github.com/cedana/cedana/pkg/version"
+)
+
+const MANIFEST_FILE = "manifest.json"
+
+type Manifest struct {
+	Version     string            `json:"version"`      // cedana version that took the dump
+	CRIUVersion int               `json:"criu_version"` // Major * 10000 + Minor * 100 + SubLevel
+	Host        *daemon.Host      `json:"host"`
+	Size        int64             `json:"size"`  // total size of all files
+	Files       map[string]string `json:"files"` // SHA-256 of each file, by path relative to the images directory
+}
+
+// WriteManifest computes the manifest of all files in the images directory, and saves it there.
+func WriteManifest(ctx context.Context, imagesDirectory string, criuVersion int) error {
+	manifest, err := newManifest(ctx, criuVersion)
+	if err != nil {
+		return err
+	}
+
+	err = walkImages(imagesDirectory, func(rel, path string, info fs.FileInfo) error {
+		sum, err := utils.FileSHA256Sum(path)
+		if err != nil {
+			return err
+		}
+		manifest.Files[rel] = sum
+		manifest.Size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compute checksums: %w", err)
+	}
+
+	return utils.SaveJSONToFile(manifest, filepath.Join(imagesDirectory, MANIFEST_FILE))
+}
+
+// TarWithManifest creates a tarball of the images directory, like io.Tar, and appends the
+// manifest of its files to it. Checksums are computed as the files are archived, instead of
+// reading them all again beforehand (e.g. in the post-dump hook, while the tasks are frozen).
+func TarWithManifest(
+	ctx context.Context,
+	imagesDirectory string,
+	dst io.Writer,
+	compression string,
+	isFuse bool,
+	criuVersion int,
+) error {
+	manifest, err := newManifest(ctx, criuVersion)
+	if err != nil {
+		return err
+	}
+
+	sums := make(map[string]*fileSum)
+
+	tee := func(name string) io.Writer {
+		if !isImage(name) {
+			return nil
+		}
+		sum := &fileSum{Hash: sha256.New()}
+		sums[name] = sum
+		return sum
+	}
+
+	trailer := func() (map[string][]byte, error) {
+		for name, sum := range sums {
+			manifest.Files[name] = fmt.Sprintf("%x", sum.Sum(nil))
+			manifest.Size += sum.size
+		}
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		return map[string][]byte{MANIFEST_FILE: data}, nil
+	}
+
+	return cedana_io.TarWith(imagesDirectory, dst, compression, isFuse, tee, trailer)
+}
+
+// ReadManifest reads the manifest saved in the images directory.
+// Returns an error satisfying errors.Is(err, fs.ErrNotExist) if there is none.
+func ReadManifest(imagesDirectory string) (*Manifest, error) {
+	path := filepath.Join(imagesDirectory, MANIFEST_FILE)
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	err := utils.LoadJSONFromFile(path, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// VerifyManifest checks all files in the images directory against its manifest.
+// Returns an error satisfying errors.Is(err, fs.ErrNotExist) if there is no manifest.
+func VerifyManifest(imagesDirectory string) error {
+	manifest, err := ReadManifest(imagesDirectory)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	seen := make(map[string]bool)
+
+	err = walkImages(imagesDirectory, func(rel, path string, info fs.FileInfo) error {
+		expected, ok := manifest.Files[rel]
+		if !ok {
+			return nil // files added after the dump are not covered
+		}
+		seen[rel] = true
+		sum, err := utils.FileSHA256Sum(path)
+		if err != nil {
+			return err
+		}
+		if sum != expected {
+			errs = append(errs, fmt.Errorf("%s: checksum mismatch", rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compute checksums: %w", err)
+	}
+
+	var missing []string
+	for rel := range manifest.Files {
+		if !seen[rel] {
+			missing = append(missing, rel)
+		}
+	}
+	slices.Sort(missing)
+	for _, rel := range missing {
+		errs = append(errs, fmt.Errorf("%s: missing", rel))
+	}
+
+	return errors.Join(errs...)
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+func newManifest(ctx context.Context, criuVersion int) (*Manifest, error) {
+	host, err := utils.GetHost(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host info: %w", err)
+	}
+
+	return &Manifest{
+		Version:     version.GetVersion(),
+		CRIUVersion: criuVersion,
+		Host:        host,
+		Files:       make(map[string]string),
+	}, nil
+}
+
+// Returns whether a file, by path relative to the images directory, is covered by the manifest.
+// The manifest itself and log files, which may still be written to, are not.
+func isImage(rel string) bool {
+	return rel != MANIFEST_FILE && filepath.Ext(rel) != ".log"
+}
+
+// Computes the checksum and size of all data written to it
+type fileSum struct {
+	hash.Hash
+	size int64
+}
+
+func (s *fileSum) Write(p []byte) (int, error) {
+	s.size += int64(len(p))
+	return s.Hash.Write(p)
+}
+
+// Calls fn for each regular file in the images directory that is covered by the manifest.
+// Symlinks (e.g. to a parent checkpoint) are not followed.
+func walkImages(imagesDirectory string, fn func(rel, path string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(imagesDirectory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(imagesDirectory, path)
+		if err != nil {
+			return err
+		}
+		if !isImage(rel) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(rel, path, info)
+	})
+}
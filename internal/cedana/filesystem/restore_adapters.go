@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
@@ -47,6 +48,12 @@ func RestoreFilesystem(next types.Restore) types.Restore {
 			size := utils.SizeFromPath(imagesDirectory)
 			profiling.AddIO(ctx, size)
 		} else {
+			// Pages would only be served once the whole checkpoint is downloaded and
+			// decompressed, which defeats the purpose of a lazy restore
+			if req.GetCriu().GetLazyPages() {
+				return nil, status.Error(codes.Unimplemented, "Lazy restore requires an uncompressed local checkpoint directory.")
+			}
+
 			// Create a temporary directory for the restore
 			imagesDirectory = filepath.Join(os.TempDir(), fmt.Sprintf("restore-%d", time.Now().UnixNano()))
 
@@ -93,6 +100,14 @@ func RestoreFilesystem(next types.Restore) types.Restore {
 			}
 		}
 
+		// Fail early on a corrupted checkpoint, instead of with a cryptic CRIU error
+		err = VerifyManifest(imagesDirectory)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Debug().Str("path", path).Msg("no manifest found in dump, skipping verification")
+		} else if err != nil {
+			return nil, status.Errorf(codes.DataLoss, "checkpoint verification failed: %v", err)
+		}
+
 		dir, err := os.Open(imagesDirectory)
 		if err != nil {
 			os.RemoveAll(imagesDirectory)
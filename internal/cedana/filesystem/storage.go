@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 )
 
 // Default filesystem storage
@@ -19,6 +20,10 @@ func (s *Storage) Open(_ context.Context, path string) (io.ReadCloser, error) {
 }
 
 func (s *Storage) Create(_ context.Context, path string) (io.WriteCloser, error) {
+	err := os.MkdirAll(filepath.Dir(path), DUMP_DIR_PERMS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parent dir: %w", err)
+	}
 	file, err := os.Create(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file: %w", err)
@@ -27,7 +32,11 @@ func (s *Storage) Create(_ context.Context, path string) (io.WriteCloser, error)
 }
 
 func (s *Storage) Delete(_ context.Context, path string) error {
-	err := os.Remove(path)
+	remove := os.Remove
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		remove = os.RemoveAll // e.g. uncompressed dumps
+	}
+	err := remove(path)
 	if err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
@@ -0,0 +1,122 @@
+package cedana
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/internal/cedana/job"
+	"github.com/cedana/cedana/pkg/config"
+	"github.com/cedana/cedana/pkg/utils"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GC collects checkpoints based on the retention policies in the request, falling back
+// to the configured ones for those not specified.
+func (s *Server) GC(ctx context.Context, req *daemon.GCReq) (*daemon.GCResp, error) {
+	policy, err := gcPolicyFromConfig()
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "invalid GC config: %v", err)
+	}
+
+	if req.GetMaxCount() < 0 || req.GetMaxAge() < 0 || req.GetMaxBytes() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "GC policies cannot be negative")
+	}
+	if req.GetMaxCount() > 0 {
+		policy.MaxCount = int(req.GetMaxCount())
+	}
+	if req.GetMaxAge() > 0 {
+		policy.MaxAge = time.Duration(req.GetMaxAge()) * time.Millisecond
+	}
+	if req.GetMaxBytes() > 0 {
+		policy.MaxBytes = req.GetMaxBytes()
+	}
+
+	if policy.IsZero() {
+		return nil, status.Errorf(codes.FailedPrecondition, "no GC policy specified or configured")
+	}
+
+	collected, err := s.gc.Collect(ctx, policy, req.GetDryRun(), req.GetJIDs()...)
+
+	resp := &daemon.GCResp{Checkpoints: collected}
+
+	var bytes int64
+	for _, checkpoint := range collected {
+		bytes += checkpoint.GetSize()
+	}
+	if req.GetDryRun() {
+		resp.Messages = append(resp.Messages, fmt.Sprintf("Would collect %d checkpoints (%s)", len(collected), utils.SizeStr(bytes)))
+	} else {
+		resp.Messages = append(resp.Messages, fmt.Sprintf("Collected %d checkpoints (%s)", len(collected), utils.SizeStr(bytes)))
+	}
+	if err != nil {
+		resp.Messages = append(resp.Messages, fmt.Sprintf("Failed to collect some checkpoints: %v", err))
+	}
+
+	return resp, nil
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Periodically collects checkpoints in the background, based on the configured policies
+func startGC(lifetime context.Context, wg *sync.WaitGroup, gc *job.GC) error {
+	if config.Global.Checkpoint.GC.Interval == "" {
+		return nil
+	}
+
+	interval, err := time.ParseDuration(config.Global.Checkpoint.GC.Interval)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("invalid GC interval '%s'", config.Global.Checkpoint.GC.Interval)
+	}
+
+	policy, err := gcPolicyFromConfig()
+	if err != nil {
+		return err
+	}
+	if policy.IsZero() {
+		return fmt.Errorf("GC interval is set, but no GC policy is configured")
+	}
+
+	wg.Go(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-lifetime.Done():
+				return
+			case <-ticker.C:
+			}
+
+			collected, err := gc.Collect(lifetime, policy, false)
+			if err != nil {
+				log.Warn().Err(err).Msg("failed to collect some checkpoints")
+			}
+			if len(collected) > 0 {
+				log.Info().Int("count", len(collected)).Msg("collected checkpoints")
+			}
+		}
+	})
+
+	return nil
+}
+
+func gcPolicyFromConfig() (policy job.GCPolicy, err error) {
+	policy.MaxCount = config.Global.Checkpoint.GC.MaxCount
+	policy.MaxBytes = config.Global.Checkpoint.GC.MaxBytes
+
+	if maxAge := config.Global.Checkpoint.GC.MaxAge; maxAge != "" {
+		policy.MaxAge, err = time.ParseDuration(maxAge)
+		if err != nil {
+			return policy, fmt.Errorf("invalid max age '%s': %w", maxAge, err)
+		}
+	}
+
+	return policy, nil
+}
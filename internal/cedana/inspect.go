@@ -0,0 +1,122 @@
+package cedana
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/internal/cedana/inspect"
+	"github.com/cedana/cedana/internal/cedana/process"
+	"github.com/cedana/cedana/internal/cedana/streamer"
+	"github.com/cedana/cedana/pkg/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const INSPECT_DIR_PATTERN = "inspect-*"
+
+// Memory pages are not needed for any view, and usually make up most of the checkpoint.
+// Neither are GPU images, of which only the sizes are needed.
+var inspectSkipPattern = regexp.MustCompile(`^pages-\d+\.img$`)
+
+// InspectCheckpoint returns views of the images of a checkpoint, along with the saved
+// process state. Compressed and remote checkpoints are decompressed on the fly.
+func (s *Server) InspectCheckpoint(ctx context.Context, req *daemon.InspectCheckpointReq) (*daemon.InspectCheckpointResp, error) {
+	if req.GetID() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing checkpoint ID")
+	}
+
+	views := req.GetTypes()
+	if len(views) == 0 {
+		views = inspect.TYPES
+	}
+	for _, t := range views {
+		if !slices.Contains(inspect.TYPES, t) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid type '%s', must be one of {%s}", t, strings.Join(inspect.TYPES, "|"))
+		}
+	}
+
+	checkpoint := s.jobs.GetCheckpoint(req.GetID())
+	if checkpoint == nil {
+		return nil, status.Errorf(codes.NotFound, "checkpoint %s not found", req.GetID())
+	}
+
+	path := checkpoint.GetPath()
+
+	storage, err := checkpointStorage(ctx, path)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to get storage: %v", err)
+	}
+
+	streams, err := streamer.IsStreamable(ctx, storage, path)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to detect checkpoint format: %v", err)
+	}
+	if streams > 0 {
+		return nil, status.Errorf(codes.Unimplemented, "inspecting streamed checkpoints is not supported")
+	}
+
+	isDir, err := storage.IsDir(ctx, path)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "path error: %s", path)
+	}
+
+	imagesDirectory := path
+
+	// Images not extracted are still listed with their sizes, e.g. for the GPU view
+	var sizes map[string]int64
+
+	if storage.IsRemote() || !isDir {
+		imagesDirectory, err = os.MkdirTemp("", INSPECT_DIR_PATTERN)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create inspect dir: %v", err)
+		}
+		defer os.RemoveAll(imagesDirectory)
+
+		sizes = make(map[string]int64)
+		err = decompressCheckpoint(ctx, storage, path, imagesDirectory, func(header *tar.Header) bool {
+			name := filepath.Base(header.Name)
+			sizes[name] = header.Size
+			return !inspectSkipPattern.MatchString(name) && !inspect.GPU_IMAGE_PATTERN.MatchString(name)
+		})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%v", err)
+		}
+	}
+
+	resp := &daemon.InspectCheckpointResp{
+		Checkpoint: checkpoint,
+		Views:      make(map[string]string),
+	}
+
+	state := &daemon.ProcessState{}
+	err = utils.LoadJSONFromFile(filepath.Join(imagesDirectory, process.STATE_FILE), state)
+	if err != nil {
+		resp.Messages = append(resp.Messages, fmt.Sprintf("No process state found: %v", err))
+	} else {
+		resp.State = state
+	}
+
+	for _, t := range views {
+		view, err := inspect.View(imagesDirectory, t, resp.GetState(), sizes)
+		if err != nil {
+			resp.Messages = append(resp.Messages, fmt.Sprintf("Failed to inspect %s: %v", t, err))
+			continue
+		}
+		data, err := json.Marshal(view)
+		if err != nil {
+			resp.Messages = append(resp.Messages, fmt.Sprintf("Failed to marshal %s: %v", t, err))
+			continue
+		}
+		resp.Views[t] = string(data)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,213 @@
+package inspect
+
+// Comparison of the views of two checkpoints, e.g. to understand why
+// checkpoints of a job grow over time
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/go-criu/v7/crit"
+)
+
+type Diff struct {
+	Processes  Changes       `json:"processes"`
+	Files      Changes       `json:"files"`
+	Sockets    Changes       `json:"sockets"`
+	Mappings   Changes       `json:"mappings"`
+	Pages      []CountChange `json:"pages,omitempty"` // resident pages of each VMA
+	GPU        []CountChange `json:"gpu,omitempty"`   // size of each GPU image
+	TotalPages CountChange   `json:"total_pages"`
+	TotalGPU   CountChange   `json:"total_gpu"`
+}
+
+type Changes struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+type CountChange struct {
+	Key    string `json:"key"`
+	Before int64  `json:"before"`
+	After  int64  `json:"after"`
+}
+
+// Compare returns the changes from the views of one checkpoint to those of another.
+// If both checkpoints have their process state saved, the process tree (with cmdlines) and
+// open files are compared from it, else from the images.
+func Compare(before, after *Views) *Diff {
+	pagesBefore, pagesAfter := pages(before.Rss), pages(after.Rss)
+	gpuBefore, gpuAfter := gpuSizes(before.GPU), gpuSizes(after.GPU)
+
+	processesBefore, processesAfter := processes(before.Ps), processes(after.Ps)
+	filesBefore, filesAfter := files(before.Fds), files(after.Fds)
+	if before.State != nil && after.State != nil {
+		processesBefore, processesAfter = stateProcesses(before.State), stateProcesses(after.State)
+		filesBefore, filesAfter = stateFiles(before.State), stateFiles(after.State)
+	}
+
+	return &Diff{
+		Processes: changes(processesBefore, processesAfter),
+		Files:     changes(filesBefore, filesAfter),
+		Sockets:   changes(sockets(before.Sks), sockets(after.Sks)),
+		Mappings:  changes(mappings(before.Mems), mappings(after.Mems)),
+		Pages:     countChanges(pagesBefore, pagesAfter),
+		GPU:       countChanges(gpuBefore, gpuAfter),
+		TotalPages: CountChange{
+			Key:    "pages",
+			Before: sum(pagesBefore),
+			After:  sum(pagesAfter),
+		},
+		TotalGPU: CountChange{
+			Key:    "gpu",
+			Before: sum(gpuBefore),
+			After:  sum(gpuAfter),
+		},
+	}
+}
+
+func (c Changes) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+func processes(tree *crit.PsTree) []string {
+	if tree == nil {
+		return nil
+	}
+	keys := []string{fmt.Sprintf("PID %d (%s)", tree.PID, tree.Comm)}
+	for _, child := range tree.Children {
+		keys = append(keys, processes(child)...)
+	}
+	return keys
+}
+
+// Processes in the state tree, keyed by PID and cmdline. The root has no PID if the
+// state holds multiple trees, e.g. for a SLURM job.
+func stateProcesses(state *daemon.ProcessState) (keys []string) {
+	if state.GetPID() != 0 {
+		keys = append(keys, fmt.Sprintf("PID %d (%s)", state.GetPID(), state.GetCmdline()))
+	}
+	for _, child := range state.GetChildren() {
+		keys = append(keys, stateProcesses(child)...)
+	}
+	return keys
+}
+
+func stateFiles(state *daemon.ProcessState) (keys []string) {
+	for _, file := range state.GetOpenFiles() {
+		keys = append(keys, fmt.Sprintf("PID %d fd %d: %s", state.GetPID(), file.GetFd(), file.GetPath()))
+	}
+	for _, child := range state.GetChildren() {
+		keys = append(keys, stateFiles(child)...)
+	}
+	return keys
+}
+
+func files(fds []*crit.Fd) (keys []string) {
+	for _, fd := range fds {
+		for _, file := range fd.Files {
+			keys = append(keys, fmt.Sprintf("PID %d fd %s: %s", fd.PId, file.Fd, file.Path))
+		}
+	}
+	return keys
+}
+
+func sockets(sks []*crit.Sk) (keys []string) {
+	for _, sk := range sks {
+		for _, s := range sk.Sockets {
+			key := fmt.Sprintf("PID %d fd %d: %s %s %s", sk.PId, s.Fd, s.FdType, s.Family, s.Protocol)
+			if s.SrcAddr != "" || s.DestAddr != "" {
+				key += fmt.Sprintf(" %s:%d -> %s:%d", s.SrcAddr, s.SrcPort, s.DestAddr, s.DestPort)
+			}
+			if s.State != "" {
+				key += fmt.Sprintf(" (%s)", s.State)
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func mappings(mems []*crit.MemMap) (keys []string) {
+	for _, mem := range mems {
+		for _, m := range mem.Mems {
+			keys = append(keys, fmt.Sprintf("PID %d %s-%s %s %s", mem.PId, m.Start, m.End, m.Protection, m.Resource))
+		}
+	}
+	return keys
+}
+
+func pages(rss []*crit.RssMap) map[string]int64 {
+	counts := make(map[string]int64)
+	for _, r := range rss {
+		for _, rs := range r.Rsses {
+			for _, vma := range rs.Vmas {
+				counts[fmt.Sprintf("PID %d %s %s", r.PId, vma.Addr, rs.Resource)] += vma.Pages
+			}
+		}
+	}
+	return counts
+}
+
+func gpuSizes(gpu *GPU) map[string]int64 {
+	sizes := make(map[string]int64)
+	if gpu == nil {
+		return sizes
+	}
+	for _, file := range gpu.Files {
+		sizes[file.Name] = file.Size
+	}
+	return sizes
+}
+
+func changes(before, after []string) (c Changes) {
+	inBefore := make(map[string]bool, len(before))
+	for _, key := range before {
+		inBefore[key] = true
+	}
+	inAfter := make(map[string]bool, len(after))
+	for _, key := range after {
+		inAfter[key] = true
+		if !inBefore[key] {
+			c.Added = append(c.Added, key)
+		}
+	}
+	for _, key := range before {
+		if !inAfter[key] {
+			c.Removed = append(c.Removed, key)
+		}
+	}
+	slices.Sort(c.Added)
+	slices.Sort(c.Removed)
+	return c
+}
+
+func countChanges(before, after map[string]int64) (c []CountChange) {
+	keys := slices.Sorted(maps.Keys(after))
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if before[key] != after[key] {
+			c = append(c, CountChange{Key: key, Before: before[key], After: after[key]})
+		}
+	}
+	return c
+}
+
+func sum(counts map[string]int64) (total int64) {
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
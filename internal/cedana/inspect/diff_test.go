@@ -0,0 +1,114 @@
+package inspect
+
+import (
+	"testing"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/go-criu/v7/crit"
+)
+
+func TestCompare(t *testing.T) {
+	before := &Views{
+		Ps: &crit.PsTree{PID: 1, Comm: "init", Children: []*crit.PsTree{{PID: 2, Comm: "worker"}}},
+		Fds: []*crit.Fd{{PId: 1, Files: []*crit.File{
+			{Fd: "0", Path: "/dev/null"},
+			{Fd: "3", Path: "/tmp/a"},
+		}}},
+		Rss: []*crit.RssMap{{PId: 1, Rsses: []*crit.Rss{{Vmas: []*crit.Vma{{Addr: "1000", Pages: 4}}}}}},
+		GPU: &GPU{Enabled: true, Files: []*GPUFile{{Name: "gpu-mem", Size: 100}}},
+	}
+	after := &Views{
+		Ps: &crit.PsTree{PID: 1, Comm: "init", Children: []*crit.PsTree{{PID: 3, Comm: "worker"}}},
+		Fds: []*crit.Fd{{PId: 1, Files: []*crit.File{
+			{Fd: "0", Path: "/dev/null"},
+			{Fd: "4", Path: "/tmp/b"},
+		}}},
+		Rss: []*crit.RssMap{{PId: 1, Rsses: []*crit.Rss{{Vmas: []*crit.Vma{{Addr: "1000", Pages: 10}, {Addr: "2000", Pages: 1}}}}}},
+		GPU: &GPU{Enabled: true, Files: []*GPUFile{{Name: "gpu-mem", Size: 300}}},
+	}
+
+	diff := Compare(before, after)
+
+	if len(diff.Processes.Added) != 1 || diff.Processes.Added[0] != "PID 3 (worker)" {
+		t.Errorf("unexpected added processes: %v", diff.Processes.Added)
+	}
+	if len(diff.Processes.Removed) != 1 || diff.Processes.Removed[0] != "PID 2 (worker)" {
+		t.Errorf("unexpected removed processes: %v", diff.Processes.Removed)
+	}
+	if len(diff.Files.Added) != 1 || len(diff.Files.Removed) != 1 {
+		t.Errorf("unexpected file changes: %+v", diff.Files)
+	}
+	if !diff.Sockets.IsEmpty() || !diff.Mappings.IsEmpty() {
+		t.Errorf("expected no socket or mapping changes")
+	}
+	if len(diff.Pages) != 2 {
+		t.Errorf("expected 2 VMA page changes, got %v", diff.Pages)
+	}
+	if diff.TotalPages.Before != 4 || diff.TotalPages.After != 11 {
+		t.Errorf("unexpected total pages: %+v", diff.TotalPages)
+	}
+	if len(diff.GPU) != 1 || diff.TotalGPU.After != 300 {
+		t.Errorf("unexpected GPU changes: %v", diff.GPU)
+	}
+}
+
+func TestCompareState(t *testing.T) {
+	before := &Views{
+		Ps: &crit.PsTree{PID: 1, Comm: "sh"},
+		State: &daemon.ProcessState{
+			PID:       1,
+			Cmdline:   "sh -c train",
+			OpenFiles: []*daemon.File{{Fd: 3, Path: "/data/a"}},
+			Children:  []*daemon.ProcessState{{PID: 2, Cmdline: "python train.py --epoch 1"}},
+		},
+	}
+	after := &Views{
+		Ps: &crit.PsTree{PID: 1, Comm: "sh"},
+		State: &daemon.ProcessState{
+			PID:       1,
+			Cmdline:   "sh -c train",
+			OpenFiles: []*daemon.File{{Fd: 3, Path: "/data/a"}},
+			Children: []*daemon.ProcessState{{
+				PID:       2,
+				Cmdline:   "python train.py --epoch 2",
+				OpenFiles: []*daemon.File{{Fd: 4, Path: "/data/b"}},
+			}},
+		},
+	}
+
+	diff := Compare(before, after)
+
+	if len(diff.Processes.Added) != 1 || diff.Processes.Added[0] != "PID 2 (python train.py --epoch 2)" {
+		t.Errorf("unexpected added processes: %v", diff.Processes.Added)
+	}
+	if len(diff.Processes.Removed) != 1 || diff.Processes.Removed[0] != "PID 2 (python train.py --epoch 1)" {
+		t.Errorf("unexpected removed processes: %v", diff.Processes.Removed)
+	}
+	if len(diff.Files.Added) != 1 || diff.Files.Added[0] != "PID 2 fd 4: /data/b" || len(diff.Files.Removed) != 0 {
+		t.Errorf("unexpected file changes: %+v", diff.Files)
+	}
+}
+
+func TestCompareStateMissing(t *testing.T) {
+	before := &Views{
+		Ps:    &crit.PsTree{PID: 1, Comm: "sh"},
+		State: &daemon.ProcessState{PID: 1, Cmdline: "sh -c train"},
+	}
+	after := &Views{
+		Ps: &crit.PsTree{PID: 1, Comm: "sh"},
+	}
+
+	diff := Compare(before, after)
+
+	if !diff.Processes.IsEmpty() || !diff.Files.IsEmpty() {
+		t.Errorf("expected images to be compared if a state is missing, got %+v", diff)
+	}
+}
+
+func TestCompareEmpty(t *testing.T) {
+	diff := Compare(&Views{}, &Views{})
+
+	if !diff.Processes.IsEmpty() || !diff.Files.IsEmpty() || len(diff.Pages) != 0 || len(diff.GPU) != 0 {
+		t.Errorf("expected no changes, got %+v", diff)
+	}
+}
@@ -0,0 +1,122 @@
+package inspect
+
+// Views of checkpoint images, for inspection and comparison
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/go-criu/v7/crit"
+)
+
+// Types of views that can be built from checkpoint images
+var TYPES = []string{"ps", "fd", "mem", "rss", "sk", "gpu"}
+
+// Images dumped by the GPU plugin, for the GPU view
+var GPU_IMAGE_PATTERN = regexp.MustCompile(`^gpu-(checkpoint|hostmem|hostmem-metadata)-\d+$`)
+
+// GPU images are opaque to us, so only their sizes are known
+type GPU struct {
+	Enabled bool       `json:"enabled"`
+	ID      string     `json:"id,omitempty"`
+	Files   []*GPUFile `json:"files,omitempty"`
+}
+
+type GPUFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// Views holds all the parsed views of a checkpoint, along with its saved process state.
+// Views that are not available are nil.
+type Views struct {
+	Ps    *crit.PsTree
+	Fds   []*crit.Fd
+	Mems  []*crit.MemMap
+	Rss   []*crit.RssMap
+	Sks   []*crit.Sk
+	GPU   *GPU
+	State *daemon.ProcessState
+}
+
+// View builds the view of the specified type from the images directory. The process state
+// saved with the checkpoint, if any, is used for the GPU view. If sizes of the images are
+// given by name (e.g. from the checkpoint tarball), they are used instead of the directory's.
+func View(imagesDirectory string, t string, state *daemon.ProcessState, sizes map[string]int64) (any, error) {
+	critter := crit.New(nil, nil, imagesDirectory, false, true)
+
+	switch t {
+	case "ps":
+		return critter.ExplorePs()
+	case "fd":
+		return critter.ExploreFds()
+	case "mem":
+		return critter.ExploreMems()
+	case "rss":
+		return critter.ExploreRss()
+	case "sk":
+		return critter.ExploreSk()
+	case "gpu":
+		view := &GPU{
+			Enabled: state.GetGPUEnabled(),
+			ID:      state.GetGPUID(),
+		}
+		if !view.Enabled {
+			return view, nil
+		}
+		if sizes == nil {
+			entries, err := os.ReadDir(imagesDirectory)
+			if err != nil {
+				return nil, err
+			}
+			sizes = make(map[string]int64)
+			for _, entry := range entries {
+				info, err := entry.Info()
+				if err != nil {
+					return nil, err
+				}
+				sizes[entry.Name()] = info.Size()
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(sizes)) {
+			if GPU_IMAGE_PATTERN.MatchString(name) {
+				view.Files = append(view.Files, &GPUFile{Name: name, Size: sizes[name]})
+			}
+		}
+		return view, nil
+	}
+
+	return nil, fmt.Errorf("unknown type '%s'", t)
+}
+
+// ParseViews parses the JSON-encoded views, keyed by type, and the process state, as returned
+// by checkpoint inspection.
+func ParseViews(views map[string]string, state *daemon.ProcessState) (*Views, error) {
+	parsed := &Views{State: state}
+
+	targets := map[string]any{
+		"ps":  &parsed.Ps,
+		"fd":  &parsed.Fds,
+		"mem": &parsed.Mems,
+		"rss": &parsed.Rss,
+		"sk":  &parsed.Sks,
+		"gpu": &parsed.GPU,
+	}
+
+	for t, data := range views {
+		target, ok := targets[t]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal([]byte(data), target); err != nil {
+			return nil, fmt.Errorf("failed to parse %s view: %w", t, err)
+		}
+	}
+
+	return parsed, nil
+}
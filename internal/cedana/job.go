@@ -3,6 +3,8 @@ package cedana
 import (
 	"context"
 	"fmt"
+	"strings"
+	"syscall"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"github.com/cedana/cedana/internal/cedana/job"
@@ -64,6 +66,20 @@ func (s *Server) Kill(ctx context.Context, req *daemon.KillReq) (*daemon.KillRes
 				messages = append(messages, fmt.Sprintf("Cannot kill remote job %s", job.JID))
 				continue
 			}
+			if req.GetSignal() != 0 {
+				// Goes through the preemptor, in case the job asked to be checkpointed on this signal
+				signal := syscall.Signal(req.GetSignal())
+				paths, err := s.preemptor.Signal(ctx, job.JID, signal)
+				if len(paths) > 0 {
+					messages = append(messages, fmt.Sprintf("Checkpointed job %s to %s", job.JID, strings.Join(paths, ", ")))
+				}
+				if err != nil {
+					messages = append(messages, fmt.Sprintf("Failed to signal job %s: %v", job.JID, err))
+					continue
+				}
+				messages = append(messages, fmt.Sprintf("Sent %s to job %s", signal, job.JID))
+				continue
+			}
 			err := s.jobs.Kill(ctx, job.JID)
 			if err != nil {
 				messages = append(messages, fmt.Sprintf("Failed to kill job %s: %v", job.JID, err))
@@ -95,6 +111,12 @@ func (s *Server) Delete(ctx context.Context, req *daemon.DeleteReq) (*daemon.Del
 			messages = append(messages, fmt.Sprintf("Cannot delete remote job %s", job.JID))
 			continue
 		}
+		if err := s.scheduler.Remove(ctx, job.JID); err != nil {
+			messages = append(messages, fmt.Sprintf("Failed to remove checkpoint schedule of job %s: %v", job.JID, err))
+		}
+		if err := s.restarter.Remove(ctx, job.JID); err != nil {
+			messages = append(messages, fmt.Sprintf("Failed to remove restart policy of job %s: %v", job.JID, err))
+		}
 		messages = append(messages, fmt.Sprintf("Deleted job %s", job.JID))
 		s.jobs.Delete(job.JID)
 	}
@@ -136,6 +158,13 @@ func (s *Server) DeleteCheckpoint(ctx context.Context, req *daemon.DeleteCheckpo
 		return nil, status.Errorf(codes.NotFound, "checkpoint not found")
 	}
 
+	// Don't delete checkpoints that others were dumped incrementally on top of
+	for _, other := range s.jobs.ListCheckpoints(checkpoint.GetJID()) {
+		if other.GetParentID() == checkpoint.GetID() {
+			return nil, status.Errorf(codes.FailedPrecondition, "checkpoint is the parent of checkpoint %s, please delete it first", other.GetID())
+		}
+	}
+
 	s.jobs.DeleteCheckpoint(req.GetID())
 
 	return &daemon.DeleteCheckpointResp{}, nil
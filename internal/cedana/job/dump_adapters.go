@@ -3,9 +3,12 @@ package job
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
 	"github.com/cedana/cedana/pkg/types"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -45,17 +48,37 @@ func ManageDump(jobs Manager) types.Adapter[types.Dump] {
 			proto.Merge(mergedDetails, req.GetDetails())
 			req.Details = mergedDetails
 
+			// Job exits once dumped (unless left running), and is then not to be restarted.
+			// Whether it's left running is only known once defaults are filled in.
+
+			job.SetStopped(true)
+
+			jobs.Publish(&daemon.Event{Type: EVENT_CHECKPOINT_STARTED, JID: jid, PID: job.GetPID()})
+			start := time.Now()
+
 			code, err = next(ctx, opts, resp, req)
+
+			exits := !req.Criu.GetLeaveRunning() && !req.Criu.GetLeaveStopped()
+			if err != nil || !exits {
+				job.SetStopped(false)
+			}
 			if err != nil {
+				event := checkpointEvent(ctx, EVENT_CHECKPOINT_FAILED, jid, start)
+				event.Error = err.Error()
+				jobs.Publish(event)
 				return code, err
 			}
 
 			job.Sync(ctx, resp.GetState())
 
-			jobs.AddCheckpoint(jid, resp.GetPaths())
+			jobs.AddCheckpoint(jid, resp.GetPaths(), req.GetParentID())
+
+			event := checkpointEvent(ctx, EVENT_CHECKPOINT_COMPLETED, jid, start)
+			event.Paths = resp.GetPaths()
+			jobs.Publish(event)
 
 			// Wait for job exit & cleanup
-			if !req.Criu.GetLeaveRunning() {
+			if exits {
 				if err := <-jobs.Done(jid); err != nil {
 					resp.Messages = append(resp.Messages, err.Error())
 				}
@@ -65,3 +88,42 @@ func ManageDump(jobs Manager) types.Adapter[types.Dump] {
 		}
 	}
 }
+
+// Adapter that resolves the parent checkpoint of an incremental dump, and points
+// CRIU to its images, so that only pages dirtied since the parent are dumped.
+func ResolveParentForDump(jobs Manager) types.Adapter[types.Dump] {
+	return func(next types.Dump) types.Dump {
+		return func(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daemon.DumpReq) (code func() <-chan int, err error) {
+			id := req.GetParentID()
+
+			parent := jobs.GetCheckpoint(id)
+			if parent == nil {
+				return nil, status.Errorf(codes.NotFound, "parent checkpoint %s not found", id)
+			}
+
+			if jid := req.GetDetails().GetJID(); jid != "" && parent.GetJID() != jid {
+				return nil, status.Errorf(codes.InvalidArgument, "parent checkpoint %s belongs to job %s, not %s", id, parent.GetJID(), jid)
+			}
+
+			path := parent.GetPath()
+			if stat, err := os.Stat(path); err != nil || !stat.IsDir() {
+				return nil, status.Errorf(
+					codes.FailedPrecondition,
+					"parent checkpoint %s is not an uncompressed local directory: %s", id, path,
+				)
+			}
+			path, err = filepath.Abs(path)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to resolve parent checkpoint path: %v", err)
+			}
+
+			if req.Criu == nil {
+				req.Criu = &criu_proto.CriuOpts{}
+			}
+			req.Criu.ParentImg = proto.String(path)
+			req.Criu.TrackMem = proto.Bool(true)
+
+			return next(ctx, opts, resp, req)
+		}
+	}
+}
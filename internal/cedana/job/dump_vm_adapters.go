@@ -0,0 +1,105 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/pkg/config"
+	"github.com/cedana/cedana/pkg/keys"
+	"github.com/cedana/cedana/pkg/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Adapter that fills in VM dump request details based on saved job info.
+// If the job does not exist yet, the VM is registered as a new job, so it can be
+// managed like any other job from here on. A diff dump is taken on top of the job's
+// latest checkpoint, which is saved as its parent. Post-dump, saves the checkpoint.
+func ManageDumpVM(jobs Manager) types.Adapter[types.DumpVM] {
+	return func(next types.DumpVM) types.DumpVM {
+		return func(ctx context.Context, opts types.Opts, resp *daemon.DumpVMResp, req *daemon.DumpVMReq) (code func() <-chan int, err error) {
+			jid := req.GetDetails().GetJID()
+
+			if jid == "" {
+				return nil, status.Errorf(codes.InvalidArgument, "missing JID for managed VM dump")
+			}
+
+			job := jobs.Get(ctx, jid)
+			register := job == nil
+
+			if register {
+				if req.GetType() == "" || req.GetDetails().GetKata() == nil {
+					return nil, status.Errorf(codes.NotFound, "job %s not found", jid)
+				}
+				job, err = jobs.New(jid, req.GetType())
+				if err != nil {
+					return nil, status.Errorf(codes.Internal, "failed to create new job: %v", err)
+				}
+				job.SetDetails(req.GetDetails())
+			} else {
+				if !job.IsRunning() {
+					return nil, status.Errorf(codes.FailedPrecondition, "job %s is not running (status: %s)", jid, job.Status())
+				}
+
+				// Use saved job details, but allow overriding from request
+
+				req.Type = job.GetType()
+				mergedDetails := proto.Clone(job.GetDetails()).(*daemon.Details)
+				proto.Merge(mergedDetails, req.GetDetails())
+				req.Details = mergedDetails
+			}
+
+			var parentID string
+			if req.GetDiff() {
+				parent := jobs.GetLatestCheckpoint(jid)
+				if parent == nil {
+					return nil, status.Errorf(codes.FailedPrecondition, "job %s has no checkpoint to take a diff dump on, take a full dump first", jid)
+				}
+				parentID = parent.GetID()
+				ctx = context.WithValue(ctx, keys.VM_SNAPSHOT_PARENT_CONTEXT_KEY, parent.GetPath())
+			}
+
+			// Each checkpoint of a job gets its own directory, as the VMM refuses
+			// to snapshot into a directory that already has one.
+
+			dir := req.GetDir()
+			if dir == "" {
+				dir = config.Global.Checkpoint.Dir
+			}
+			dumpDir := filepath.Join(dir, fmt.Sprintf("dump-vm-%s-%s-%d", req.Type, jid, time.Now().Unix()))
+			if err := os.MkdirAll(dumpDir, 0o755); err != nil {
+				if register {
+					jobs.Delete(jid)
+				}
+				return nil, status.Errorf(codes.Internal, "failed to create dump dir: %v", err)
+			}
+			req.Dir = dumpDir
+
+			code, err = next(ctx, opts, resp, req)
+			if err != nil {
+				os.RemoveAll(dumpDir)
+				if register {
+					jobs.Delete(jid)
+				}
+				return code, err
+			}
+
+			if register {
+				err = jobs.Manage(opts.Lifetime, jid, resp.GetPID(), code())
+				if err != nil {
+					jobs.Delete(jid)
+					return nil, status.Errorf(codes.Internal, "failed to manage VM job: %v", err)
+				}
+			}
+
+			jobs.AddCheckpoint(jid, []string{dumpDir}, parentID)
+
+			return code, nil
+		}
+	}
+}
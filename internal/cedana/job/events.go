@@ -0,0 +1,118 @@
+package job
+
+// Implements a broker for job events, so clients can watch for changes to jobs instead of
+// polling. Recent events are kept in memory, so new watchers can catch up on what they missed.
+// Watchers that fall too far behind are dropped, instead of blocking the daemon.
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/pkg/keys"
+	"github.com/cedana/cedana/pkg/profiling"
+)
+
+const (
+	EVENT_CREATED              = "created"
+	EVENT_STARTED              = "started"
+	EVENT_EXITED               = "exited"
+	EVENT_RESTORED             = "restored"
+	EVENT_DELETED              = "deleted"
+	EVENT_CHECKPOINT_STARTED   = "checkpoint-started"
+	EVENT_CHECKPOINT_COMPLETED = "checkpoint-completed"
+	EVENT_CHECKPOINT_FAILED    = "checkpoint-failed"
+
+	EVENT_HISTORY = 256 // recent events kept for new watchers
+	EVENT_BUFFER  = 64  // events buffered per watcher, before it's dropped
+)
+
+var EventTypes = []string{
+	EVENT_CREATED,
+	EVENT_STARTED,
+	EVENT_EXITED,
+	EVENT_RESTORED,
+	EVENT_DELETED,
+	EVENT_CHECKPOINT_STARTED,
+	EVENT_CHECKPOINT_COMPLETED,
+	EVENT_CHECKPOINT_FAILED,
+}
+
+type Events struct {
+	mu       sync.Mutex
+	history  []*daemon.Event
+	watchers map[chan *daemon.Event]struct{}
+}
+
+func NewEvents() *Events {
+	return &Events{watchers: make(map[chan *daemon.Event]struct{})}
+}
+
+/////////////////
+//// Methods ////
+/////////////////
+
+// Publish sends the event to all watchers, stamping it with the current time if not set.
+func (e *Events) Publish(event *daemon.Event) {
+	if event.Time == 0 {
+		event.Time = time.Now().UnixMilli()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.history = append(e.history, event)
+	if len(e.history) > EVENT_HISTORY {
+		e.history[0] = nil
+		e.history = e.history[1:]
+	}
+
+	for watcher := range e.watchers {
+		select {
+		case watcher <- event:
+		default:
+			delete(e.watchers, watcher)
+			close(watcher)
+		}
+	}
+}
+
+// Watch returns recent events, and a channel of new events that is closed once the
+// context is done, or if the watcher falls too far behind.
+func (e *Events) Watch(ctx context.Context) (recent []*daemon.Event, events <-chan *daemon.Event) {
+	watcher := make(chan *daemon.Event, EVENT_BUFFER)
+
+	e.mu.Lock()
+	recent = append([]*daemon.Event(nil), e.history...)
+	e.watchers[watcher] = struct{}{}
+	e.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if _, ok := e.watchers[watcher]; ok {
+			delete(e.watchers, watcher)
+			close(watcher)
+		}
+	}()
+
+	return recent, watcher
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Returns a checkpoint event for the job, summarizing the profiling data in the context.
+func checkpointEvent(ctx context.Context, typ string, jid string, start time.Time) *daemon.Event {
+	data, _ := ctx.Value(keys.PROFILING_CONTEXT_KEY).(*profiling.Data)
+
+	return &daemon.Event{
+		Type:     typ,
+		JID:      jid,
+		Duration: time.Since(start).Nanoseconds(),
+		IO:       profiling.TotalIO(data),
+	}
+}
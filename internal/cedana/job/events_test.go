@@ -0,0 +1,83 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+)
+
+func TestEventsWatch(t *testing.T) {
+	e := NewEvents()
+
+	e.Publish(&daemon.Event{Type: EVENT_CREATED, JID: "job"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	recent, events := e.Watch(ctx)
+
+	if len(recent) != 1 || recent[0].GetType() != EVENT_CREATED {
+		t.Fatalf("expected the created event as recent, got %v", recent)
+	}
+	if recent[0].GetTime() == 0 {
+		t.Errorf("expected event to be stamped with the time")
+	}
+
+	e.Publish(&daemon.Event{Type: EVENT_STARTED, JID: "job", PID: 42})
+
+	select {
+	case event := <-events:
+		if event.GetType() != EVENT_STARTED || event.GetPID() != 42 {
+			t.Errorf("expected started event with PID 42, got %v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected no more events once context is done")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for events to be closed")
+	}
+}
+
+func TestEventsHistory(t *testing.T) {
+	e := NewEvents()
+
+	for i := range EVENT_HISTORY + 10 {
+		e.Publish(&daemon.Event{Type: EVENT_CREATED, JID: fmt.Sprint(i)})
+	}
+
+	recent, _ := e.Watch(t.Context())
+
+	if len(recent) != EVENT_HISTORY {
+		t.Fatalf("expected %d recent events, got %d", EVENT_HISTORY, len(recent))
+	}
+	if recent[0].GetJID() != "10" {
+		t.Errorf("expected oldest events to be dropped first, got %s as oldest", recent[0].GetJID())
+	}
+}
+
+func TestEventsSlowWatcher(t *testing.T) {
+	e := NewEvents()
+
+	_, events := e.Watch(t.Context())
+
+	for range EVENT_BUFFER + 1 {
+		e.Publish(&daemon.Event{Type: EVENT_CREATED, JID: "job"})
+	}
+
+	n := 0
+	for range events {
+		n++
+	}
+	if n != EVENT_BUFFER {
+		t.Errorf("expected %d buffered events before being dropped, got %d", EVENT_BUFFER, n)
+	}
+}
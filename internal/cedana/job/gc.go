@@ -0,0 +1,207 @@
+package job
+
+// Implements garbage collection of checkpoints, deleting both the checkpoint records and
+// the underlying objects in storage, based on retention policies.
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/pkg/io"
+	"github.com/rs/zerolog/log"
+)
+
+// GCPolicy specifies which checkpoints are collected. Zero values disable the respective policy.
+type GCPolicy struct {
+	MaxCount int           // max number of checkpoints to keep per job
+	MaxAge   time.Duration // max age of a checkpoint
+	MaxBytes int64         // max total size of collectable checkpoints under each storage prefix
+}
+
+func (p GCPolicy) IsZero() bool {
+	return p.MaxCount <= 0 && p.MaxAge <= 0 && p.MaxBytes <= 0
+}
+
+type GC struct {
+	jobs    Manager
+	hostID  string
+	storage StorageFunc
+}
+
+// NewGC creates a new checkpoint garbage collector, for checkpoints of jobs on the given host.
+// Storage for deleting checkpoints is looked up using the provided storage function.
+func NewGC(jobs Manager, hostID string, storage StorageFunc) *GC {
+	return &GC{
+		jobs:    jobs,
+		hostID:  hostID,
+		storage: storage,
+	}
+}
+
+/////////////////
+//// Methods ////
+/////////////////
+
+// Collect deletes checkpoints that violate the given policy, and returns them. Checkpoints that
+// others are dumped incrementally on top of are only collected along with them. If dry run,
+// only returns the checkpoints that would be collected. Optionally filtered by JID, in which
+// case all policies, including the max total size, only apply to checkpoints of those jobs.
+func (gc *GC) Collect(ctx context.Context, policy GCPolicy, dryRun bool, jids ...string) ([]*daemon.Checkpoint, error) {
+	if policy.IsZero() {
+		return nil, fmt.Errorf("no GC policy specified")
+	}
+
+	jidSet := make(map[string]any)
+	for _, jid := range jids {
+		jidSet[jid] = nil
+	}
+
+	var checkpoints []*daemon.Checkpoint
+	for _, job := range gc.jobs.ListByHostIDs(ctx, gc.hostID) {
+		if _, ok := jidSet[job.JID]; len(jids) > 0 && !ok {
+			continue
+		}
+		checkpoints = append(checkpoints, gc.jobs.ListCheckpoints(job.JID)...)
+	}
+
+	// Newest first, so older checkpoints are the first to go
+	slices.SortFunc(checkpoints, func(a, b *daemon.Checkpoint) int {
+		return cmp.Compare(b.GetTime(), a.GetTime())
+	})
+
+	collect := make(map[string]bool)
+
+	if policy.MaxAge > 0 {
+		oldest := time.Now().Add(-policy.MaxAge).UnixMilli()
+		for _, checkpoint := range checkpoints {
+			if checkpoint.GetTime() < oldest {
+				collect[checkpoint.GetID()] = true
+			}
+		}
+	}
+
+	if policy.MaxCount > 0 {
+		count := make(map[string]int)
+		for _, checkpoint := range checkpoints {
+			count[checkpoint.GetJID()]++
+			if count[checkpoint.GetJID()] > policy.MaxCount {
+				collect[checkpoint.GetID()] = true
+			}
+		}
+	}
+
+	if policy.MaxBytes > 0 {
+		bytes := make(map[string]int64)
+		for _, checkpoint := range checkpoints {
+			if collect[checkpoint.GetID()] {
+				continue
+			}
+			prefix := storagePrefix(checkpoint.GetPath())
+			bytes[prefix] += checkpoint.GetSize()
+			if bytes[prefix] > policy.MaxBytes {
+				collect[checkpoint.GetID()] = true
+			}
+		}
+	}
+
+	// Keep the whole parent chain of every checkpoint that is kept
+	byID := make(map[string]*daemon.Checkpoint)
+	for _, checkpoint := range checkpoints {
+		byID[checkpoint.GetID()] = checkpoint
+	}
+	for _, checkpoint := range checkpoints {
+		if collect[checkpoint.GetID()] {
+			continue
+		}
+		for parent := byID[checkpoint.GetParentID()]; parent != nil; parent = byID[parent.GetParentID()] {
+			delete(collect, parent.GetID())
+		}
+	}
+
+	var collected []*daemon.Checkpoint
+	for _, checkpoint := range checkpoints {
+		if collect[checkpoint.GetID()] {
+			collected = append(collected, checkpoint)
+		}
+	}
+	if dryRun {
+		return collected, nil
+	}
+
+	return gc.delete(ctx, collected)
+}
+
+////////////////////////
+//// Helper Methods ////
+////////////////////////
+
+// Deletes the checkpoints from storage and their records, returning the ones deleted.
+func (gc *GC) delete(ctx context.Context, checkpoints []*daemon.Checkpoint) ([]*daemon.Checkpoint, error) {
+	var deleted []*daemon.Checkpoint
+	var errs []error
+
+	for _, checkpoint := range checkpoints {
+		storage, err := gc.storage(ctx, checkpoint.GetPath())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("checkpoint %s: %w", checkpoint.GetID(), err))
+			continue
+		}
+		err = storage.Delete(ctx, checkpoint.GetPath())
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("checkpoint %s: %w", checkpoint.GetID(), err))
+			continue
+		}
+		gc.jobs.DeleteCheckpoint(checkpoint.GetID())
+		deleted = append(deleted, checkpoint)
+
+		log.Debug().Str("JID", checkpoint.GetJID()).Str("path", checkpoint.GetPath()).Msg("deleted checkpoint")
+	}
+
+	// Deduplicated checkpoints share chunks, which can only be deleted once no longer referenced
+	swept := make(map[string]bool)
+	for _, checkpoint := range deleted {
+		prefix := storagePrefix(checkpoint.GetPath())
+		if swept[prefix] {
+			continue
+		}
+		swept[prefix] = true
+
+		storage, err := gc.storage(ctx, checkpoint.GetPath())
+		if err != nil {
+			continue
+		}
+		dedup, ok := storage.(*io.DedupStorage)
+		if !ok {
+			continue
+		}
+		count, err := dedup.Sweep(ctx, prefix)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to sweep chunks under %s: %w", prefix, err))
+		}
+		if count > 0 {
+			log.Debug().Str("prefix", prefix).Int("count", count).Msg("swept unreferenced chunks")
+		}
+	}
+
+	return deleted, errors.Join(errs...)
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Returns the storage prefix (dir) of a checkpoint path, which may be a URL
+func storagePrefix(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
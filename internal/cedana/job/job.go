@@ -22,6 +22,10 @@ type Job struct {
 	proto daemon.Job
 	done  chan error
 
+	// Whether the job was stopped on purpose (e.g. killed or dumped), so it's not restarted
+	// on exit. Only kept in memory, and reset every time the job is managed.
+	stopped bool
+
 	sync.RWMutex
 }
 
@@ -51,6 +55,8 @@ func fromProto(j *daemon.Job) *Job {
 			State:   j.GetState(),
 			Details: j.GetDetails(),
 			Log:     j.GetLog(),
+
+			CheckpointOnSignal: j.GetCheckpointOnSignal(),
 		},
 	}
 }
@@ -162,6 +168,30 @@ func (j *Job) SetLog(log string) {
 	j.proto.Log = log
 }
 
+func (j *Job) GetCheckpointOnSignal() *daemon.CheckpointOnSignal {
+	j.RLock()
+	defer j.RUnlock()
+	return proto.CloneOf(j.proto.CheckpointOnSignal)
+}
+
+func (j *Job) SetCheckpointOnSignal(policy *daemon.CheckpointOnSignal) {
+	j.Lock()
+	defer j.Unlock()
+	j.proto.CheckpointOnSignal = proto.CloneOf(policy)
+}
+
+func (j *Job) SetStopped(stopped bool) {
+	j.Lock()
+	defer j.Unlock()
+	j.stopped = stopped
+}
+
+func (j *Job) IsStopped() bool {
+	j.RLock()
+	defer j.RUnlock()
+	return j.stopped
+}
+
 func (j *Job) IsRunning() bool {
 	j.RLock()
 	defer j.RUnlock()
@@ -62,8 +62,9 @@ type Manager interface {
 	//// Checkpoints ////
 	/////////////////////
 
-	// AddCheckpoint adds a checkpoint path to the job.
-	AddCheckpoint(jid string, paths []string)
+	// AddCheckpoint adds a checkpoint path to the job. If the checkpoint was dumped
+	// incrementally, parentID is the ID of the checkpoint it references.
+	AddCheckpoint(jid string, paths []string, parentID string)
 
 	// Get a specific checkpoint.
 	GetCheckpoint(id string) *daemon.Checkpoint
@@ -77,6 +78,17 @@ type Manager interface {
 	// DeleteCheckpoint deletes a checkpoint with the given ID.
 	DeleteCheckpoint(id string)
 
+	////////////////
+	//// Events ////
+	////////////////
+
+	// Publish sends an event about a job to all watchers.
+	Publish(event *daemon.Event)
+
+	// Watch returns recent job events, and a channel of new events that is closed once the
+	// context is done, or if the watcher falls too far behind.
+	Watch(ctx context.Context) (recent []*daemon.Event, events <-chan *daemon.Event)
+
 	//////////////
 	//// Misc ////
 	//////////////
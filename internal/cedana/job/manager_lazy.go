@@ -39,6 +39,7 @@ type ManagerLazy struct {
 	db      db.DB
 	pending chan action
 	sync    sync.Mutex // to protect syncWithDB from concurrent access
+	events  *Events
 
 	wg *sync.WaitGroup // for all manger background routines
 }
@@ -79,6 +80,7 @@ func NewManagerLazy(
 		plugins: plugins,
 		gpus:    gpuManager,
 		db:      db,
+		events:  NewEvents(),
 	}
 
 	err := manager.Sync(lifetime)
@@ -148,6 +150,8 @@ func (m *ManagerLazy) New(jid string, jobType string) (*Job, error) {
 	job := newJob(jid, jobType, m.host)
 	m.jobs.Store(jid, job)
 
+	m.Publish(&daemon.Event{Type: EVENT_CREATED, JID: jid})
+
 	return job, nil
 }
 
@@ -196,6 +200,8 @@ func (m *ManagerLazy) Delete(jid string) {
 	for _, checkpoint := range checkpoints {
 		m.DeleteCheckpoint(checkpoint.ID)
 	}
+
+	m.Publish(&daemon.Event{Type: EVENT_DELETED, JID: jid})
 }
 
 func (m *ManagerLazy) List(ctx context.Context, jids ...string) []*Job {
@@ -284,6 +290,7 @@ func (m *ManagerLazy) Manage(lifetime context.Context, jid string, pid uint32, c
 	log := log.With().Str("JID", jid).Str("type", job.GetType()).Uint32("PID", pid).Logger()
 
 	job.SetPID(pid)
+	job.SetStopped(false)
 	job.SyncDeep(lifetime)
 	job.done = make(chan error, 1)
 
@@ -291,6 +298,8 @@ func (m *ManagerLazy) Manage(lifetime context.Context, jid string, pid uint32, c
 
 	log.Info().Msg("managing job")
 
+	m.Publish(&daemon.Event{Type: EVENT_STARTED, JID: jid, PID: pid})
+
 	m.wg.Go(func() {
 		var exitCode int
 
@@ -303,6 +312,8 @@ func (m *ManagerLazy) Manage(lifetime context.Context, jid string, pid uint32, c
 
 		log.Info().Int("code", exitCode).Msg("job exited")
 
+		m.Publish(&daemon.Event{Type: EVENT_EXITED, JID: jid, PID: pid, ExitCode: int32(exitCode)})
+
 		m.gpus.Detach(lifetime, pid)
 
 		// Check if a clean up handler is available for the job type
@@ -362,7 +373,13 @@ func (m *ManagerLazy) Kill(ctx context.Context, jid string, signal ...syscall.Si
 			target = -int(pgid) // If the job is the process group leader, send signal to the entire process group
 		}
 
-		return syscall.Kill(target, signalToUse)
+		job.SetStopped(true)
+
+		err := syscall.Kill(target, signalToUse)
+		if err != nil {
+			job.SetStopped(false)
+		}
+		return err
 	}
 
 	return fmt.Errorf("job %s is not running, PID %d is not valid", jid, pid)
@@ -378,7 +395,7 @@ func (m *ManagerLazy) Done(jid string) <-chan error {
 	return job.done
 }
 
-func (m *ManagerLazy) AddCheckpoint(jid string, paths []string) {
+func (m *ManagerLazy) AddCheckpoint(jid string, paths []string, parentID string) {
 	job := m.lookup(jid)
 	if job == nil {
 		return
@@ -386,11 +403,12 @@ func (m *ManagerLazy) AddCheckpoint(jid string, paths []string) {
 
 	for _, path := range paths {
 		checkpoint := &daemon.Checkpoint{
-			ID:   uuid.New().String(),
-			JID:  jid,
-			Path: path,
-			Time: time.Now().UnixMilli(),
-			Size: utils.SizeFromPath(path),
+			ID:       uuid.New().String(),
+			JID:      jid,
+			Path:     path,
+			Time:     time.Now().UnixMilli(),
+			Size:     utils.SizeFromPath(path),
+			ParentID: parentID,
 		}
 		m.checkpoints.Store(checkpoint.ID, checkpoint)
 
@@ -448,6 +466,14 @@ func (m *ManagerLazy) DeleteCheckpoint(id string) {
 	m.pending <- action{putCheckpoint, id}
 }
 
+func (m *ManagerLazy) Publish(event *daemon.Event) {
+	m.events.Publish(event)
+}
+
+func (m *ManagerLazy) Watch(ctx context.Context) (recent []*daemon.Event, events <-chan *daemon.Event) {
+	return m.events.Watch(ctx)
+}
+
 func (m *ManagerLazy) Sync(ctx context.Context) error {
 	return m.syncWithDB(ctx, action{initialize, ""})
 }
@@ -0,0 +1,264 @@
+package job
+
+// Implements checkpoint-on-signal for managed jobs. Jobs can opt in to be dumped right before
+// they are sent a given signal (e.g. SIGTERM of a spot instance or SLURM preemption), and only
+// then is the signal delivered, so they are terminated within their grace period with a
+// checkpoint to restore from. Preemption notices can also be picked up from a local hook
+// file or endpoint, which signals all opted-in jobs on the host.
+//
+// NOTE: Only signals sent through the daemon (i.e. killing the job, or a preemption notice)
+// are intercepted. A signal sent straight to the job's process, e.g. by kill(1) or a
+// scheduler, is delivered as usual without a dump, as there is no way to hold it back.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	IMDS_TOKEN_PATH       = "/latest/api/token"
+	IMDS_TOKEN_HEADER     = "X-aws-ec2-metadata-token"
+	IMDS_TOKEN_TTL_HEADER = "X-aws-ec2-metadata-token-ttl-seconds"
+	IMDS_TOKEN_TTL        = 6 * time.Hour
+)
+
+// PreemptionPolicy specifies where preemption notices are picked up from.
+// Zero values disable the respective source.
+type PreemptionPolicy struct {
+	Interval time.Duration // how often the sources are polled
+	File     string        // hook file, whose existence is a notice
+	URL      string        // endpoint, for which a 200 response (other than 'false') is a notice
+
+	Compression string
+}
+
+func (p PreemptionPolicy) IsZero() bool {
+	return p.File == "" && p.URL == ""
+}
+
+type Preemptor struct {
+	jobs   Manager
+	hostID string
+	dump   DumpFunc
+	policy PreemptionPolicy
+	client *http.Client
+
+	token        string // IMDSv2 session token, if the URL is of an EC2 instance metadata service
+	tokenExpires time.Time
+}
+
+// NewPreemptor creates a new preemptor, for jobs on the given host.
+// Dumps are taken using the provided dump function.
+func NewPreemptor(jobs Manager, hostID string, dump DumpFunc, policy PreemptionPolicy) *Preemptor {
+	return &Preemptor{
+		jobs:   jobs,
+		hostID: hostID,
+		dump:   dump,
+		policy: policy,
+		client: &http.Client{Timeout: policy.Interval},
+	}
+}
+
+/////////////////
+//// Methods ////
+/////////////////
+
+// Signal sends the signal to a job. If the job asked to be checkpointed on this signal,
+// it is dumped first and left running, so it can still handle the signal itself.
+// The signal is delivered even if the dump fails, as the job is going away regardless.
+func (p *Preemptor) Signal(ctx context.Context, jid string, signal syscall.Signal) (paths []string, err error) {
+	job := p.jobs.Get(ctx, jid)
+	if job == nil {
+		return nil, fmt.Errorf("job %s does not exist", jid)
+	}
+	if !job.IsRunning() {
+		return nil, fmt.Errorf("job %s is not running", jid)
+	}
+
+	policy := job.GetCheckpointOnSignal()
+
+	if policy != nil && syscall.Signal(policy.GetSignal()) == signal {
+		log := log.With().Str("JID", jid).Str("signal", signal.String()).Logger()
+
+		log.Info().Msg("dumping job before delivering signal")
+
+		resp, dumpErr := p.dump(ctx, &daemon.DumpReq{
+			Dir:         policy.GetDir(),
+			Compression: p.policy.Compression,
+			Details:     &daemon.Details{JID: proto.String(jid)},
+			Criu:        &criu_proto.CriuOpts{LeaveRunning: proto.Bool(true)},
+		})
+		if dumpErr != nil {
+			log.Error().Err(dumpErr).Msg("failed to dump job before delivering signal")
+			err = fmt.Errorf("failed to dump job before signal: %w", dumpErr)
+		} else {
+			paths = resp.GetPaths()
+			log.Info().Strs("paths", paths).Msg("dumped job before delivering signal")
+		}
+	}
+
+	return paths, errors.Join(err, p.jobs.Kill(ctx, jid, signal))
+}
+
+// Watch polls the configured sources for a preemption notice, until the context is done.
+// On a notice, all running jobs on the host that opted in are dumped and signaled, once
+// per notice.
+func (p *Preemptor) Watch(ctx context.Context) {
+	log.Info().Str("interval", p.policy.Interval.String()).Msg("preemption watcher started")
+	defer log.Info().Msg("preemption watcher stopped")
+
+	ticker := time.NewTicker(p.policy.Interval)
+	defer ticker.Stop()
+
+	noticed := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		notice, err := p.notice(ctx)
+		if err != nil {
+			log.Trace().Err(err).Msg("failed to check for preemption notice")
+			continue
+		}
+		if !notice {
+			noticed = false
+			continue
+		}
+		if noticed {
+			continue // already handled, and notice is yet to clear
+		}
+		noticed = true
+
+		log.Warn().Msg("received preemption notice, checkpointing jobs")
+
+		// All jobs share the same grace period, so they are handled concurrently
+
+		wg := &sync.WaitGroup{}
+
+		for _, job := range p.jobs.ListByHostIDs(ctx, p.hostID) {
+			if job.IsRemote() || !job.IsRunning() {
+				continue
+			}
+			policy := job.GetCheckpointOnSignal()
+			if policy == nil {
+				continue
+			}
+
+			wg.Go(func() {
+				_, err := p.Signal(ctx, job.JID, syscall.Signal(policy.GetSignal()))
+				if err != nil {
+					log.Error().Err(err).Str("JID", job.JID).Msg("failed to checkpoint job on preemption")
+				}
+			})
+		}
+
+		wg.Wait()
+	}
+}
+
+////////////////////////
+//// Helper Methods ////
+////////////////////////
+
+// Checks whether any of the configured sources has a preemption notice.
+func (p *Preemptor) notice(ctx context.Context) (bool, error) {
+	if p.policy.File != "" {
+		_, err := os.Stat(p.policy.File)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+
+	if p.policy.URL != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.policy.URL, nil)
+		if err != nil {
+			return false, err
+		}
+		if token := p.imdsToken(ctx); token != "" {
+			req.Header.Set(IMDS_TOKEN_HEADER, token)
+		}
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false, nil
+		}
+
+		// Some endpoints always respond, with whether the instance is being preempted
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if err != nil {
+			return false, err
+		}
+		return !strings.EqualFold(strings.TrimSpace(string(body)), "false"), nil
+	}
+
+	return false, nil
+}
+
+// Returns a session token for an EC2 instance metadata service (IMDSv2), if the URL is of
+// one, renewing it before it expires. Returns empty if a token cannot be had, e.g. with
+// IMDSv1 only, in which case the URL is requested without one.
+func (p *Preemptor) imdsToken(ctx context.Context) string {
+	endpoint, err := url.Parse(p.policy.URL)
+	if err != nil || !strings.HasPrefix(endpoint.Path, "/latest/") {
+		return ""
+	}
+	if p.token != "" && time.Now().Before(p.tokenExpires) {
+		return p.token
+	}
+
+	endpoint.Path = IMDS_TOKEN_PATH
+	endpoint.RawQuery = ""
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint.String(), nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set(IMDS_TOKEN_TTL_HEADER, strconv.Itoa(int(IMDS_TOKEN_TTL.Seconds())))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Trace().Err(err).Msg("failed to get IMDSv2 token")
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	token, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return ""
+	}
+
+	p.token = strings.TrimSpace(string(token))
+	p.tokenExpires = time.Now().Add(IMDS_TOKEN_TTL - time.Minute)
+
+	return p.token
+}
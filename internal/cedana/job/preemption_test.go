@@ -0,0 +1,97 @@
+package job
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPreemptionNoticeFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "preempted")
+	p := NewPreemptor(nil, "", nil, PreemptionPolicy{Interval: time.Second, File: file})
+
+	notice, err := p.notice(context.Background())
+	if err != nil || notice {
+		t.Fatalf("expected no notice without hook file, got %v (err: %v)", notice, err)
+	}
+
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	notice, err = p.notice(context.Background())
+	if err != nil || !notice {
+		t.Fatalf("expected notice with hook file, got %v (err: %v)", notice, err)
+	}
+}
+
+func TestPreemptionNoticeURL(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"not found", http.StatusNotFound, "", false},
+		{"instance action", http.StatusOK, `{"action": "terminate", "time": "2026-10-17T08:22:00Z"}`, true},
+		{"not preempted", http.StatusOK, "FALSE", false},
+		{"preempted", http.StatusOK, "TRUE\n", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(c.status)
+				w.Write([]byte(c.body))
+			}))
+			defer server.Close()
+
+			p := NewPreemptor(nil, "", nil, PreemptionPolicy{Interval: time.Second, URL: server.URL})
+
+			notice, err := p.notice(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if notice != c.want {
+				t.Errorf("expected notice %v, got %v", c.want, notice)
+			}
+		})
+	}
+}
+
+func TestPreemptionNoticeIMDSv2(t *testing.T) {
+	tokens := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == IMDS_TOKEN_PATH:
+			if r.Header.Get(IMDS_TOKEN_TTL_HEADER) == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			tokens++
+			w.Write([]byte("token"))
+		case r.Header.Get(IMDS_TOKEN_HEADER) != "token":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Write([]byte(`{"action": "terminate", "time": "2026-10-17T08:22:00Z"}`))
+		}
+	}))
+	defer server.Close()
+
+	p := NewPreemptor(nil, "", nil, PreemptionPolicy{Interval: time.Second, URL: server.URL + "/latest/meta-data/spot/instance-action"})
+
+	for range 2 {
+		notice, err := p.notice(context.Background())
+		if err != nil || !notice {
+			t.Fatalf("expected notice with IMDSv2 token, got %v (err: %v)", notice, err)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("expected token to be reused, got %d tokens", tokens)
+	}
+}
@@ -0,0 +1,387 @@
+package job
+
+// Implements a memory pressure watcher, that dumps managed jobs when their cgroup is about to
+// run out of memory, so they can be restored elsewhere instead of being OOM killed or evicted.
+// Checkpoints are recorded like for any other dump of the job.
+//
+// Only jobs with a dedicated cgroup are watched, i.e. one with no processes other than the
+// job's, such as containers or jobs run with resource limits. The memory, PSI and events of a
+// cgroup shared with other processes (e.g. the daemon's) cannot be attributed to a single job.
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
+	"github.com/opencontainers/cgroups"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	CGROUP_ROOT = "/sys/fs/cgroup"
+
+	// Jobs under pressure waiting to be dumped, beyond which more are skipped until the next sample
+	PRESSURE_QUEUE_SIZE = 16
+)
+
+// PressurePolicy specifies when jobs are dumped under memory pressure, and how.
+// Zero values disable the respective trigger.
+type PressurePolicy struct {
+	Interval      time.Duration // how often memory is sampled
+	MemoryPercent float64       // working set, as a percentage of the job's tightest memory limit
+	PSI           float64       // memory stall ('some' avg10), as a percentage
+	Events        bool          // dump on new hard memory limit ('max') events
+
+	Dir          string
+	Compression  string
+	LeaveRunning bool
+}
+
+func (p PressurePolicy) IsZero() bool {
+	return p.MemoryPercent <= 0 && p.PSI <= 0 && !p.Events
+}
+
+type PressureWatcher struct {
+	jobs   Manager
+	hostID string
+	dump   DumpFunc
+	policy PressurePolicy
+	root   string // cgroup v2 mount
+
+	events map[string]uint64 // JID -> last total of hard memory limit events
+
+	mu      sync.Mutex
+	tripped map[string]bool // JID -> dumped under pressure that is yet to clear
+	pending map[string]bool // JID -> queued for or being dumped
+}
+
+// NewPressureWatcher creates a new memory pressure watcher, for jobs on the given host.
+// Dumps are taken using the provided dump function.
+func NewPressureWatcher(jobs Manager, hostID string, dump DumpFunc, policy PressurePolicy) *PressureWatcher {
+	return &PressureWatcher{
+		jobs:    jobs,
+		hostID:  hostID,
+		dump:    dump,
+		policy:  policy,
+		root:    CGROUP_ROOT,
+		events:  make(map[string]uint64),
+		tripped: make(map[string]bool),
+		pending: make(map[string]bool),
+	}
+}
+
+/////////////////
+//// Methods ////
+/////////////////
+
+// Watch samples memory of all running jobs on the host, dumping those under pressure,
+// until the context is done. Dumps are taken in the background, one at a time as each
+// needs memory of its own, so sampling of other jobs continues meanwhile.
+func (w *PressureWatcher) Watch(ctx context.Context) {
+	log.Info().Str("interval", w.policy.Interval.String()).Msg("memory pressure watcher started")
+	defer log.Info().Msg("memory pressure watcher stopped")
+
+	queue := make(chan *Job, PRESSURE_QUEUE_SIZE)
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for job := range queue {
+			w.dumpJob(ctx, job)
+		}
+	})
+	defer wg.Wait()
+	defer close(queue)
+
+	ticker := time.NewTicker(w.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		running := make(map[string]bool)
+
+		for _, job := range w.jobs.ListByHostIDs(ctx, w.hostID) {
+			if job.IsRemote() || !job.IsRunning() {
+				continue
+			}
+			running[job.JID] = true
+
+			log := log.With().Str("JID", job.JID).Uint32("PID", job.GetPID()).Logger()
+
+			reason, err := w.check(job.JID, job.GetPID())
+			if err != nil {
+				log.Trace().Err(err).Msg("failed to sample memory pressure")
+				continue
+			}
+
+			w.mu.Lock()
+			if reason == "" {
+				delete(w.tripped, job.JID)
+			}
+			skip := reason == "" || w.tripped[job.JID] || w.pending[job.JID] // already dumped, and still under pressure
+			w.mu.Unlock()
+			if skip {
+				continue
+			}
+
+			select {
+			case queue <- job:
+				w.mu.Lock()
+				w.pending[job.JID] = true
+				w.mu.Unlock()
+				log.Warn().Str("reason", reason).Msg("job under memory pressure, dumping")
+			default:
+				log.Warn().Str("reason", reason).Msg("job under memory pressure, but too many dumps are queued")
+			}
+		}
+
+		// Forget jobs that are no longer running
+		for jid := range w.events {
+			if !running[jid] {
+				delete(w.events, jid)
+			}
+		}
+		w.mu.Lock()
+		for jid := range w.tripped {
+			if !running[jid] {
+				delete(w.tripped, jid)
+			}
+		}
+		w.mu.Unlock()
+	}
+}
+
+////////////////////////
+//// Helper Methods ////
+////////////////////////
+
+func (w *PressureWatcher) dumpJob(ctx context.Context, job *Job) {
+	log := log.With().Str("JID", job.JID).Uint32("PID", job.GetPID()).Logger()
+
+	resp, err := w.dump(ctx, &daemon.DumpReq{
+		Dir:         w.policy.Dir,
+		Compression: w.policy.Compression,
+		Details:     &daemon.Details{JID: proto.String(job.JID)},
+		Criu:        &criu_proto.CriuOpts{LeaveRunning: proto.Bool(w.policy.LeaveRunning)},
+	})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.pending, job.JID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to dump job under memory pressure")
+		return
+	}
+	w.tripped[job.JID] = true
+
+	log.Info().Strs("paths", resp.GetPaths()).Msg("dumped job under memory pressure")
+}
+
+// Samples the memory of the job's cgroup, and returns why it should be dumped, or empty if
+// it should not. Fails if the cgroup is not dedicated to the job.
+func (w *PressureWatcher) check(jid string, pid uint32) (reason string, err error) {
+	paths, err := cgroups.ParseCgroupFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", err
+	}
+	cgroup, ok := paths[""]
+	if !ok {
+		return "", fmt.Errorf("process %d is not in a cgroup v2 hierarchy", pid)
+	}
+
+	if err := dedicatedCgroup(w.root, cgroup, pid); err != nil {
+		return "", err
+	}
+
+	sample, err := sampleMemory(w.root, cgroup)
+	if err != nil {
+		return "", err
+	}
+
+	// Events are cumulative, so only new ones since the last sample count
+
+	last, seen := w.events[jid]
+	w.events[jid] = sample.Events
+
+	switch {
+	case w.policy.MemoryPercent > 0 && sample.Percent >= w.policy.MemoryPercent:
+		return fmt.Sprintf("memory working set at %.1f%% of %s", sample.Percent, sample.Limit), nil
+	case w.policy.PSI > 0 && sample.PSI >= w.policy.PSI:
+		return fmt.Sprintf("memory stall at %.1f%% (avg10)", sample.PSI), nil
+	case w.policy.Events && seen && sample.Events > last:
+		return fmt.Sprintf("hit hard memory limit %d times", sample.Events-last), nil
+	}
+
+	return "", nil
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Checks that all processes in the cgroup (relative to the root) belong to the process tree.
+func dedicatedCgroup(root, cgroup string, pid uint32) error {
+	data, err := os.ReadFile(filepath.Join(root, cgroup, "cgroup.procs"))
+	if err != nil {
+		return err
+	}
+	for field := range strings.FieldsSeq(string(data)) {
+		member, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid PID '%s' in cgroup %s", field, cgroup)
+		}
+		if !isDescendant(uint32(member), pid) {
+			return fmt.Errorf("cgroup %s is shared with process %d, outside the job", cgroup, member)
+		}
+	}
+	return nil
+}
+
+// Returns true if the process is the ancestor, or one of its descendants.
+func isDescendant(pid, ancestor uint32) bool {
+	for pid > 1 {
+		if pid == ancestor {
+			return true
+		}
+		data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			return false
+		}
+		// The command name may contain spaces, so parse after its closing paren
+		i := bytes.LastIndexByte(data, ')')
+		if i < 0 {
+			return false
+		}
+		fields := strings.Fields(string(data[i+1:]))
+		if len(fields) < 2 {
+			return false
+		}
+		ppid, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return false
+		}
+		pid = uint32(ppid)
+	}
+	return pid == ancestor
+}
+
+type memorySample struct {
+	Percent float64 // working set, as a percentage of the tightest memory limit
+	Limit   string  // file of the tightest memory limit, e.g. memory.max
+	PSI     float64 // 'some' avg10 of the cgroup
+	Events  uint64  // total hard memory limit events of the cgroup
+}
+
+// Samples memory of a cgroup (relative to the root), against its own limits. The working set
+// excludes inactive page cache, as the kernel reclaims it before running out of memory.
+func sampleMemory(root, cgroup string) (sample memorySample, err error) {
+	dir := filepath.Join(root, cgroup)
+
+	sample.PSI, err = readPSI(filepath.Join(dir, "memory.pressure"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return sample, err
+	}
+
+	usage, err := readUint(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return sample, err
+	}
+	stat, err := readKeyed(filepath.Join(dir, "memory.stat"))
+	if err != nil {
+		return sample, err
+	}
+	workingSet := usage - min(usage, stat["inactive_file"])
+
+	for _, file := range []string{"memory.max", "memory.high"} {
+		limit, err := readUint(filepath.Join(dir, file))
+		if err != nil || limit == 0 || limit == math.MaxUint64 {
+			continue
+		}
+		if percent := 100 * float64(workingSet) / float64(limit); percent > sample.Percent {
+			sample.Percent = percent
+			sample.Limit = file
+		}
+	}
+
+	events, err := readKeyed(filepath.Join(dir, "memory.events"))
+	if err == nil {
+		sample.Events = events["max"]
+	}
+
+	return sample, nil
+}
+
+// Reads a single value cgroup file, where "max" means no limit.
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return math.MaxUint64, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// Reads a flat keyed cgroup file, e.g. memory.events.
+func readKeyed(path string) (map[string]uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s in %s: %w", fields[0], path, err)
+		}
+		values[fields[0]] = value
+	}
+	return values, scanner.Err()
+}
+
+// Reads the 'some' avg10 of a PSI file, e.g. memory.pressure.
+func readPSI(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			value, ok := strings.CutPrefix(field, "avg10=")
+			if !ok {
+				continue
+			}
+			return strconv.ParseFloat(value, 64)
+		}
+	}
+	return 0, fmt.Errorf("no 'some avg10' in %s", path)
+}
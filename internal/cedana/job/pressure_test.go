@@ -0,0 +1,141 @@
+package job
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// writeCgroup creates a fake cgroup v2 directory with the given files.
+func writeCgroup(t *testing.T, root, path string, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSampleMemory(t *testing.T) {
+	root := t.TempDir()
+
+	// Most of the usage is inactive page cache, which the kernel reclaims first
+	writeCgroup(t, root, "job", map[string]string{
+		"memory.current":  "900\n",
+		"memory.max":      "1000\n",
+		"memory.high":     "max\n",
+		"memory.stat":     "anon 400\nfile 500\nactive_file 100\ninactive_file 400\n",
+		"memory.events":   "low 0\nhigh 0\nmax 2\noom 0\noom_kill 0\n",
+		"memory.pressure": "some avg10=12.50 avg60=3.00 avg300=1.00 total=1234\nfull avg10=5.00 avg60=1.00 avg300=0.00 total=456\n",
+	})
+
+	sample, err := sampleMemory(root, "/job")
+	if err != nil {
+		t.Fatalf("sample: %v", err)
+	}
+
+	if sample.Percent != 50 {
+		t.Errorf("expected working set at 50%%, got %v", sample.Percent)
+	}
+	if sample.Limit != "memory.max" {
+		t.Errorf("expected limit of memory.max, got %s", sample.Limit)
+	}
+	if sample.PSI != 12.5 {
+		t.Errorf("expected PSI of 12.5, got %v", sample.PSI)
+	}
+	if sample.Events != 2 {
+		t.Errorf("expected 2 events, got %d", sample.Events)
+	}
+}
+
+func TestSampleMemoryAncestorLimit(t *testing.T) {
+	root := t.TempDir()
+
+	// Limit is on the parent (e.g. a pod), the job's own cgroup is unlimited
+	writeCgroup(t, root, "pod", map[string]string{
+		"memory.current": "900\n",
+		"memory.max":     "1000\n",
+		"memory.stat":    "anon 900\ninactive_file 0\n",
+		"memory.events":  "low 0\nhigh 0\nmax 3\noom 0\noom_kill 0\n",
+	})
+	writeCgroup(t, root, "pod/job", map[string]string{
+		"memory.current": "800\n",
+		"memory.max":     "max\n",
+		"memory.high":    "max\n",
+		"memory.stat":    "anon 800\ninactive_file 0\n",
+		"memory.events":  "low 0\nhigh 0\nmax 0\noom 0\noom_kill 0\n",
+	})
+
+	sample, err := sampleMemory(root, "/pod/job")
+	if err != nil {
+		t.Fatalf("sample: %v", err)
+	}
+
+	if sample.Percent != 0 || sample.Events != 0 {
+		t.Errorf("expected only the job's own limit to count, got %+v", sample)
+	}
+}
+
+func TestSampleMemoryHigh(t *testing.T) {
+	root := t.TempDir()
+
+	// memory.high is tighter than memory.max, and there's no PSI
+	writeCgroup(t, root, "job", map[string]string{
+		"memory.current": "500\n",
+		"memory.max":     "2000\n",
+		"memory.high":    "1000\n",
+		"memory.stat":    "anon 500\ninactive_file 0\n",
+	})
+
+	sample, err := sampleMemory(root, "job")
+	if err != nil {
+		t.Fatalf("sample: %v", err)
+	}
+
+	if sample.Percent != 50 {
+		t.Errorf("expected 50%% usage, got %v", sample.Percent)
+	}
+	if sample.Limit != "memory.high" {
+		t.Errorf("expected limit of memory.high, got %s", sample.Limit)
+	}
+	if sample.PSI != 0 || sample.Events != 0 {
+		t.Errorf("expected no PSI or events, got %+v", sample)
+	}
+}
+
+func TestReadUint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.max")
+
+	os.WriteFile(path, []byte("max\n"), 0o644)
+	if value, err := readUint(path); err != nil || value != math.MaxUint64 {
+		t.Errorf("expected no limit, got %d (%v)", value, err)
+	}
+
+	os.WriteFile(path, []byte("4096\n"), 0o644)
+	if value, err := readUint(path); err != nil || value != 4096 {
+		t.Errorf("expected 4096, got %d (%v)", value, err)
+	}
+}
+
+func TestDedicatedCgroup(t *testing.T) {
+	root := t.TempDir()
+
+	self := strconv.Itoa(os.Getpid())
+
+	writeCgroup(t, root, "job", map[string]string{"cgroup.procs": self + "\n"})
+	if err := dedicatedCgroup(root, "/job", uint32(os.Getppid())); err != nil {
+		t.Errorf("expected cgroup of a descendant to be dedicated: %v", err)
+	}
+
+	// Shared with a process outside the job, e.g. the daemon
+	writeCgroup(t, root, "shared", map[string]string{"cgroup.procs": self + "\n1\n"})
+	if err := dedicatedCgroup(root, "/shared", uint32(os.Getppid())); err == nil {
+		t.Errorf("expected cgroup shared with init not to be dedicated")
+	}
+}
@@ -0,0 +1,314 @@
+package job
+
+// Implements restart policies for managed jobs, so the daemon can act as a supervisor. When a
+// job fails on its own (i.e. not killed or dumped through the daemon), it's restored from its
+// latest checkpoint, or run again from scratch if it has none. A job that exits cleanly is only
+// ever run again from scratch, as restoring it would repeat work it has already completed.
+// Consecutive restarts are backed off exponentially. Policies, along with restart counts and
+// reasons, are kept in the DB.
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/internal/db"
+	"github.com/cedana/cedana/pkg/keys"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	RESTART_NEVER      = "never"
+	RESTART_ON_FAILURE = "on-failure"
+	RESTART_ALWAYS     = "always"
+)
+
+type (
+	RunFunc     func(ctx context.Context, req *daemon.RunReq) (*daemon.RunResp, error)
+	RestoreFunc func(ctx context.Context, req *daemon.RestoreReq) (*daemon.RestoreResp, error)
+)
+
+// RestartBackoff specifies the delay before restarting a job, which is doubled on every
+// consecutive restart, up to the max. Resets once a job stays up for longer than the max.
+type RestartBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+type Restarter struct {
+	runs   sync.Map // JID -> *daemon.RunReq the job was last run with, to run it again
+	delays sync.Map // JID -> time.Duration of the last restart delay
+
+	jobs    Manager
+	db      db.DB
+	run     RunFunc
+	restore RestoreFunc
+	backoff RestartBackoff
+
+	lifetime context.Context
+	wg       *sync.WaitGroup // for all restarter background routines
+}
+
+// NewRestarter creates a new restarter. Jobs are restored and run again using the provided
+// restore and run functions.
+func NewRestarter(
+	lifetime context.Context,
+	serverWg *sync.WaitGroup,
+	jobs Manager,
+	db db.DB,
+	run RunFunc,
+	restore RestoreFunc,
+	backoff RestartBackoff,
+) *Restarter {
+	return &Restarter{
+		jobs:     jobs,
+		db:       db,
+		run:      run,
+		restore:  restore,
+		backoff:  backoff,
+		lifetime: lifetime,
+		wg:       serverWg,
+	}
+}
+
+// ParseRestartPolicy parses a restart policy, e.g. "never", "always", "on-failure"
+// or "on-failure:5" (to give up after 5 restarts).
+func ParseRestartPolicy(s string) (*daemon.RestartPolicy, error) {
+	name, max, hasMax := strings.Cut(strings.TrimSpace(s), ":")
+
+	policy := &daemon.RestartPolicy{Policy: name}
+
+	switch name {
+	case RESTART_NEVER, RESTART_ALWAYS:
+		if hasMax {
+			return nil, fmt.Errorf("max restarts can only be set for '%s'", RESTART_ON_FAILURE)
+		}
+	case RESTART_ON_FAILURE:
+		if hasMax {
+			n, err := strconv.Atoi(max)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid max restarts '%s'", max)
+			}
+			policy.MaxRestarts = int32(n)
+		}
+	default:
+		return nil, fmt.Errorf("unknown restart policy '%s', must be one of %s, %s[:max] or %s",
+			name, RESTART_NEVER, RESTART_ON_FAILURE, RESTART_ALWAYS)
+	}
+
+	return policy, nil
+}
+
+/////////////////
+//// Methods ////
+/////////////////
+
+// Set creates or replaces the restart policy for a job, resetting its restart count.
+func (r *Restarter) Set(ctx context.Context, policy *daemon.RestartPolicy) error {
+	if policy.GetJID() == "" {
+		return fmt.Errorf("missing JID")
+	}
+
+	r.delays.Delete(policy.GetJID())
+
+	if policy.GetPolicy() == RESTART_NEVER {
+		return r.db.DeleteRestartPolicy(ctx, policy.GetJID())
+	}
+
+	if _, err := ParseRestartPolicy(policy.GetPolicy()); err != nil {
+		return err
+	}
+
+	return r.db.PutRestartPolicy(ctx, &daemon.RestartPolicy{
+		JID:         policy.GetJID(),
+		Policy:      policy.GetPolicy(),
+		MaxRestarts: policy.GetMaxRestarts(),
+	})
+}
+
+// Remove deletes the restart policy for a job.
+func (r *Restarter) Remove(ctx context.Context, jid string) error {
+	r.runs.Delete(jid)
+	r.delays.Delete(jid)
+
+	return r.db.DeleteRestartPolicy(ctx, jid)
+}
+
+// List returns restart policies filtered by JID.
+func (r *Restarter) List(ctx context.Context, jids ...string) ([]*daemon.RestartPolicy, error) {
+	return r.db.ListRestartPolicies(ctx, jids...)
+}
+
+// Watch waits in the background for a job to exit, and restarts it based on its policy.
+// If the job was run (as opposed to restored), the run request is kept to run it again.
+func (r *Restarter) Watch(jid string, run *daemon.RunReq, exited <-chan int) {
+	if run != nil {
+		run = proto.CloneOf(run)
+		run.RestartPolicy = nil
+		r.runs.Store(jid, run)
+	}
+
+	started := time.Now()
+
+	r.wg.Go(func() {
+		code, ok := <-exited
+		if !ok {
+			return
+		}
+		<-r.jobs.Done(jid) // wait for cleanup
+
+		r.restart(jid, code, time.Since(started))
+	})
+}
+
+////////////////////////
+//// Helper Methods ////
+////////////////////////
+
+// Restarts the job that exited with the given code, if its policy asks for it. Failed
+// restarts are retried with backoff, and count towards the max restarts.
+func (r *Restarter) restart(jid string, code int, uptime time.Duration) {
+	ctx := r.lifetime
+	if ctx.Err() != nil {
+		return // jobs are stopped along with the daemon
+	}
+
+	log := log.With().Str("JID", jid).Int("code", code).Logger()
+
+	policies, err := r.db.ListRestartPolicies(ctx, jid)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get restart policy of job")
+		return
+	}
+	if len(policies) == 0 {
+		return
+	}
+	policy := policies[0]
+
+	if policy.GetPolicy() == RESTART_ON_FAILURE && code == 0 {
+		return
+	}
+
+	job := r.jobs.Get(ctx, jid)
+	if job == nil {
+		log.Debug().Msg("job no longer exists, removing restart policy")
+		err := r.Remove(ctx, jid)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to remove restart policy")
+		}
+		return
+	}
+	if job.IsStopped() {
+		log.Debug().Msg("job was stopped through the daemon, not restarting")
+		return
+	}
+	if _, hasRun := r.runs.Load(jid); code == 0 && !hasRun {
+		log.Debug().Msg("job exited cleanly, and was not run by this daemon to run it again, not restarting")
+		return
+	}
+
+	reason := fmt.Sprintf("exited with code %d", code)
+	delay := r.delay(jid, uptime)
+
+	for {
+		if max := policy.GetMaxRestarts(); max > 0 && policy.GetCount() >= max {
+			log.Warn().Int32("restarts", policy.GetCount()).Msg("job reached max restarts, giving up")
+			return
+		}
+
+		log.Info().Str("reason", reason).Str("delay", delay.String()).Msg("restarting job")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		// Job could have been deleted, or started by hand in the meantime
+
+		job = r.jobs.Get(ctx, jid)
+		if job == nil || job.IsRunning() {
+			log.Debug().Msg("job no longer needs restarting")
+			return
+		}
+
+		how, err := r.restartJob(ctx, jid, code)
+
+		policy.Count++
+		policy.Reason = reason
+		policy.Time = time.Now().UnixMilli()
+
+		if dbErr := r.db.PutRestartPolicy(ctx, policy); dbErr != nil {
+			log.Warn().Err(dbErr).Msg("failed to save restart count of job")
+		}
+
+		if err == nil {
+			log.Info().Int32("restarts", policy.GetCount()).Msgf("restarted job, %s", how)
+			return
+		}
+
+		log.Error().Err(err).Int32("restarts", policy.GetCount()).Msg("failed to restart job")
+
+		reason = fmt.Sprintf("previous restart failed: %v", err)
+		delay = r.delay(jid, 0)
+	}
+}
+
+// Restores the job that failed with the given code from its latest checkpoint, or runs it
+// again if it has none, or if it exited cleanly. Returns how the job was restarted.
+func (r *Restarter) restartJob(ctx context.Context, jid string, code int) (how string, err error) {
+	run, hasRun := r.runs.Load(jid)
+
+	if checkpoint := r.jobs.GetLatestCheckpoint(jid); checkpoint != nil && code != 0 {
+		req := &daemon.RestoreReq{
+			Details: &daemon.Details{JID: proto.String(jid)},
+		}
+		if hasRun {
+			req.Attachable = run.(*daemon.RunReq).GetAttachable()
+		}
+
+		_, err := r.restore(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("failed to restore from checkpoint %s: %w", checkpoint.GetID(), err)
+		}
+
+		return fmt.Sprintf("restored from checkpoint %s", checkpoint.GetID()), nil
+	}
+
+	if !hasRun {
+		return "", fmt.Errorf("job has no checkpoint, and was not run by this daemon to run it again")
+	}
+	if code == 0 {
+		how = "ran again, as it exited cleanly"
+	} else {
+		how = "ran again, as it has no checkpoint"
+	}
+
+	req := proto.CloneOf(run.(*daemon.RunReq))
+
+	_, err = r.run(context.WithValue(ctx, keys.RESTART_CONTEXT_KEY, true), req)
+	if err != nil {
+		return "", fmt.Errorf("failed to run again: %w", err)
+	}
+
+	return how, nil
+}
+
+// Returns the delay before restarting the job, doubling the previous one if the job
+// was not up for long since.
+func (r *Restarter) delay(jid string, uptime time.Duration) time.Duration {
+	delay := r.backoff.Initial
+
+	if previous, ok := r.delays.Load(jid); ok && uptime < r.backoff.Max {
+		delay = min(2*previous.(time.Duration), r.backoff.Max)
+	}
+
+	r.delays.Store(jid, delay)
+
+	return delay
+}
@@ -0,0 +1,63 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRestartPolicy(t *testing.T) {
+	cases := []struct {
+		in      string
+		policy  string
+		max     int32
+		invalid bool
+	}{
+		{"never", RESTART_NEVER, 0, false},
+		{"always", RESTART_ALWAYS, 0, false},
+		{"on-failure", RESTART_ON_FAILURE, 0, false},
+		{"on-failure:5", RESTART_ON_FAILURE, 5, false},
+		{"on-failure:0", "", 0, true},
+		{"on-failure:x", "", 0, true},
+		{"always:3", "", 0, true},
+		{"sometimes", "", 0, true},
+		{"", "", 0, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			policy, err := ParseRestartPolicy(c.in)
+			if c.invalid {
+				if err == nil {
+					t.Fatalf("expected error, got policy %v", policy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if policy.GetPolicy() != c.policy || policy.GetMaxRestarts() != c.max {
+				t.Errorf("expected %s with max %d, got %s with max %d", c.policy, c.max, policy.GetPolicy(), policy.GetMaxRestarts())
+			}
+		})
+	}
+}
+
+func TestRestartDelay(t *testing.T) {
+	backoff := RestartBackoff{Initial: time.Second, Max: 5 * time.Second}
+	r := NewRestarter(context.Background(), &sync.WaitGroup{}, nil, nil, nil, nil, backoff)
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := r.delay("job", 0); got != w {
+			t.Errorf("restart %d: expected delay %v, got %v", i, w, got)
+		}
+	}
+
+	// Stayed up for longer than the max, so backoff resets
+
+	if got := r.delay("job", time.Minute); got != time.Second {
+		t.Errorf("expected delay to reset to %v, got %v", time.Second, got)
+	}
+}
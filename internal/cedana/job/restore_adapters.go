@@ -3,6 +3,7 @@ package job
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
@@ -75,6 +76,26 @@ func ManageRestore(jobs Manager) types.Adapter[types.Restore] {
 			if req.Path == "" {
 				return nil, status.Errorf(codes.FailedPrecondition, "job %s has no saved checkpoint. pass in path to override", jid)
 			}
+
+			// If restoring from an incremental checkpoint, ensure its whole parent chain is
+			// still around, as CRIU will need to read pages from the parent images.
+
+			for _, checkpoint := range jobs.ListCheckpoints(jid) {
+				if checkpoint.GetPath() != req.Path {
+					continue
+				}
+				for parentID := checkpoint.GetParentID(); parentID != ""; {
+					parent := jobs.GetCheckpoint(parentID)
+					if parent == nil {
+						return nil, status.Errorf(codes.FailedPrecondition, "parent checkpoint %s of %s no longer exists", parentID, checkpoint.GetID())
+					}
+					if _, err := os.Stat(parent.GetPath()); err != nil {
+						return nil, status.Errorf(codes.FailedPrecondition, "parent checkpoint %s is missing: %v", parentID, err)
+					}
+					parentID = parent.GetParentID()
+				}
+				break
+			}
 			if req.Criu == nil {
 				req.Criu = &criu_proto.CriuOpts{}
 			}
@@ -103,6 +124,24 @@ func ManageRestore(jobs Manager) types.Adapter[types.Restore] {
 				return nil, status.Errorf(codes.Internal, "failed to manage restored job: %v", err)
 			}
 
+			jobs.Publish(&daemon.Event{Type: EVENT_RESTORED, JID: jid, PID: resp.PID, Paths: []string{req.Path}})
+
+			return code, nil
+		}
+	}
+}
+
+// Adapter that watches the restored job to restart on exit, if it has a restart policy.
+func RestartOnExitForRestore(restarter *Restarter) types.Adapter[types.Restore] {
+	return func(next types.Restore) types.Restore {
+		return func(ctx context.Context, opts types.Opts, resp *daemon.RestoreResp, req *daemon.RestoreReq) (code func() <-chan int, err error) {
+			code, err = next(ctx, opts, resp, req)
+			if err != nil {
+				return nil, err
+			}
+
+			restarter.Watch(req.GetDetails().GetJID(), nil, code())
+
 			return code, nil
 		}
 	}
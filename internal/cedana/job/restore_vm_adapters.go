@@ -0,0 +1,97 @@
+package job
+
+import (
+	"context"
+	"path/filepath"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/pkg/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Adapter that fills in VM restore request details based on saved job info.
+// Post-restore, manages the restored VM under the same job.
+func ManageRestoreVM(jobs Manager) types.Adapter[types.RestoreVM] {
+	return func(next types.RestoreVM) types.RestoreVM {
+		return func(ctx context.Context, opts types.Opts, resp *daemon.RestoreVMResp, req *daemon.RestoreVMReq) (code func() <-chan int, err error) {
+			jid := req.GetDetails().GetJID()
+
+			if jid == "" {
+				return nil, status.Errorf(codes.InvalidArgument, "missing JID in managed VM restore")
+			}
+
+			job := jobs.Get(ctx, jid)
+			if job == nil {
+				return nil, status.Errorf(codes.NotFound, "job %s not found", jid)
+			}
+
+			if job.IsRunning() {
+				return nil, status.Errorf(codes.FailedPrecondition, "job %s is already running", jid)
+			}
+
+			// Fill in restore request details based on saved job info
+
+			req.Type = job.GetType()
+
+			// Use saved job details, but allow overriding from request
+
+			if job.GetDetails() != nil {
+				mergedDetails := proto.Clone(job.GetDetails()).(*daemon.Details)
+				proto.Merge(mergedDetails, req.GetDetails())
+				req.Details = mergedDetails
+			}
+
+			latest := jobs.GetLatestCheckpoint(jid).GetPath()
+			if req.VMSnapshotPath == "" {
+				req.VMSnapshotPath = latest
+			}
+			if req.VMSnapshotPath == "" {
+				return nil, status.Errorf(codes.FailedPrecondition, "job %s has no saved checkpoint. pass in path to override", jid)
+			}
+
+			// Diff dumps of the job are taken on top of its latest checkpoint, which
+			// must then be the one the VM is restored from.
+
+			if req.GetDiff() && filepath.Clean(req.VMSnapshotPath) != filepath.Clean(latest) {
+				return nil, status.Errorf(codes.FailedPrecondition, "diff snapshots can only be enabled when restoring job %s from its latest checkpoint", jid)
+			}
+
+			// The VMM to restore into must already be listening, by default on the same
+			// socket as the VM that was dumped.
+
+			if req.VMSocketPath == "" {
+				req.VMSocketPath = req.GetDetails().GetKata().GetVmSocket()
+			}
+			if req.VMSocketPath == "" {
+				return nil, status.Errorf(codes.InvalidArgument, "missing VM socket for job %s", jid)
+			}
+			if kata := req.GetDetails().GetKata(); kata != nil {
+				kata.VmSocket = req.VMSocketPath
+			}
+
+			// Create child lifetime context, so we have cancellation ability over restored
+			// VM created by the next handler(s).
+
+			lifetime, cancel := context.WithCancel(opts.Lifetime)
+			opts.Lifetime = lifetime
+
+			code, err = next(ctx, opts, resp, req)
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+
+			job.SetDetails(req.Details) // Set again, in case they got modified
+
+			err = jobs.Manage(opts.Lifetime, jid, resp.GetPID(), code())
+			if err != nil {
+				cancel()
+				return nil, status.Errorf(codes.Internal, "failed to manage restored VM job: %v", err)
+			}
+
+			return code, nil
+		}
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/pkg/keys"
 	"github.com/cedana/cedana/pkg/types"
 	"github.com/rb-go/namegen"
+	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -23,9 +25,25 @@ func Manage(jobs Manager) types.Adapter[types.Run] {
 				req.JID = namegen.GetName(1)
 			}
 
-			job, err := jobs.New(req.JID, req.Type)
-			if err != nil {
-				return nil, status.Errorf(codes.Internal, "failed to create new job: %v", err)
+			// When restarting, the existing job is run again instead
+
+			restarting, _ := ctx.Value(keys.RESTART_CONTEXT_KEY).(bool)
+
+			var job *Job
+
+			if restarting {
+				job = jobs.Get(ctx, req.JID)
+				if job == nil {
+					return nil, status.Errorf(codes.NotFound, "job %s not found", req.JID)
+				}
+				if job.IsRunning() {
+					return nil, status.Errorf(codes.FailedPrecondition, "job %s is already running", req.JID)
+				}
+			} else {
+				job, err = jobs.New(req.JID, req.Type)
+				if err != nil {
+					return nil, status.Errorf(codes.Internal, "failed to create new job: %v", err)
+				}
 			}
 
 			if !req.Attachable {
@@ -36,6 +54,7 @@ func Manage(jobs Manager) types.Adapter[types.Run] {
 
 			job.SetLog(req.Log)
 			job.SetDetails(req.Details)
+			job.SetCheckpointOnSignal(req.CheckpointOnSignal)
 
 			// Create child lifetime context, so we have cancellation ability over started process
 			lifetime, cancel := context.WithCancel(opts.Lifetime)
@@ -43,7 +62,9 @@ func Manage(jobs Manager) types.Adapter[types.Run] {
 
 			code, err = next(ctx, opts, resp, req)
 			if err != nil {
-				jobs.Delete(job.JID)
+				if !restarting {
+					jobs.Delete(job.JID)
+				}
 				return nil, err
 			}
 
@@ -54,7 +75,9 @@ func Manage(jobs Manager) types.Adapter[types.Run] {
 				if req.Action == daemon.RunAction_START_NEW { // we don't want to cancel if manage was called for an existing process
 					cancel()
 				}
-				jobs.Delete(job.JID)
+				if !restarting {
+					jobs.Delete(job.JID)
+				}
 				return nil, status.Errorf(codes.Internal, "failed to manage job: %v", err)
 			}
 
@@ -62,3 +85,47 @@ func Manage(jobs Manager) types.Adapter[types.Run] {
 		}
 	}
 }
+
+// Adapter that sets the restart policy of the job, and watches it to restart on exit.
+// Jobs being restarted are watched again, keeping their existing policy.
+func RestartOnExit(restarter *Restarter) types.Adapter[types.Run] {
+	return func(next types.Run) types.Run {
+		return func(ctx context.Context, opts types.Opts, resp *daemon.RunResp, req *daemon.RunReq) (code func() <-chan int, err error) {
+			policy := req.GetRestartPolicy()
+			if policy != nil {
+				_, err := ParseRestartPolicy(policy.GetPolicy())
+				if err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "invalid restart policy: %v", err)
+				}
+			}
+
+			restarting, _ := ctx.Value(keys.RESTART_CONTEXT_KEY).(bool)
+
+			code, err = next(ctx, opts, resp, req)
+			if err != nil {
+				return nil, err
+			}
+
+			if policy != nil {
+				policy.JID = req.JID
+				err = restarter.Set(ctx, policy)
+				if err != nil {
+					if killErr := restarter.jobs.Kill(ctx, req.JID); killErr != nil {
+						log.Error().Err(killErr).Str("JID", req.JID).Msg("failed to kill job after failing to set its restart policy")
+					}
+					return nil, status.Errorf(codes.Internal, "failed to set restart policy: %v", err)
+				}
+			}
+
+			if restarting || (policy != nil && policy.GetPolicy() != RESTART_NEVER) {
+				var run *daemon.RunReq
+				if req.Action == daemon.RunAction_START_NEW {
+					run = req // existing processes can't be run again
+				}
+				restarter.Watch(req.JID, run, code())
+			}
+
+			return code, nil
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package job
+
+// Implements a checkpoint scheduler, that periodically dumps jobs in the background,
+// pruning older checkpoints to keep only a specified number. Schedules are persisted
+// in the DB, so they are resumed when the daemon restarts.
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
+	"github.com/cedana/cedana/internal/db"
+	"github.com/cedana/cedana/pkg/io"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+const SCHEDULED_DUMP_PREFIX = "scheduled-" // to tell scheduled checkpoints apart from others
+
+type (
+	DumpFunc    func(ctx context.Context, req *daemon.DumpReq) (*daemon.DumpResp, error)
+	StorageFunc func(ctx context.Context, path string) (io.Storage, error)
+)
+
+type Scheduler struct {
+	running sync.Map // JID -> *runningSchedule
+
+	jobs    Manager
+	db      db.DB
+	dump    DumpFunc
+	storage StorageFunc
+
+	lifetime context.Context
+	wg       *sync.WaitGroup // for all scheduler background routines
+}
+
+// Held while a scheduled checkpoint is taken, so a schedule is never stopped midway
+type runningSchedule struct {
+	sync.Mutex
+	cancel context.CancelFunc
+}
+
+// NewScheduler creates a new checkpoint scheduler, resuming all schedules saved in the DB.
+// Dumps are taken using the provided dump function, and storage for pruning older checkpoints
+// is looked up using the provided storage function.
+func NewScheduler(
+	lifetime context.Context,
+	serverWg *sync.WaitGroup,
+	jobs Manager,
+	db db.DB,
+	dump DumpFunc,
+	storage StorageFunc,
+) (*Scheduler, error) {
+	scheduler := &Scheduler{
+		jobs:     jobs,
+		db:       db,
+		dump:     dump,
+		storage:  storage,
+		lifetime: lifetime,
+		wg:       serverWg,
+	}
+
+	schedules, err := db.ListSchedules(lifetime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+
+	for _, schedule := range schedules {
+		scheduler.start(schedule)
+	}
+
+	return scheduler, nil
+}
+
+/////////////////
+//// Methods ////
+/////////////////
+
+// Set creates or replaces the checkpoint schedule for a job.
+func (s *Scheduler) Set(ctx context.Context, schedule *daemon.Schedule) error {
+	if schedule.GetInterval() <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if schedule.GetKeep() < 0 {
+		return fmt.Errorf("number of checkpoints to keep cannot be negative")
+	}
+	if schedule.GetDir() == "" {
+		return fmt.Errorf("dump dir must be specified")
+	}
+
+	err := s.db.PutSchedule(ctx, schedule)
+	if err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+
+	s.start(schedule)
+
+	return nil
+}
+
+// Remove stops and deletes the checkpoint schedule for a job.
+// Checkpoints already taken are not deleted.
+func (s *Scheduler) Remove(ctx context.Context, jid string) error {
+	s.stop(jid)
+
+	return s.db.DeleteSchedule(ctx, jid)
+}
+
+// List returns checkpoint schedules filtered by JID.
+func (s *Scheduler) List(ctx context.Context, jids ...string) ([]*daemon.Schedule, error) {
+	return s.db.ListSchedules(ctx, jids...)
+}
+
+////////////////////////
+//// Helper Methods ////
+////////////////////////
+
+func (s *Scheduler) start(schedule *daemon.Schedule) {
+	ctx, cancel := context.WithCancel(s.lifetime)
+	running := &runningSchedule{cancel: cancel}
+
+	if previous, ok := s.running.Swap(schedule.GetJID(), running); ok {
+		previous.(*runningSchedule).stop()
+	}
+
+	s.wg.Go(func() {
+		s.run(ctx, running, schedule)
+	})
+}
+
+func (s *Scheduler) stop(jid string) {
+	if running, ok := s.running.LoadAndDelete(jid); ok {
+		running.(*runningSchedule).stop()
+	}
+}
+
+// Waits for any scheduled checkpoint in progress before cancelling.
+func (r *runningSchedule) stop() {
+	r.Lock()
+	defer r.Unlock()
+	r.cancel()
+}
+
+func (s *Scheduler) run(ctx context.Context, running *runningSchedule, schedule *daemon.Schedule) {
+	jid := schedule.GetJID()
+	interval := time.Duration(schedule.GetInterval()) * time.Millisecond
+
+	log := log.With().Str("JID", jid).Str("interval", interval.String()).Logger()
+	log.Info().Msg("checkpoint schedule started")
+	defer log.Info().Msg("checkpoint schedule stopped")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !s.jobs.Exists(jid) {
+			log.Info().Msg("job no longer exists, removing checkpoint schedule")
+			err := s.Remove(context.WithoutCancel(ctx), jid)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to remove checkpoint schedule")
+			}
+			return
+		}
+
+		job := s.jobs.Get(ctx, jid)
+		if job == nil || job.IsRemote() || !job.IsRunning() {
+			log.Debug().Msg("job is not running on this host, skipping scheduled checkpoint")
+			continue
+		}
+
+		running.Lock()
+		if ctx.Err() == nil { // could have been removed while checking the job
+			s.checkpoint(ctx, schedule)
+		}
+		running.Unlock()
+	}
+}
+
+// Takes a scheduled checkpoint of the job, pruning older ones.
+func (s *Scheduler) checkpoint(ctx context.Context, schedule *daemon.Schedule) {
+	jid := schedule.GetJID()
+	log := log.With().Str("JID", jid).Logger()
+
+	resp, err := s.dump(ctx, &daemon.DumpReq{
+		Dir:         schedule.GetDir(),
+		Name:        fmt.Sprintf("%s%s-%d", SCHEDULED_DUMP_PREFIX, jid, time.Now().UnixNano()),
+		Compression: schedule.GetCompression(),
+		Details:     &daemon.Details{JID: proto.String(jid)},
+		Criu:        &criu_proto.CriuOpts{LeaveRunning: proto.Bool(true)},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("scheduled checkpoint failed")
+		return
+	}
+
+	log.Info().Strs("paths", resp.GetPaths()).Msg("scheduled checkpoint taken")
+
+	err = s.prune(ctx, schedule)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to prune older scheduled checkpoints")
+	}
+}
+
+// Deletes the oldest scheduled checkpoints of the job in the schedule's dir, keeping only
+// the specified number. Checkpoints that others were dumped incrementally on top of are kept,
+// and checkpoints not taken by the schedule (e.g. manual dumps to the same dir) are never pruned.
+func (s *Scheduler) prune(ctx context.Context, schedule *daemon.Schedule) error {
+	keep := int(schedule.GetKeep())
+	if keep == 0 {
+		return nil
+	}
+
+	var scheduled []*daemon.Checkpoint
+	parents := make(map[string]any)
+	prefix := strings.TrimSuffix(schedule.GetDir(), "/") + "/" + SCHEDULED_DUMP_PREFIX
+
+	for _, checkpoint := range s.jobs.ListCheckpoints(schedule.GetJID()) {
+		if checkpoint.GetParentID() != "" {
+			parents[checkpoint.GetParentID()] = nil
+		}
+		if strings.HasPrefix(checkpoint.GetPath(), prefix) {
+			scheduled = append(scheduled, checkpoint)
+		}
+	}
+
+	if len(scheduled) <= keep {
+		return nil
+	}
+
+	storage, err := s.storage(ctx, schedule.GetDir())
+	if err != nil {
+		return err
+	}
+
+	// Newest first
+	slices.SortFunc(scheduled, func(a, b *daemon.Checkpoint) int {
+		return cmp.Compare(b.GetTime(), a.GetTime())
+	})
+
+	var errs []error
+	for _, checkpoint := range scheduled[keep:] {
+		if _, ok := parents[checkpoint.GetID()]; ok {
+			continue
+		}
+		err := storage.Delete(ctx, checkpoint.GetPath())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.jobs.DeleteCheckpoint(checkpoint.GetID())
+		log.Debug().Str("JID", schedule.GetJID()).Str("path", checkpoint.GetPath()).Msg("pruned scheduled checkpoint")
+	}
+
+	// Chunks of deduplicated checkpoints are only deleted once no longer referenced
+	if dedup, ok := storage.(*io.DedupStorage); ok {
+		_, err := dedup.Sweep(ctx, schedule.GetDir())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to sweep chunks: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,321 @@
+package cedana
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/grpc/go/daemon/daemongrpc"
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
+	"github.com/cedana/cedana/pkg/client"
+	"github.com/cedana/cedana/pkg/config"
+	cedana_io "github.com/cedana/cedana/pkg/io"
+	"github.com/cedana/cedana/pkg/keys"
+	"github.com/cedana/cedana/pkg/utils"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	MIGRATE_DIR_PATTERN         = "migrate-*"
+	MIGRATE_DEFAULT_COMPRESSION = "lz4"
+)
+
+// Migrate moves a running job to the daemon at the destination address. The job is dumped and
+// left stopped, while the checkpoint is streamed to the destination for restore. With iterative
+// pre-dumps, each is streamed while the job is still running, to shorten the time it stays frozen.
+// Only once the destination confirms the restore is the source killed and cleaned up, otherwise
+// it's resumed.
+func (s *Server) Migrate(ctx context.Context, req *daemon.MigrateReq) (*daemon.MigrateResp, error) {
+	jid := req.GetJID()
+	if jid == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing JID")
+	}
+	if req.GetAddress() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing destination address")
+	}
+	if req.GetPreDumps() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "number of pre-dumps cannot be negative")
+	}
+
+	compression := req.GetCompression()
+	if compression == "" || compression == "none" {
+		compression = MIGRATE_DEFAULT_COMPRESSION
+	}
+	ext, err := cedana_io.ExtForCompression(compression)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	job := s.jobs.Get(ctx, jid)
+	if job == nil {
+		return nil, status.Errorf(codes.NotFound, "job %s not found", jid)
+	}
+	if job.IsRemote() {
+		return nil, status.Errorf(codes.FailedPrecondition, "job %s is on a remote host", jid)
+	}
+	if !job.IsRunning() {
+		return nil, status.Errorf(codes.FailedPrecondition, "job %s is not running (status: %s)", jid, job.Status())
+	}
+
+	log := log.With().Str("JID", jid).Str("destination", req.GetAddress()).Logger()
+
+	protocol := req.GetProtocol()
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	destination, err := client.New(req.GetAddress(), protocol)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to create client for destination: %v", err)
+	}
+	defer destination.Close()
+
+	if ok, err := destination.HealthCheckConnection(ctx); !ok {
+		return nil, status.Errorf(codes.Unavailable, "destination daemon is not serving: %v", err)
+	}
+
+	attachable := cedana_io.GetIOSlave(job.GetPID()) != nil
+
+	dir, err := os.MkdirTemp("", MIGRATE_DIR_PATTERN)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create migration dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := fmt.Sprintf("migrate-%s-%d", jid, time.Now().Unix())
+
+	details := &daemon.Details{}
+	if job.GetDetails() != nil {
+		details = proto.Clone(job.GetDetails()).(*daemon.Details)
+	}
+	details.JID = proto.String(jid)
+
+	restoreReq := &daemon.RestoreReq{
+		Type:       job.GetType(),
+		Details:    details,
+		Attachable: attachable,
+		Criu:       &criu_proto.CriuOpts{LazyPages: proto.Bool(req.GetLazy())},
+	}
+	if uids, gids := job.GetState().GetUIDs(), job.GetState().GetGIDs(); len(uids) > 0 && len(gids) > 0 {
+		restoreReq.UID = uids[0]
+		restoreReq.GID = gids[0]
+	}
+
+	// Stream the checkpoint as a tarball while it's being written, without writing the tarball
+	// to disk. Each pre-dump is streamed as soon as it's done, while the job keeps running, so
+	// only the pages dirtied since the last one are left to stream once the job is stopped.
+
+	preDumped := make(chan string)
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(cedana_io.TarIncremental(filepath.Join(dir, name), writer, compression, preDumped))
+	}()
+
+	type received struct {
+		resp *daemon.RestoreResp
+		err  error
+	}
+	receiving := make(chan received, 1)
+	go func() {
+		resp, err := destination.ReceiveMigration(ctx, restoreReq, name+".tar"+ext, reader)
+		reader.Close()
+		receiving <- received{resp, err}
+	}()
+
+	log.Info().Int32("pre-dumps", req.GetPreDumps()).Str("compression", compression).Msg("dumping job, streaming to destination")
+
+	// Leave the job stopped instead of killing it, so it can be resumed if the destination fails
+
+	dumpResp, err := s.Dump(context.WithValue(ctx, keys.PRE_DUMP_CHANNEL_CONTEXT_KEY, (chan<- string)(preDumped)), &daemon.DumpReq{
+		Dir:         dir,
+		Name:        name,
+		Compression: "none",
+		PreDumps:    req.GetPreDumps(),
+		Details:     &daemon.Details{JID: proto.String(jid)},
+		Criu:        &criu_proto.CriuOpts{LeaveStopped: proto.Bool(true)},
+	})
+	if err != nil {
+		writer.CloseWithError(err) // before the rest is archived, so the destination gets no complete tarball
+	}
+	close(preDumped)
+	if err != nil {
+		<-receiving
+		return nil, err
+	}
+	path := dumpResp.GetPaths()[0]
+	state := dumpResp.GetState()
+
+	// The checkpoint is only needed for the migration
+	defer func() {
+		if checkpoint := s.jobs.GetLatestCheckpoint(jid); checkpoint != nil && checkpoint.GetPath() == path {
+			s.jobs.DeleteCheckpoint(checkpoint.GetID())
+		}
+	}()
+
+	log.Info().Msg("streaming rest of checkpoint to destination")
+
+	result := <-receiving
+	if result.err != nil {
+		if err := utils.SignalProcessTree(state, syscall.SIGCONT); err != nil {
+			log.Error().Err(err).Msg("failed to resume job after failed migration")
+		} else {
+			log.Warn().Msg("resumed job after failed migration")
+		}
+		return nil, status.Errorf(codes.Aborted, "destination failed to restore job: %v", result.err)
+	}
+	restoreResp := result.resp
+
+	// Destination has confirmed, so the source can now be killed
+
+	resp := &daemon.MigrateResp{PID: restoreResp.GetPID()}
+
+	err = s.jobs.Kill(ctx, jid, syscall.SIGKILL)
+	if err != nil {
+		resp.Messages = append(resp.Messages, fmt.Sprintf("Failed to kill source job: %v", err))
+	} else if err := <-s.jobs.Done(jid); err != nil {
+		resp.Messages = append(resp.Messages, err.Error())
+	}
+
+	// With a remote DB, the job record is shared with the destination, so keep it
+	if !config.Global.DB.Remote {
+		s.jobs.Delete(jid)
+	}
+
+	log.Info().Uint32("PID", resp.PID).Msg("migration successful")
+	resp.Messages = append(resp.Messages, fmt.Sprintf("Migrated job %s to %s, PID: %d", jid, req.GetAddress(), resp.PID))
+
+	return resp, nil
+}
+
+// ReceiveMigration receives a checkpoint streamed by the source daemon of a migration,
+// and restores it as a managed job on this host.
+func (s *Server) ReceiveMigration(stream daemongrpc.Daemon_ReceiveMigrationServer) error {
+	ctx := stream.Context()
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	req := first.GetRestore()
+	if req == nil {
+		return status.Errorf(codes.InvalidArgument, "missing restore request")
+	}
+	jid := req.GetDetails().GetJID()
+	if jid == "" {
+		return status.Errorf(codes.InvalidArgument, "missing JID")
+	}
+	name := filepath.Base(first.GetName())
+	compression, err := cedana_io.CompressionFromExt(name)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid checkpoint name: %v", err)
+	}
+
+	log := log.With().Str("JID", jid).Logger()
+
+	created := false
+	if job := s.jobs.Get(ctx, jid); job == nil {
+		_, err = s.jobs.New(jid, req.GetType())
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to create job: %v", err)
+		}
+		created = true
+	} else if job.IsRunning() && !job.IsRemote() {
+		return status.Errorf(codes.AlreadyExists, "job %s is already running on this host", jid)
+	}
+
+	dir, err := os.MkdirTemp("", MIGRATE_DIR_PATTERN)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to create migration dir: %v", err)
+	}
+
+	// The lazy-pages daemon keeps serving pages from the images directory after the
+	// restore returns, so only remove it once it's done
+
+	restored := false
+	if req.GetCriu().GetLazyPages() {
+		lazyPagesDone := make(chan struct{})
+		ctx = context.WithValue(ctx, keys.LAZY_PAGES_DONE_CONTEXT_KEY, lazyPagesDone)
+		defer func() {
+			if !restored {
+				os.RemoveAll(dir)
+				return
+			}
+			s.wg.Go(func() {
+				<-lazyPagesDone
+				os.RemoveAll(dir)
+			})
+		}()
+	} else {
+		defer os.RemoveAll(dir)
+	}
+
+	// Untar the checkpoint as it's received, without writing the tarball to disk first
+
+	receive := func() (err error) {
+		reader := &migrationReader{stream: stream}
+
+		err = cedana_io.Untar(reader, dir, compression)
+		if err == nil {
+			_, err = io.Copy(io.Discard, reader) // any trailing padding
+		}
+		switch {
+		case reader.err != nil:
+			return status.Errorf(codes.Aborted, "failed to receive checkpoint: %v", reader.err)
+		case err != nil:
+			return status.Errorf(codes.InvalidArgument, "failed to extract checkpoint: %v", err)
+		}
+		return nil
+	}
+
+	log.Info().Str("name", name).Msg("receiving migrated checkpoint")
+
+	err = receive()
+	if err == nil {
+		req.Path = dir
+
+		var resp *daemon.RestoreResp
+		resp, err = s.Restore(ctx, req)
+		if err == nil {
+			restored = true
+			log.Info().Uint32("PID", resp.GetPID()).Msg("restored migrated job")
+			return stream.SendAndClose(resp)
+		}
+	}
+
+	if created {
+		s.jobs.Delete(jid)
+	}
+
+	return err
+}
+
+// Reads the data of migration chunks as they are received.
+type migrationReader struct {
+	stream daemongrpc.Daemon_ReceiveMigrationServer
+	data   []byte
+	err    error // receive error, other than EOF
+}
+
+func (r *migrationReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		chunk, err := r.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			r.err = err
+			return 0, err
+		}
+		r.data = chunk.GetData()
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
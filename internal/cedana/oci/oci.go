@@ -0,0 +1,522 @@
+package oci
+
+// Packaging of checkpoints as OCI artifacts, so they can be distributed through any
+// OCI registry, or stored as an OCI image layout on disk.
+//
+// The artifact manifest has a config blob with the checkpoint metadata, a layer with
+// the CRIU images (and everything else in the images directory), and a layer for each
+// container rootfs diff of the checkpoint.
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cedana/cedana/internal/cedana/filesystem"
+	cedana_io "github.com/cedana/cedana/pkg/io"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/content/local"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/image-spec/specs-go"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ARTIFACT_TYPE     = "application/vnd.cedana.checkpoint.v1"
+	CONFIG_MEDIA_TYPE = "application/vnd.cedana.checkpoint.config.v1+json"
+	IMAGES_MEDIA_TYPE = "application/vnd.cedana.checkpoint.images.v1.tar+gzip"
+
+	// Files in the images directory with this suffix are container rootfs diffs (gzipped
+	// tar layers), which are exported as separate layers, usable as regular image layers.
+	ROOTFS_DIFF_SUFFIX = ".rootfs.tar.gz"
+
+	ANNOTATION_ID           = "io.cedana.checkpoint.id"
+	ANNOTATION_JID          = "io.cedana.checkpoint.jid"
+	ANNOTATION_TYPE         = "io.cedana.checkpoint.type"
+	ANNOTATION_VERSION      = "io.cedana.checkpoint.version"
+	ANNOTATION_CRIU_VERSION = "io.cedana.checkpoint.criu-version"
+	ANNOTATION_HOST         = "io.cedana.checkpoint.host"
+
+	LAYOUT_DIR_PATTERN = "oci-*"
+	INGEST_DIR         = "ingest" // used by the content store for in-progress writes
+)
+
+// Metadata is saved as the config blob of the artifact.
+type Metadata struct {
+	ID       string               `json:"id"`
+	JID      string               `json:"jid"`
+	Type     string               `json:"type"`
+	Time     int64                `json:"time"`               // unix millis of the checkpoint
+	Manifest *filesystem.Manifest `json:"manifest,omitempty"` // manifest of the images directory
+	State    json.RawMessage      `json:"state,omitempty"`    // process state saved with the dump
+}
+
+type Auth struct {
+	Username string
+	Secret   string
+}
+
+// IsLayout returns true if the reference is an OCI image layout directory, i.e. an absolute path.
+// Anything else is treated as a registry reference.
+func IsLayout(ref string) bool {
+	return filepath.IsAbs(ref)
+}
+
+// Export packages the images directory as an OCI artifact, and writes it to the destination
+// layout directory, or pushes it to the destination registry reference. Rootfs diffs are
+// taken from both the images directory and the rootfs directory, if any.
+// Returns the descriptor of the artifact manifest.
+func Export(ctx context.Context, imagesDirectory string, rootfsDirectory string, metadata *Metadata, dest string, auth Auth) (v1.Descriptor, error) {
+	layoutDir := dest
+	if !IsLayout(dest) {
+		tmp, err := os.MkdirTemp("", LAYOUT_DIR_PATTERN)
+		if err != nil {
+			return v1.Descriptor{}, fmt.Errorf("failed to create temporary layout dir: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		layoutDir = tmp
+	}
+
+	store, err := local.NewStore(layoutDir)
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("failed to open layout dir: %w", err)
+	}
+	defer os.RemoveAll(filepath.Join(layoutDir, INGEST_DIR))
+
+	desc, err := writeArtifact(ctx, store, imagesDirectory, rootfsDirectory, metadata)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	if IsLayout(dest) {
+		desc.Annotations = map[string]string{v1.AnnotationRefName: metadata.ID}
+		if err := addToIndex(layoutDir, desc); err != nil {
+			return v1.Descriptor{}, fmt.Errorf("failed to write layout index: %w", err)
+		}
+		desc.Annotations = nil
+		return desc, nil
+	}
+
+	log.Debug().Str("ref", dest).Str("digest", desc.Digest.String()).Msg("pushing checkpoint artifact")
+
+	if err := push(ctx, store, desc, dest, auth); err != nil {
+		return v1.Descriptor{}, fmt.Errorf("failed to push to %s: %w", dest, err)
+	}
+
+	return desc, nil
+}
+
+// Import reads the OCI artifact from the source layout directory, or pulls it from the
+// source registry reference, and extracts it to the images directory, which should
+// already exist. If a layout has multiple checkpoints, the latest added is imported.
+// All blobs are verified against their digests.
+func Import(ctx context.Context, src string, imagesDirectory string, auth Auth) (*Metadata, error) {
+	layoutDir := src
+	if !IsLayout(src) {
+		tmp, err := os.MkdirTemp("", LAYOUT_DIR_PATTERN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary layout dir: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		layoutDir = tmp
+	}
+
+	store, err := local.NewStore(layoutDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open layout dir: %w", err)
+	}
+
+	if !IsLayout(src) {
+		log.Debug().Str("ref", src).Msg("pulling checkpoint artifact")
+
+		desc, err := pull(ctx, store, src, auth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pull from %s: %w", src, err)
+		}
+		if err := addToIndex(layoutDir, desc); err != nil {
+			return nil, fmt.Errorf("failed to write layout index: %w", err)
+		}
+	}
+
+	manifest, err := findManifest(ctx, store, layoutDir)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &Metadata{}
+	config, err := readBlob(ctx, store, manifest.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := json.Unmarshal(config, metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	for _, layer := range manifest.Layers {
+		switch layer.MediaType {
+		case IMAGES_MEDIA_TYPE:
+			err = extractBlob(ctx, store, layer, func(r io.Reader) error {
+				return cedana_io.Untar(r, imagesDirectory, "gzip")
+			})
+		case v1.MediaTypeImageLayerGzip:
+			name := layer.Annotations[v1.AnnotationTitle]
+			if name != filepath.Base(name) || !strings.HasSuffix(name, ROOTFS_DIFF_SUFFIX) {
+				return nil, fmt.Errorf("invalid rootfs layer title '%s'", name)
+			}
+			err = extractBlob(ctx, store, layer, func(r io.Reader) error {
+				return copyToFile(r, filepath.Join(imagesDirectory, name))
+			})
+		default:
+			log.Debug().Str("media_type", layer.MediaType).Msg("skipping unknown layer")
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract layer %s: %w", layer.Digest, err)
+		}
+	}
+
+	return metadata, nil
+}
+
+// Annotations returns the annotations to set on the artifact manifest.
+func (m *Metadata) Annotations() map[string]string {
+	annotations := map[string]string{
+		ANNOTATION_ID:        m.ID,
+		ANNOTATION_JID:       m.JID,
+		ANNOTATION_TYPE:      m.Type,
+		v1.AnnotationCreated: time.UnixMilli(m.Time).UTC().Format(time.RFC3339),
+	}
+	if m.Manifest != nil {
+		annotations[ANNOTATION_VERSION] = m.Manifest.Version
+		annotations[ANNOTATION_CRIU_VERSION] = strconv.Itoa(m.Manifest.CRIUVersion)
+		annotations[ANNOTATION_HOST] = m.Manifest.Host.GetHostname()
+	}
+	return annotations
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+func writeArtifact(ctx context.Context, store content.Store, imagesDirectory string, rootfsDirectory string, metadata *Metadata) (v1.Descriptor, error) {
+	config, err := json.Marshal(metadata)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	configDesc, err := writeBlob(ctx, store, CONFIG_MEDIA_TYPE, func(w io.Writer) error {
+		_, err := w.Write(config)
+		return err
+	})
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("failed to write config: %w", err)
+	}
+
+	imagesDesc, err := writeBlob(ctx, store, IMAGES_MEDIA_TYPE, func(w io.Writer) error {
+		return tarImages(imagesDirectory, w)
+	})
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("failed to write images layer: %w", err)
+	}
+	layers := []v1.Descriptor{imagesDesc}
+
+	for _, dir := range []string{imagesDirectory, rootfsDirectory} {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return v1.Descriptor{}, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.Type().IsRegular() || !strings.HasSuffix(name, ROOTFS_DIFF_SUFFIX) {
+				continue
+			}
+			layer, err := writeBlob(ctx, store, v1.MediaTypeImageLayerGzip, func(w io.Writer) error {
+				f, err := os.Open(filepath.Join(dir, name))
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				_, err = io.Copy(w, f)
+				return err
+			})
+			if err != nil {
+				return v1.Descriptor{}, fmt.Errorf("failed to write rootfs layer %s: %w", name, err)
+			}
+			layer.Annotations = map[string]string{v1.AnnotationTitle: name}
+			layers = append(layers, layer)
+		}
+	}
+
+	manifest := v1.Manifest{
+		Versioned:    specs.Versioned{SchemaVersion: 2},
+		MediaType:    v1.MediaTypeImageManifest,
+		ArtifactType: ARTIFACT_TYPE,
+		Config:       configDesc,
+		Layers:       layers,
+		Annotations:  metadata.Annotations(),
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	desc, err := writeBlob(ctx, store, v1.MediaTypeImageManifest, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	desc.ArtifactType = ARTIFACT_TYPE
+
+	return desc, nil
+}
+
+// Writes a blob to the content store, computing its digest as it's written.
+func writeBlob(ctx context.Context, store content.Store, mediaType string, write func(io.Writer) error) (v1.Descriptor, error) {
+	ref := fmt.Sprintf("cedana-%d", time.Now().UnixNano())
+	writer, err := content.OpenWriter(ctx, store, content.WithRef(ref))
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	defer writer.Close()
+
+	if err := write(writer); err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	status, err := writer.Status()
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	desc := v1.Descriptor{
+		MediaType: mediaType,
+		Digest:    writer.Digest(),
+		Size:      status.Offset,
+	}
+
+	if err := writer.Commit(ctx, desc.Size, desc.Digest); err != nil && !errdefs.IsAlreadyExists(err) {
+		return v1.Descriptor{}, err
+	}
+
+	return desc, nil
+}
+
+func readBlob(ctx context.Context, store content.Store, desc v1.Descriptor) ([]byte, error) {
+	var data []byte
+	err := extractBlob(ctx, store, desc, func(r io.Reader) (err error) {
+		data, err = io.ReadAll(r)
+		return err
+	})
+	return data, err
+}
+
+// Passes the blob to the extract function, and verifies it against its descriptor.
+func extractBlob(ctx context.Context, store content.Store, desc v1.Descriptor, extract func(io.Reader) error) error {
+	readerAt, err := store.ReaderAt(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer readerAt.Close()
+
+	if readerAt.Size() != desc.Size {
+		return fmt.Errorf("size mismatch for %s: expected %d, got %d", desc.Digest, desc.Size, readerAt.Size())
+	}
+
+	verifier := desc.Digest.Verifier()
+	reader := io.TeeReader(content.NewReader(readerAt), verifier)
+
+	if err := extract(reader); err != nil {
+		return err
+	}
+	// Drain what the extract function did not consume, e.g. tar padding
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return err
+	}
+
+	if !verifier.Verified() {
+		return fmt.Errorf("digest mismatch for %s", desc.Digest)
+	}
+
+	return nil
+}
+
+// Finds the (latest) checkpoint artifact manifest in the layout index.
+func findManifest(ctx context.Context, store content.Store, layoutDir string) (*v1.Manifest, error) {
+	index, err := readIndex(layoutDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layout index: %w", err)
+	}
+
+	manifest, err := findManifestIn(ctx, store, index.Manifests)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("no checkpoint artifact found")
+	}
+
+	return manifest, nil
+}
+
+// Searches the descriptors in reverse, descending into nested indexes.
+func findManifestIn(ctx context.Context, store content.Store, descs []v1.Descriptor) (*v1.Manifest, error) {
+	for i := len(descs) - 1; i >= 0; i-- {
+		desc := descs[i]
+
+		switch desc.MediaType {
+		case v1.MediaTypeImageIndex:
+			data, err := readBlob(ctx, store, desc)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read index %s: %w", desc.Digest, err)
+			}
+			index := v1.Index{}
+			if err := json.Unmarshal(data, &index); err != nil {
+				return nil, fmt.Errorf("failed to parse index %s: %w", desc.Digest, err)
+			}
+			manifest, err := findManifestIn(ctx, store, index.Manifests)
+			if manifest != nil || err != nil {
+				return manifest, err
+			}
+		case v1.MediaTypeImageManifest:
+			data, err := readBlob(ctx, store, desc)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read manifest %s: %w", desc.Digest, err)
+			}
+			manifest := &v1.Manifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse manifest %s: %w", desc.Digest, err)
+			}
+			if manifest.ArtifactType == ARTIFACT_TYPE || manifest.Config.MediaType == CONFIG_MEDIA_TYPE {
+				return manifest, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func readIndex(layoutDir string) (*v1.Index, error) {
+	index := &v1.Index{}
+	data, err := os.ReadFile(filepath.Join(layoutDir, v1.ImageIndexFile))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Adds the descriptor to the layout index, creating the layout if it does not exist.
+// Replaces any existing descriptor with the same ref name.
+func addToIndex(layoutDir string, desc v1.Descriptor) error {
+	layout, err := json.Marshal(v1.ImageLayout{Version: v1.ImageLayoutVersion})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(layoutDir, v1.ImageLayoutFile), layout, 0o644); err != nil {
+		return err
+	}
+
+	index, err := readIndex(layoutDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		index = &v1.Index{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			MediaType: v1.MediaTypeImageIndex,
+		}
+	} else if err != nil {
+		return err
+	}
+
+	name := desc.Annotations[v1.AnnotationRefName]
+	manifests := index.Manifests[:0]
+	for _, existing := range index.Manifests {
+		if name == "" || existing.Annotations[v1.AnnotationRefName] != name {
+			manifests = append(manifests, existing)
+		}
+	}
+	index.Manifests = append(manifests, desc)
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(layoutDir, v1.ImageIndexFile), data, 0o644)
+}
+
+// Writes a gzipped tarball of the images directory, excluding rootfs diffs, which are
+// exported as separate layers. Compatible with io.Untar.
+func tarImages(imagesDirectory string, w io.Writer) (err error) {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	defer func() {
+		err = errors.Join(err, tarWriter.Close(), gzipWriter.Close())
+	}()
+
+	return filepath.Walk(imagesDirectory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == imagesDirectory || strings.HasSuffix(info.Name(), ROOTFS_DIFF_SUFFIX) {
+			return nil
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name, err = filepath.Rel(imagesDirectory, path)
+		if err != nil {
+			return err
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tarWriter, f)
+		return err
+	})
+}
+
+func copyToFile(r io.Reader, path string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+	_, err = io.Copy(f, r)
+	return err
+}
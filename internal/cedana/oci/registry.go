@@ -0,0 +1,74 @@
+package oci
+
+// Pushing and pulling of artifacts to/from OCI registries
+
+import (
+	"context"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/containerd/platforms"
+	"github.com/distribution/reference"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Pushes the manifest and all its blobs from the content store to the registry.
+func push(ctx context.Context, store content.Store, desc v1.Descriptor, ref string, auth Auth) error {
+	named, err := reference.ParseDockerRef(ref)
+	if err != nil {
+		return err
+	}
+
+	pusher, err := newResolver(auth).Pusher(ctx, named.String())
+	if err != nil {
+		return err
+	}
+
+	return remotes.PushContent(ctx, pusher, desc, store, nil, platforms.All, nil)
+}
+
+// Pulls the manifest and all its blobs from the registry into the content store.
+// Returns the descriptor the reference resolved to.
+func pull(ctx context.Context, store content.Store, ref string, auth Auth) (v1.Descriptor, error) {
+	named, err := reference.ParseDockerRef(ref)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	resolver := newResolver(auth)
+
+	name, desc, err := resolver.Resolve(ctx, named.String())
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	handler := images.Handlers(
+		remotes.FetchHandler(store, fetcher),
+		images.ChildrenHandler(store),
+	)
+	if err := images.Dispatch(ctx, handler, nil, desc); err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	return desc, nil
+}
+
+func newResolver(auth Auth) remotes.Resolver {
+	authorizer := docker.NewDockerAuthorizer(docker.WithAuthCreds(func(string) (string, string, error) {
+		return auth.Username, auth.Secret, nil
+	}))
+
+	return docker.NewResolver(docker.ResolverOptions{
+		Hosts: docker.ConfigureDefaultRegistries(
+			docker.WithAuthorizer(authorizer),
+			docker.WithPlainHTTP(docker.MatchLocalhost),
+		),
+	})
+}
@@ -0,0 +1,42 @@
+package cedana
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cedana/cedana/internal/cedana/job"
+	"github.com/cedana/cedana/pkg/config"
+)
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Watches for preemption notices in the background, checkpointing and signaling jobs that
+// opted in
+func startPreemptionWatcher(lifetime context.Context, wg *sync.WaitGroup, preemptor *job.Preemptor) {
+	wg.Go(func() {
+		preemptor.Watch(lifetime)
+	})
+}
+
+func preemptionPolicyFromConfig() (policy job.PreemptionPolicy, err error) {
+	preemption := config.Global.Checkpoint.Preemption
+
+	policy.File = preemption.File
+	policy.URL = preemption.URL
+	policy.Compression = config.Global.Checkpoint.Compression
+
+	interval := preemption.Interval
+	if interval == "" {
+		interval = config.DEFAULT_CHECKPOINT_PREEMPTION_INTERVAL
+	}
+	policy.Interval, err = time.ParseDuration(interval)
+	if err != nil || policy.Interval <= 0 {
+		return policy, fmt.Errorf("invalid interval '%s'", interval)
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,56 @@
+package cedana
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cedana/cedana/internal/cedana/job"
+	"github.com/cedana/cedana/pkg/config"
+)
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Watches managed jobs for memory pressure in the background, dumping them based on the
+// configured thresholds
+func startPressureWatcher(lifetime context.Context, wg *sync.WaitGroup, watcher *job.PressureWatcher) {
+	wg.Go(func() {
+		watcher.Watch(lifetime)
+	})
+}
+
+func pressurePolicyFromConfig() (policy job.PressurePolicy, err error) {
+	pressure := config.Global.Checkpoint.Pressure
+
+	if pressure.MemoryPercent < 0 || pressure.MemoryPercent > 100 {
+		return policy, fmt.Errorf("invalid memory percent %v", pressure.MemoryPercent)
+	}
+	if pressure.PSI < 0 || pressure.PSI > 100 {
+		return policy, fmt.Errorf("invalid PSI %v", pressure.PSI)
+	}
+
+	policy.MemoryPercent = pressure.MemoryPercent
+	policy.PSI = pressure.PSI
+	policy.Events = pressure.Events
+	policy.LeaveRunning = pressure.LeaveRunning
+	policy.Compression = config.Global.Checkpoint.Compression
+
+	policy.Dir = pressure.Dir
+	if policy.Dir == "" {
+		policy.Dir = config.Global.Checkpoint.Dir
+	}
+
+	interval := pressure.Interval
+	if interval == "" {
+		interval = config.DEFAULT_CHECKPOINT_PRESSURE_INTERVAL
+	}
+	policy.Interval, err = time.ParseDuration(interval)
+	if err != nil || policy.Interval <= 0 {
+		return policy, fmt.Errorf("invalid interval '%s'", interval)
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,187 @@
+package process
+
+// Utilities for the cgroup v2 freezer of a process tree, and resource limits of jobs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/pkg/types"
+	"github.com/opencontainers/cgroups"
+	cgroupsManager "github.com/opencontainers/cgroups/manager"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	CGROUP_ROOT = "/sys/fs/cgroup"
+
+	// Transient cgroups created to freeze a process tree that shares its cgroup with
+	// other processes. Removed on unfreeze, after moving the tree back to the parent.
+	FREEZE_CGROUP_PREFIX = "cedana-freeze-"
+
+	// Parent of the cgroups created for jobs run with resource limits, one per job.
+	// Removed once the job exits, and created again on restore.
+	JOB_CGROUP_PARENT = "cedana"
+
+	CPU_PERIOD = 100000 // us, over which the CPU quota of a job is enforced
+)
+
+// Returns the cgroup v2 path (relative to the root) of the process.
+func cgroupOf(pid uint32) (string, error) {
+	paths, err := cgroups.ParseCgroupFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", err
+	}
+	path, ok := paths[""]
+	if !ok {
+		return "", fmt.Errorf("process %d is not in a cgroup v2 hierarchy", pid)
+	}
+	return path, nil
+}
+
+func cgroupManager(path string) (cgroups.Manager, error) {
+	return cgroupsManager.New(&cgroups.Cgroup{
+		Path:      path,
+		Resources: &cgroups.Resources{},
+	})
+}
+
+// Returns the cgroup to freeze the process tree with. Uses the tree's own cgroup if it has
+// no other processes, otherwise moves the tree into a transient child cgroup.
+func freezeCgroupFor(state *daemon.ProcessState) (path string, err error) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return "", fmt.Errorf("cgroup v2 is not available")
+	}
+
+	tree := treePIDs(state)
+
+	path, err = cgroupOf(state.GetPID())
+	if err != nil {
+		return "", err
+	}
+
+	for _, pid := range tree {
+		other, err := cgroupOf(uint32(pid))
+		if err != nil {
+			return "", err
+		}
+		if other != path {
+			return "", fmt.Errorf("process tree spans multiple cgroups")
+		}
+	}
+
+	if path != "/" {
+		all, err := cgroups.GetAllPids(filepath.Join(CGROUP_ROOT, path))
+		if err != nil {
+			return "", err
+		}
+		if !slices.ContainsFunc(all, func(pid int) bool { return !slices.Contains(tree, pid) }) {
+			return path, nil
+		}
+	}
+
+	transient := filepath.Join(path, fmt.Sprintf("%s%d", FREEZE_CGROUP_PREFIX, state.GetPID()))
+	dir := filepath.Join(CGROUP_ROOT, transient)
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create transient cgroup: %w", err)
+	}
+
+	for _, pid := range tree {
+		if err := cgroups.WriteCgroupProc(dir, pid); err != nil {
+			return "", errors.Join(err, releaseFreezeCgroup(transient))
+		}
+	}
+
+	return transient, nil
+}
+
+// Moves all processes in the transient freeze cgroup back to its parent, and removes it.
+func releaseFreezeCgroup(path string) error {
+	dir := filepath.Join(CGROUP_ROOT, path)
+	parent := filepath.Dir(dir)
+
+	pids, err := cgroups.GetPids(dir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, pid := range pids {
+		errs = append(errs, cgroups.WriteCgroupProc(parent, pid))
+	}
+	errs = append(errs, cgroups.RemovePath(dir))
+
+	return errors.Join(errs...)
+}
+
+func isFreezeCgroup(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), FREEZE_CGROUP_PREFIX)
+}
+
+func treePIDs(state *daemon.ProcessState) []int {
+	pids := []int{int(state.GetPID())}
+	for _, child := range state.GetChildren() {
+		pids = append(pids, treePIDs(child)...)
+	}
+	return pids
+}
+
+// Creates the cgroup of the job, limited to the given resources. Reuses it if it already
+// exists (e.g. left behind by a previous run), setting the limits again.
+func jobCgroup(jid string, resources *daemon.Resources) (cgroups.Manager, error) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return nil, fmt.Errorf("cgroup v2 is not available")
+	}
+	if jid == "" {
+		return nil, fmt.Errorf("missing JID")
+	}
+
+	limits := &cgroups.Resources{
+		Memory:    resources.GetMemory(),
+		PidsLimit: resources.GetPidsLimit(),
+	}
+	if cpus := resources.GetCPUs(); cpus > 0 {
+		limits.CpuPeriod = CPU_PERIOD
+		limits.CpuQuota = int64(cpus * CPU_PERIOD)
+	}
+	if weight := resources.GetIOWeight(); weight > 0 {
+		limits.Unified = map[string]string{"io.weight": fmt.Sprintf("default %d", weight)}
+	}
+
+	manager, err := cgroupsManager.New(&cgroups.Cgroup{
+		Path:      filepath.Join("/", JOB_CGROUP_PARENT, jid),
+		Resources: limits,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = manager.Apply(-1) // only creates it, and enables the controllers of its parents
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	err = manager.Set(limits)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to set cgroup limits: %w", err), manager.Destroy())
+	}
+
+	return manager, nil
+}
+
+// Removes the cgroup of the job once the given exit channel is closed.
+func releaseJobCgroupOnExit(opts types.Opts, manager cgroups.Manager, exited <-chan int) {
+	opts.WG.Go(func() {
+		<-exited
+		err := manager.Destroy()
+		if err != nil {
+			log.Debug().Err(err).Str("path", manager.Path("")).Msg("failed to remove job cgroup")
+		}
+	})
+}
@@ -9,6 +9,7 @@ import (
 	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
 
 	"github.com/cedana/cedana/pkg/criu"
+	cedana_io "github.com/cedana/cedana/pkg/io"
 	"github.com/cedana/cedana/pkg/types"
 	"github.com/cedana/cedana/pkg/utils"
 
@@ -146,7 +147,8 @@ func DetectIOUringForDump(next types.Dump) types.Dump {
 
 // Detects any open files that are in an external namespace
 // and sets appropriate options for CRIU.
-// Also detects any TTY files and sets options for CRIU.
+// Also detects any TTY files and sets options for CRIU. A PTY owned by the daemon is
+// also marked external, as it is re-created on restore.
 // Keeps the initial pass for older CRIU builds, then refreshes the list from
 // CRIU's query-ext-files hook after CRIU has seized the process tree.
 func AddExternalFilesForDump(next types.Dump) types.Dump {
@@ -161,7 +163,12 @@ func AddExternalFilesForDump(next types.Dump) types.Dump {
 			req.Criu = &criu_proto.CriuOpts{}
 		}
 
-		req.Criu.External = append(req.Criu.External, externalFileKeys(state)...)
+		ownedTTY := false
+		if slave := cedana_io.GetIOSlave(state.PID); slave != nil {
+			ownedTTY = slave.IsTTY()
+		}
+
+		req.Criu.External = append(req.Criu.External, externalFileKeys(state, ownedTTY)...)
 
 		// CRIU queries again after seizing the process tree. Re-read the frozen
 		// tree so file and mount changes since the initial snapshot are included.
@@ -174,7 +181,7 @@ func AddExternalFilesForDump(next types.Dump) types.Dump {
 					if err := utils.FillProcessState(ctx, pid, frozen, true); err != nil {
 						return nil, err
 					}
-					return externalFileKeys(frozen), nil
+					return externalFileKeys(frozen, ownedTTY), nil
 				},
 			})
 		}
@@ -184,7 +191,8 @@ func AddExternalFilesForDump(next types.Dump) types.Dump {
 }
 
 // externalFileKeys returns the CRIU '--external' keys for every open file in the state tree that lives outside the process's mount table.
-func externalFileKeys(state *daemon.ProcessState) []string {
+// If the daemon owns the process' PTY, its TTY files are included as well.
+func externalFileKeys(state *daemon.ProcessState, ownedTTY bool) []string {
 	mounts := make(map[uint64]any)
 	utils.WalkTree(state, "Mounts", "Children", func(m *daemon.Mount) bool {
 		mounts[m.ID] = nil
@@ -192,6 +200,7 @@ func externalFileKeys(state *daemon.ProcessState) []string {
 	})
 
 	var keys []string
+	ttys := make(map[string]bool)
 
 	utils.WalkTree(state, "OpenFiles", "Children", func(f *daemon.File) bool {
 		isPipe := strings.HasPrefix(f.Path, "pipe")
@@ -206,14 +215,16 @@ func externalFileKeys(state *daemon.ProcessState) []string {
 		internal := mountFound || isPipe || isSocket || isAnon || isMemfd
 		external := !internal
 
-		if external {
-			if f.IsTTY {
+		if f.IsTTY && (external || ownedTTY) {
+			key := fmt.Sprintf("tty[%x:%x]", f.Rdev, f.Dev)
+			if !ttys[key] {
 				log.Trace().Str("path", f.Path).Uint64("rdev", f.Rdev).Uint64("dev", f.Dev).Msg("marking TTY file as external")
-				keys = append(keys, fmt.Sprintf("tty[%x:%x]", f.Rdev, f.Dev))
-			} else {
-				log.Trace().Str("path", f.Path).Uint64("mount_id", f.MountID).Uint64("inode", f.Inode).Msg("marking file as external")
-				keys = append(keys, fmt.Sprintf("file[%x:%x]", f.MountID, f.Inode))
+				keys = append(keys, key)
+				ttys[key] = true
 			}
+		} else if external {
+			log.Trace().Str("path", f.Path).Uint64("mount_id", f.MountID).Uint64("inode", f.Inode).Msg("marking file as external")
+			keys = append(keys, fmt.Sprintf("file[%x:%x]", f.MountID, f.Inode))
 		}
 
 		return true
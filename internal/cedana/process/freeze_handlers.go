@@ -2,15 +2,64 @@ package process
 
 import (
 	"context"
+	"syscall"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"github.com/cedana/cedana/pkg/types"
+	"github.com/cedana/cedana/pkg/utils"
+	"github.com/opencontainers/cgroups"
+	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 var Freeze types.Freeze = freeze
 
+// Freezes the process tree using the cgroup v2 freezer, falling back to
+// stopping each process in the tree with SIGSTOP.
 func freeze(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daemon.DumpReq) (code func() <-chan int, err error) {
-	return nil, status.Errorf(codes.Unimplemented, "process freeze not implemented")
+	state := resp.GetState()
+
+	path, err := freezeCgroupFor(state)
+	if err == nil {
+		err = freezeCgroup(path)
+		if err == nil {
+			log.Debug().Uint32("PID", state.GetPID()).Str("cgroup", path).Msg("froze process tree with cgroup freezer")
+			return nil, nil
+		}
+		if status.Code(err) == codes.FailedPrecondition {
+			return nil, err
+		}
+		if isFreezeCgroup(path) {
+			if err := releaseFreezeCgroup(path); err != nil {
+				log.Warn().Err(err).Str("cgroup", path).Msg("failed to release transient cgroup")
+			}
+		}
+	}
+
+	log.Debug().Err(err).Uint32("PID", state.GetPID()).Msg("cgroup freezer unavailable, stopping process tree")
+
+	err = utils.SignalProcessTree(state, syscall.SIGSTOP)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to stop process tree: %v", err)
+	}
+
+	return nil, nil
+}
+
+func freezeCgroup(path string) error {
+	manager, err := cgroupManager(path)
+	if err != nil {
+		return err
+	}
+
+	freezerState, err := manager.GetFreezerState()
+	if err != nil {
+		return err
+	}
+	if freezerState == cgroups.Frozen {
+		return status.Errorf(codes.FailedPrecondition, "process cgroup is already frozen")
+	}
+
+	return manager.Freeze(cgroups.Frozen)
 }
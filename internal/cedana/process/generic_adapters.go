@@ -17,9 +17,47 @@ const (
 	OUT_FILE_FLAGS int         = os.O_CREATE | os.O_WRONLY | os.O_APPEND | os.O_TRUNC
 )
 
+// Allocates a PTY owned by the daemon, whose slave end is set up as the IO files for the
+// handlers to pick up. Its master end is exposed through an IO slave, for masters to attach
+// to and resize. Does nothing in serverless mode, where the caller's terminal is used instead.
+func SetupTTY[REQ, RESP any](next types.Handler[REQ, RESP]) types.Handler[REQ, RESP] {
+	return func(ctx context.Context, opts types.Opts, resp *RESP, req *REQ) (code func() <-chan int, err error) {
+		if opts.Serverless || !types.TTY(req) {
+			return next(ctx, opts, resp, req)
+		}
+
+		pty, tty, err := cedana_io.OpenPTY()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to allocate PTY: %v", err)
+		}
+		defer tty.Close() // the process has its own copy once started
+
+		id := rand.Uint32()
+
+		cedana_io.NewStreamIOSlaveTTY(opts.Lifetime, opts.WG, id, types.WaitFirstMaster(req), pty)
+		defer func() {
+			if err == nil {
+				cedana_io.SetIOSlaveExitCode(id, code())
+				cedana_io.SetIOSlavePID(id, types.PID(resp)) // Since PID should be available at this point
+			}
+		}()
+
+		opts.IO.Stdin = tty
+		opts.IO.Stdout = tty
+		opts.IO.Stderr = tty
+		opts.IO.TTY = tty
+
+		return next(ctx, opts, resp, req)
+	}
+}
+
 // Sets up the IO files for the handlers to simply pick up and plug in
 func SetupIO[REQ, RESP any](next types.Handler[REQ, RESP]) types.Handler[REQ, RESP] {
 	return func(ctx context.Context, opts types.Opts, resp *RESP, req *REQ) (code func() <-chan int, err error) {
+		if opts.IO.TTY != nil {
+			return next(ctx, opts, resp, req) // already set up
+		}
+
 		var stdin io.Reader
 		var stdout, stderr io.Writer
 
@@ -9,6 +9,7 @@ import (
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
 
+	"github.com/cedana/cedana/pkg/criu"
 	"github.com/cedana/cedana/pkg/types"
 	"github.com/cedana/cedana/pkg/utils"
 
@@ -51,6 +52,55 @@ func WritePIDFileForRestore(next types.Restore) types.Restore {
 	}
 }
 
+// Adapter that creates the cgroup of the job again, with the resource limits it was run with,
+// and restores the process into it. The restored tree inherits the cgroup of CRIU, unless
+// CRIU is asked to restore cgroups too, in which case they are restored under it.
+// The cgroup is removed once the process exits.
+func ApplyResourcesForRestore(next types.Restore) types.Restore {
+	return func(ctx context.Context, opts types.Opts, resp *daemon.RestoreResp, req *daemon.RestoreReq) (code func() <-chan int, err error) {
+		resources := req.GetDetails().GetResources()
+		if resources == nil {
+			return next(ctx, opts, resp, req)
+		}
+
+		if req.Criu == nil {
+			req.Criu = &criu_proto.CriuOpts{}
+		}
+
+		manager, err := jobCgroup(req.GetDetails().GetJID(), resources)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create job cgroup: %v", err)
+		}
+
+		callback := &criu.NotifyCallback{
+			InitializeFunc: func(ctx context.Context, criuPid int32) error {
+				err := manager.Apply(int(criuPid))
+				if err != nil {
+					return fmt.Errorf("failed to apply job cgroup to CRIU process: %v", err)
+				}
+				for c, p := range manager.GetPaths() {
+					req.Criu.CgRoot = append(req.Criu.CgRoot, &criu_proto.CgroupRoot{
+						Ctrl: proto.String(c),
+						Path: proto.String(strings.TrimPrefix(p, CGROUP_ROOT)),
+					})
+				}
+				return nil
+			},
+		}
+		opts.CRIUCallback.Include(callback)
+
+		code, err = next(ctx, opts, resp, req)
+		if err != nil {
+			manager.Destroy()
+			return nil, err
+		}
+
+		releaseJobCgroupOnExit(opts, manager, code())
+
+		return code, nil
+	}
+}
+
 // Reload process state from the dump dir in the restore response
 func ReloadProcessStateForRestore(next types.Restore) types.Restore {
 	return func(ctx context.Context, opts types.Opts, resp *daemon.RestoreResp, req *daemon.RestoreReq) (code func() <-chan int, err error) {
@@ -101,13 +151,42 @@ func DetectShellJobForRestore(next types.Restore) types.Restore {
 	}
 }
 
+// Detects if the process' stdio was a terminal owned by the daemon, in which case a new one
+// is allocated for the restore (see SetupTTY), and the restored process is made attachable.
+// Processes run in the caller's terminal (shell jobs) are left alone.
+func DetectTTYForRestore(next types.Restore) types.Restore {
+	return func(ctx context.Context, opts types.Opts, resp *daemon.RestoreResp, req *daemon.RestoreReq) (code func() <-chan int, err error) {
+		state := resp.GetState()
+		if state == nil {
+			log.Warn().Msg("no process info found. it should have been filled by an adapter")
+			return next(ctx, opts, resp, req)
+		}
+
+		if opts.Serverless || state.SID != state.PID {
+			return next(ctx, opts, resp, req)
+		}
+
+		for _, f := range state.GetOpenFiles() {
+			if f.Fd <= 2 && f.IsTTY {
+				log.Debug().Str("path", f.Path).Uint64("fd", f.Fd).Msg("found stdio TTY, allocating a new PTY for restore")
+				req.TTY = true
+				req.Attachable = true
+				break
+			}
+		}
+
+		return next(ctx, opts, resp, req)
+	}
+}
+
 // If req.Attachable is set, inherit fd is set to 0, 1, 2.
 // assuming CRIU will be spawned with these set to appropriate files later on.
 // If just req.Log is set, inherit fd is set only for 1, 2. stdin is not inherited.
 // If these options are not set, it is assumed that these files still exist
 // and the restore will just fail if they don't.
 //
-// If a file is a TTY, restore will fail because there is no TTY to inherit.
+// If a file is a TTY, the PTY allocated by the daemon is inherited in its place. Without one,
+// restore will fail because there is no TTY to inherit.
 //
 // If there were any external (namespace) files during dump, they are also
 // added to be inherited. Note that this would still fail if the files don't exist.
@@ -182,7 +261,12 @@ func InheritFilesForRestore(next types.Restore) types.Restore {
 				}
 				visitedStdioFds[f.Fd] = true
 
-				if f.IsTTY || opts.Serverless {
+				if f.IsTTY && opts.IO.TTY != nil {
+					// Inherit the PTY re-created by the daemon, as it was marked external on dump
+					extraFile = opts.IO.TTY
+					key = fmt.Sprintf("tty[%x:%x]", f.Rdev, f.Dev)
+					fd = int32(3 + len(opts.ExtraFiles))
+				} else if f.IsTTY || opts.Serverless {
 					if !opts.Serverless {
 						err = status.Errorf(codes.FailedPrecondition,
 							"found open file %s with fd %d which is a TTY and so restoring will fail because no TTY to inherit. Try --no-server restore", f.Path, f.Fd)
@@ -8,6 +8,8 @@ import (
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"github.com/cedana/cedana/pkg/types"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Adapter that writes PID to a file after the next handler is called.
@@ -42,3 +44,40 @@ func WritePIDFile(next types.Run) types.Run {
 		return code, nil
 	}
 }
+
+// Adapter that creates a cgroup for the job, limited to the requested resources, to start
+// the process in. The cgroup is removed once the process exits.
+func ApplyResources(next types.Run) types.Run {
+	return func(ctx context.Context, opts types.Opts, resp *daemon.RunResp, req *daemon.RunReq) (code func() <-chan int, err error) {
+		resources := req.GetDetails().GetResources()
+		if resources == nil {
+			return next(ctx, opts, resp, req)
+		}
+
+		manager, err := jobCgroup(req.JID, resources)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create job cgroup: %v", err)
+		}
+
+		dir, err := os.Open(manager.Path(""))
+		if err != nil {
+			manager.Destroy()
+			return nil, status.Errorf(codes.Internal, "failed to open job cgroup: %v", err)
+		}
+		defer dir.Close() // process is already in it once started
+
+		opts.Cgroup = dir
+
+		code, err = next(ctx, opts, resp, req)
+		if err != nil {
+			manager.Destroy()
+			return nil, err
+		}
+
+		log.Debug().Str("path", manager.Path("")).Uint32("PID", resp.PID).Msg("started process in job cgroup")
+
+		releaseJobCgroupOnExit(opts, manager, code())
+
+		return code, nil
+	}
+}
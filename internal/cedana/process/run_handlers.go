@@ -45,6 +45,16 @@ func Run(ctx context.Context, opts types.Opts, resp *daemon.RunResp, req *daemon
 	cmd.Stdout = opts.IO.Stdout
 	cmd.Stderr = opts.IO.Stderr
 
+	if opts.Cgroup != nil {
+		cmd.SysProcAttr.UseCgroupFD = true // Start directly in the cgroup, so no child escapes its limits
+		cmd.SysProcAttr.CgroupFD = int(opts.Cgroup.Fd())
+	}
+
+	if opts.IO.TTY != nil {
+		cmd.SysProcAttr.Setctty = true // Make the PTY the controlling terminal of the new session
+		cmd.SysProcAttr.Ctty = 0       // Child's stdin
+	}
+
 	if opts.Serverless {
 		cmd.SysProcAttr.Setsid = false                     // Use the current session
 		cmd.SysProcAttr.GidMappingsEnableSetgroups = false // Avoid permission issues when running as non-root user
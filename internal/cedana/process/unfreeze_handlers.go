@@ -2,14 +2,77 @@ package process
 
 import (
 	"context"
-	"fmt"
+	"syscall"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"github.com/cedana/cedana/pkg/types"
+	"github.com/cedana/cedana/pkg/utils"
+	"github.com/opencontainers/cgroups"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var Unfreeze types.Unfreeze = unfreeze
 
+// Thaws the process tree if its cgroup is frozen, otherwise continues
+// each process in the tree with SIGCONT.
 func unfreeze(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daemon.DumpReq) (code func() <-chan int, err error) {
-	return nil, fmt.Errorf("unfreeze not implemented for processes")
+	state := resp.GetState()
+
+	thawed, err := thawCgroupOf(state.GetPID())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to thaw process cgroup: %v", err)
+	}
+	if thawed {
+		return nil, nil
+	}
+
+	err = utils.SignalProcessTree(state, syscall.SIGCONT)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to continue process tree: %v", err)
+	}
+
+	return nil, nil
+}
+
+// Thaws the cgroup of the process if it's frozen. Returns false if it's not,
+// or if the cgroup v2 freezer is not available.
+func thawCgroupOf(pid uint32) (bool, error) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return false, nil
+	}
+
+	path, err := cgroupOf(pid)
+	if err != nil {
+		log.Debug().Err(err).Uint32("PID", pid).Msg("cgroup freezer unavailable")
+		return false, nil
+	}
+
+	manager, err := cgroupManager(path)
+	if err != nil {
+		return false, err
+	}
+
+	freezerState, err := manager.GetFreezerState()
+	if err != nil {
+		return false, err
+	}
+	if freezerState != cgroups.Frozen {
+		return false, nil
+	}
+
+	if err := manager.Freeze(cgroups.Thawed); err != nil {
+		return false, err
+	}
+
+	if isFreezeCgroup(path) {
+		if err := releaseFreezeCgroup(path); err != nil {
+			log.Warn().Err(err).Str("cgroup", path).Msg("failed to release transient cgroup")
+		}
+	}
+
+	log.Debug().Uint32("PID", pid).Str("cgroup", path).Msg("thawed process tree with cgroup freezer")
+
+	return true, nil
 }
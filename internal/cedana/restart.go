@@ -0,0 +1,37 @@
+package cedana
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cedana/cedana/internal/cedana/job"
+	"github.com/cedana/cedana/pkg/config"
+)
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+func restartBackoffFromConfig() (backoff job.RestartBackoff, err error) {
+	restart := config.Global.Checkpoint.Restart
+
+	initial := restart.Backoff
+	if initial == "" {
+		initial = config.DEFAULT_CHECKPOINT_RESTART_BACKOFF
+	}
+	backoff.Initial, err = time.ParseDuration(initial)
+	if err != nil || backoff.Initial < 0 {
+		return backoff, fmt.Errorf("invalid backoff '%s'", initial)
+	}
+
+	max := restart.MaxBackoff
+	if max == "" {
+		max = config.DEFAULT_CHECKPOINT_RESTART_MAX_BACKOFF
+	}
+	backoff.Max, err = time.ParseDuration(max)
+	if err != nil || backoff.Max < backoff.Initial {
+		return backoff, fmt.Errorf("invalid max backoff '%s'", max)
+	}
+
+	return backoff, nil
+}
@@ -3,7 +3,6 @@ package cedana
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"github.com/cedana/cedana/internal/cedana/criu"
@@ -41,10 +40,14 @@ func (s *Server) Restore(ctx context.Context, req *daemon.RestoreReq) (*daemon.R
 
 		pluginRestoreMiddleware, // middleware from plugins
 
+		process.ApplyResourcesForRestore,
+		process.DetectTTYForRestore,
+		process.SetupTTY[daemon.RestoreReq, daemon.RestoreResp],
 		process.InheritFilesForRestore,
 		process.AddExternalMountsForRestore,
 		process.SetupIO[daemon.RestoreReq, daemon.RestoreResp],
 		criu.CheckOptsForRestore,
+		criu.StartLazyPagesForRestore,
 	}
 
 	restore := pluginRestoreHandler().With(middleware...)
@@ -54,6 +57,7 @@ func (s *Server) Restore(ctx context.Context, req *daemon.RestoreReq) (*daemon.R
 
 	if req.GetDetails().GetJID() != "" || req.Attachable {
 		restore = restore.With(job.ManageRestore(s.jobs))
+		restore = restore.With(job.RestartOnExitForRestore(s.restarter))
 	}
 
 	restore = restore.With(criu.New[daemon.RestoreReq, daemon.RestoreResp](s.plugins))
@@ -99,6 +103,7 @@ func (s *Cedana) Restore(req *daemon.RestoreReq) (exitCode <-chan int, err error
 		process.AddExternalMountsForRestore,
 		process.SetupIO[daemon.RestoreReq, daemon.RestoreResp],
 		criu.CheckOptsForRestore,
+		criu.StartLazyPagesForRestore,
 	}
 
 	restore := pluginRestoreHandler().With(middleware...)
@@ -158,21 +163,18 @@ func pluginRestoreStorage(next types.Restore) types.Restore {
 	return func(ctx context.Context, opts types.Opts, resp *daemon.RestoreResp, req *daemon.RestoreReq) (code func() <-chan int, err error) {
 		dir := req.GetPath()
 
-		var storage io.Storage = &filesystem.Storage{}
-
-		if strings.Contains(dir, "://") {
-			pluginName := fmt.Sprintf("storage/%s", strings.Split(dir, "://")[0])
-			err := features.Storage.IfAvailable(func(name string, newPluginStorage func(ctx context.Context) (io.Storage, error)) (err error) {
-				if newPluginStorage == nil {
-					return fmt.Errorf("plugin '%s' does not implement '%s'", name, features.Storage)
-				}
-				storage, err = newPluginStorage(ctx)
-				return err
-			}, pluginName)
-			if err != nil {
-				return nil, status.Error(codes.Unavailable, err.Error())
-			}
+		storage, err := storageForPath(ctx, dir)
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+
+		// Always wrapped, so an encrypted checkpoint fails clearly when no key is configured,
+		// and deduplicated checkpoints are reassembled
+		key, err := checkpointKey(ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "failed to get encryption key: %v", err)
 		}
+		storage = io.NewDedupStorage(io.NewEncryptedStorage(storage, key), "")
 
 		opts.Storage = storage
 
@@ -185,6 +187,10 @@ func pluginRestoreStorage(next types.Restore) types.Restore {
 			return nil, status.Error(codes.Internal, "A minimum of 2 streams is required by streaming.")
 		}
 
+		if streams > 1 && req.GetCriu().GetLazyPages() {
+			return nil, status.Error(codes.Unimplemented, "Lazy restore is not supported with streaming.")
+		}
+
 		filesystem := filesystem.RestoreFilesystem
 		if streams > 1 {
 			filesystem = streamer.RestoreFilesystem(streams)
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/internal/cedana/job"
 	"github.com/cedana/cedana/pkg/features"
 	"github.com/cedana/cedana/pkg/profiling"
 	"github.com/cedana/cedana/pkg/types"
@@ -21,6 +22,10 @@ func (s *Server) RestoreVM(ctx context.Context, req *daemon.RestoreVMReq) (*daem
 
 	restore := pluginRestoreVMHandler().With(middleware...)
 
+	if req.GetDetails().GetJID() != "" { // If using job restore
+		restore = restore.With(job.ManageRestoreVM(s.jobs))
+	}
+
 	opts := types.Opts{
 		Lifetime: s.lifetime,
 		Plugins:  s.plugins,
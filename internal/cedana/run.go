@@ -24,6 +24,7 @@ func (s *Server) Run(ctx context.Context, req *daemon.RunReq) (*daemon.RunResp,
 	// inserted from a plugin or will be the built-in process run handler.
 
 	middleware := types.Middleware[types.Run]{
+		job.RestartOnExit(s.restarter),
 		job.Manage(s.jobs), // always manage jobs run through daemon
 		defaults.FillMissingRunDefaults,
 		validation.ValidateRunRequest,
@@ -32,6 +33,8 @@ func (s *Server) Run(ctx context.Context, req *daemon.RunReq) (*daemon.RunResp,
 
 		pluginRunMiddleware, // middleware from plugins
 
+		process.ApplyResources,
+		process.SetupTTY[daemon.RunReq, daemon.RunResp],
 		process.SetupIO[daemon.RunReq, daemon.RunResp],
 	}
 
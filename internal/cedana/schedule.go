@@ -0,0 +1,77 @@
+package cedana
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) Schedule(ctx context.Context, req *daemon.ScheduleReq) (*daemon.ScheduleResp, error) {
+	schedule := req.GetSchedule()
+	if schedule == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "missing schedule")
+	}
+
+	jid := schedule.GetJID()
+	if jid == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing JID")
+	}
+
+	job := s.jobs.Get(ctx, jid)
+	if job == nil {
+		return nil, status.Errorf(codes.NotFound, "job %s not found", jid)
+	}
+	if job.IsRemote() {
+		return nil, status.Errorf(codes.FailedPrecondition, "job %s is on a remote host", jid)
+	}
+
+	err := s.scheduler.Set(ctx, schedule)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to schedule checkpoints: %v", err)
+	}
+
+	interval := time.Duration(schedule.GetInterval()) * time.Millisecond
+	message := fmt.Sprintf("Scheduled checkpoints of job %s every %s to %s", jid, interval, schedule.GetDir())
+	if schedule.GetKeep() > 0 {
+		message += fmt.Sprintf(", keeping the latest %d", schedule.GetKeep())
+	}
+
+	return &daemon.ScheduleResp{Messages: []string{message}}, nil
+}
+
+func (s *Server) Unschedule(ctx context.Context, req *daemon.UnscheduleReq) (*daemon.UnscheduleResp, error) {
+	schedules, err := s.scheduler.List(ctx, req.GetJIDs()...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list schedules: %v", err)
+	}
+
+	if len(schedules) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no schedules found")
+	}
+
+	messages := []string{}
+
+	for _, schedule := range schedules {
+		err := s.scheduler.Remove(ctx, schedule.GetJID())
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("Failed to unschedule job %s: %v", schedule.GetJID(), err))
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("Unscheduled checkpoints of job %s", schedule.GetJID()))
+	}
+
+	return &daemon.UnscheduleResp{Messages: messages}, nil
+}
+
+func (s *Server) ListSchedules(ctx context.Context, req *daemon.ListSchedulesReq) (*daemon.ListSchedulesResp, error) {
+	schedules, err := s.scheduler.List(ctx, req.GetJIDs()...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list schedules: %v", err)
+	}
+
+	return &daemon.ListSchedulesResp{Schedules: schedules}, nil
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/cedana/cedana/pkg/channel"
 	"github.com/cedana/cedana/pkg/client"
 	"github.com/cedana/cedana/pkg/config"
+	cedana_io "github.com/cedana/cedana/pkg/io"
 	"github.com/cedana/cedana/pkg/logging"
 	"github.com/cedana/cedana/pkg/metrics"
 	"github.com/cedana/cedana/pkg/plugins"
@@ -41,9 +42,13 @@ type Server struct {
 
 	// fdStore stores a map of fds used for clh kata restores to persist network fds and send them
 	// to the appropriate clh vm api
-	fdStore sync.Map
-	jobs    job.Manager
-	db      db.DB
+	fdStore   sync.Map
+	jobs      job.Manager
+	scheduler *job.Scheduler
+	gc        *job.GC
+	preemptor *job.Preemptor
+	restarter *job.Restarter
+	db        db.DB
 
 	host    *daemon.Host
 	version string
@@ -84,6 +89,9 @@ func NewServer(ctx context.Context, opts *ServeOpts) (server *Server, err error)
 		return nil, fmt.Errorf("failed to put host info: %w", err)
 	}
 
+	cedana_io.ZstdLevel = config.Global.Checkpoint.Zstd.Level
+	cedana_io.ZstdConcurrency = config.Global.Checkpoint.Zstd.Concurrency
+
 	pluginManager := plugins.NewLocalManager()
 
 	gpuPoolSize := config.Global.GPU.PoolSize
@@ -122,6 +130,43 @@ func NewServer(ctx context.Context, opts *ServeOpts) (server *Server, err error)
 		version:      opts.Version,
 	}
 
+	// Background services below (scheduled checkpoints, memory pressure, preemption, restarts)
+	// dump, run and restore jobs through the same paths as requests
+
+	server.scheduler, err = job.NewScheduler(ctx, wg, jobManager, database, server.Dump, checkpointStorage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint scheduler: %w", err)
+	}
+
+	server.gc = job.NewGC(jobManager, host.ID, checkpointStorage)
+	err = startGC(ctx, wg, server.gc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start checkpoint GC: %w", err)
+	}
+
+	pressurePolicy, err := pressurePolicyFromConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid memory pressure config: %w", err)
+	}
+	if !pressurePolicy.IsZero() {
+		startPressureWatcher(ctx, wg, job.NewPressureWatcher(jobManager, host.ID, server.Dump, pressurePolicy))
+	}
+
+	preemptionPolicy, err := preemptionPolicyFromConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid preemption config: %w", err)
+	}
+	server.preemptor = job.NewPreemptor(jobManager, host.ID, server.Dump, preemptionPolicy)
+	if !preemptionPolicy.IsZero() {
+		startPreemptionWatcher(ctx, wg, server.preemptor)
+	}
+
+	restartBackoff, err := restartBackoffFromConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid restart config: %w", err)
+	}
+	server.restarter = job.NewRestarter(ctx, wg, jobManager, database, server.Run, server.Restore, restartBackoff)
+
 	daemongrpc.RegisterDaemonServer(server.grpcServer, server)
 	grpc_health_v1.RegisterHealthServer(server.grpcServer, server.healthServer)
 	reflection.Register(server.grpcServer)
@@ -117,8 +117,15 @@ func DumpFilesystem(streams int32) types.Adapter[types.Dump] {
 				storagePath = path
 			}
 
+			// Every dump carries a manifest of its shards, so it can be verified before restore
+			var criuVersion int
+			if opts.CRIU != nil {
+				criuVersion, _ = opts.CRIU.GetCriuVersion(ctx)
+			}
+
+			var fs *Fs
 			var waitForIO func() error
-			opts.DumpFs, waitForIO, err = NewStreamingFs(
+			fs, waitForIO, err = NewStreamingFs(
 				ctx,
 				imgStreamer.BinaryPaths()[0],
 				imagesDirectory,
@@ -131,6 +138,16 @@ func DumpFilesystem(streams int32) types.Adapter[types.Dump] {
 			if err != nil {
 				return nil, status.Errorf(codes.Internal, "failed to create streaming fs: %v", err)
 			}
+			opts.DumpFs = fs
+
+			// Waits for all shards to be written, and saves their manifest alongside
+			waitForShards := func() error {
+				if err := waitForIO(); err != nil {
+					return err
+				}
+				sums, size := fs.Shards()
+				return writeManifest(ctx, streamStorage, storagePath, sums, size, criuVersion)
+			}
 
 			// XXX: We do not differentiate between leave-running or not, because unfortunately CRIU
 			// does not close the streaming file descriptors on its side when the PostDumpFunc is triggered.
@@ -140,34 +157,36 @@ func DumpFilesystem(streams int32) types.Adapter[types.Dump] {
 
 				upload := func(ctx context.Context) error {
 					var wg sync.WaitGroup
-					errCh := make(chan error, streams)
 
+					names := []string{filesystem.MANIFEST_FILE}
 					for i := range streams {
-						wg.Add(1)
-						go func(i int32) {
-							defer wg.Done()
+						names = append(names, fmt.Sprintf(IMG_FILE_FORMATTER, i)+ext)
+					}
+					errCh := make(chan error, len(names))
 
-							localPath := filepath.Join(imagesDirectory, fmt.Sprintf(IMG_FILE_FORMATTER, i)+ext)
-							remotePath := path + string(os.PathSeparator) + fmt.Sprintf(IMG_FILE_FORMATTER, i) + ext // do not use filepath.Join as it removes a slash
+					for _, name := range names {
+						wg.Go(func() {
+							localPath := filepath.Join(imagesDirectory, name)
+							remotePath := path + string(os.PathSeparator) + name // do not use filepath.Join as it removes a slash
 
 							src, err := streamStorage.Open(ctx, localPath)
 							if err != nil {
-								errCh <- fmt.Errorf("failed to open local shard %d: %w", i, err)
+								errCh <- fmt.Errorf("failed to open local %s: %w", name, err)
 								return
 							}
 							defer src.Close()
 
 							dst, err := storage.Create(ctx, remotePath)
 							if err != nil {
-								errCh <- fmt.Errorf("failed to create remote shard %d: %w", i, err)
+								errCh <- fmt.Errorf("failed to create remote %s: %w", name, err)
 								return
 							}
 							defer dst.Close()
 
 							if _, err := io.Copy(dst, src); err != nil {
-								errCh <- fmt.Errorf("failed to upload shard %d: %w", i, err)
+								errCh <- fmt.Errorf("failed to upload %s: %w", name, err)
 							}
-						}(i)
+						})
 					}
 
 					wg.Wait()
@@ -183,7 +202,7 @@ func DumpFilesystem(streams int32) types.Adapter[types.Dump] {
 				}
 
 				defer func() {
-					err = errors.Join(err, waitForIO())
+					err = errors.Join(err, waitForShards())
 					if err != nil {
 						return
 					}
@@ -203,7 +222,7 @@ func DumpFilesystem(streams int32) types.Adapter[types.Dump] {
 				// Sync upload, wait for IO completion in PostDumpFunc
 				defer func() {
 					_, end := profiling.StartTimingCategory(ctx, "storage", waitForIO)
-					err = errors.Join(err, waitForIO())
+					err = errors.Join(err, waitForShards())
 					end()
 				}()
 			}
@@ -57,10 +57,14 @@ type Fs struct {
 	dir       string
 	globCache map[string][]string // Cache glob results since streamer state is consumed
 	globMutex sync.Mutex
+
+	// Checksums of the shards written, by name, for the manifest
+	names []string
+	sums  []*checksum
 }
 
-// For READ_ONLY mode, compression is automatically determined.
-// For WRITE_ONLY mode, compression may be specified.
+// For READ_ONLY mode, compression is automatically determined, and shards are verified against
+// the manifest (if any) before anything is served. For WRITE_ONLY mode, compression may be specified.
 // Returns a wait function that *must* be called to tell the streamer to shutdown,
 // and wait for it to finish streaming and exit gracefully. The wait function returns
 // any IO errors that occurred during the streaming process.
@@ -105,11 +109,27 @@ func NewStreamingFs(
 	wg := &sync.WaitGroup{}
 	io := &sync.WaitGroup{}
 	io.Add(int(streams))
-	ioErr := make(chan error, streams)
+	ioErr := make(chan error, 2*streams) // for an IO and a close error per stream
 	paths, err := imgPaths(ctx, storage, storagePath, mode, streams)
 	if err != nil {
 		return nil, nil, err
 	}
+
+	// Checksums of the shards, for the manifest
+	sums := make([]*checksum, streams)
+	names := make([]string, streams)
+
+	if mode == READ_ONLY {
+		manifest, err := readManifest(ctx, storage, storagePath)
+		if errors.Is(err, os.ErrNotExist) {
+			log.Debug().Msg("no manifest found in streamed dump, skipping verification")
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to read manifest: %w", err)
+		} else if err := verifyShards(ctx, storage, paths, manifest); err != nil {
+			return nil, nil, fmt.Errorf("checkpoint verification failed: %w", err)
+		}
+	}
+
 	for i := range streams {
 		switch mode {
 		case READ_ONLY:
@@ -149,6 +169,8 @@ func NewStreamingFs(
 				return nil, nil, err
 			}
 			path := paths[i] + ext
+			names[i] = filepath.Base(path)
+			sums[i] = newChecksum()
 			file, err := storage.Create(ctx, path)
 			if err != nil {
 				return nil, nil, err
@@ -167,7 +189,7 @@ func NewStreamingFs(
 					fmt.Sprintf("shard-%d", i),
 					compression,
 				)
-				_, err := cedana_io.WriteTo(readFds[i], file, compression)
+				_, err := cedana_io.WriteTo(readFds[i], sums[i].writer(file), compression)
 				readFds[i].Close()
 				if err != nil {
 					ioErr <- err
@@ -233,6 +255,8 @@ func NewStreamingFs(
 		conn:      nil,
 		dir:       imagesDir,
 		globCache: make(map[string][]string),
+		names:     names,
+		sums:      sums,
 	}
 
 	// Clean up on exit
@@ -296,6 +320,17 @@ func NewStreamingFs(
 	return fs, wait, nil
 }
 
+// Shards returns the checksums of the shards written, by name, and their total size.
+// Only valid for WRITE_ONLY mode, once the wait function has returned without error.
+func (fs *Fs) Shards() (sums map[string]string, size int64) {
+	sums = make(map[string]string)
+	for i, sum := range fs.sums {
+		sums[fs.names[i]] = sum.String()
+		size += sum.size
+	}
+	return sums, size
+}
+
 func (fs *Fs) Create(name string) (afero.File, error) {
 	if fs.mode != WRITE_ONLY {
 		return nil, fmt.Errorf("create failed: streaming filesystem not open for writing")
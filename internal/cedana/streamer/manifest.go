@@ -0,0 +1,161 @@
+package streamer
+
+// Streamed dumps carry a manifest of their shards, like other dumps do of their files (see
+// filesystem.Manifest). Checksums are computed as shards are streamed to storage. On restore,
+// all shards are verified before any of them is streamed to CRIU, at the cost of reading them twice.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sync"
+
+	"github.com/cedana/cedana/internal/cedana/filesystem"
+	cedana_io "github.com/cedana/cedana/pkg/io"
+	"github.com/cedana/cedana/pkg/utils"
+	"github.com/cedana/cedana/pkg/version"
+)
+
+// Saves the manifest of the shards, by name relative to the dir, to the dir in storage.
+func writeManifest(
+	ctx context.Context,
+	storage cedana_io.Storage,
+	dir string,
+	sums map[string]string,
+	size int64,
+	criuVersion int,
+) (err error) {
+	manifest := &filesystem.Manifest{
+		Version:     version.GetVersion(),
+		CRIUVersion: criuVersion,
+		Size:        size,
+		Files:       sums,
+	}
+
+	host, err := utils.GetHost(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get host info: %w", err)
+	}
+	manifest.Host = host
+
+	file, err := storage.Create(ctx, dir+"/"+filesystem.MANIFEST_FILE) // do not use filepath.Join as it removes a slash (for remote)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	return json.NewEncoder(file).Encode(manifest)
+}
+
+// Reads the manifest of the shards from the dir in storage.
+// Returns an error satisfying errors.Is(err, fs.ErrNotExist) if there is none.
+func readManifest(ctx context.Context, storage cedana_io.Storage, dir string) (*filesystem.Manifest, error) {
+	list, err := storage.ReadDir(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, entry := range list {
+		if filepath.Base(entry) == filesystem.MANIFEST_FILE {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fs.ErrNotExist
+	}
+
+	file, err := storage.Open(ctx, dir+"/"+filesystem.MANIFEST_FILE)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	manifest := &filesystem.Manifest{}
+	err = json.NewDecoder(file).Decode(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Checks the shards at the given paths in storage against the manifest, reading them in parallel.
+func verifyShards(ctx context.Context, storage cedana_io.Storage, paths []string, manifest *filesystem.Manifest) error {
+	if len(manifest.Files) != len(paths) {
+		return fmt.Errorf("expected %d shards, got %d", len(manifest.Files), len(paths))
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(paths))
+
+	for i, path := range paths {
+		name := filepath.Base(path)
+		expected, ok := manifest.Files[name]
+		if !ok {
+			return fmt.Errorf("%s: not in manifest", name)
+		}
+		wg.Go(func() {
+			file, err := storage.Open(ctx, path)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", name, err)
+				return
+			}
+			defer file.Close()
+
+			sum := newChecksum()
+			if err := sum.drain(file); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", name, err)
+				return
+			}
+			if sum.String() != expected {
+				errs[i] = fmt.Errorf("%s: checksum mismatch", name)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Computes the checksum and size of all data written to it.
+type checksum struct {
+	hash hash.Hash
+	size int64
+}
+
+func newChecksum() *checksum {
+	return &checksum{hash: sha256.New()}
+}
+
+func (c *checksum) Write(p []byte) (int, error) {
+	c.size += int64(len(p))
+	return c.hash.Write(p)
+}
+
+func (c *checksum) String() string {
+	return fmt.Sprintf("%x", c.hash.Sum(nil))
+}
+
+// Returns a writer that computes the checksum of all data written through it.
+func (c *checksum) writer(w io.Writer) io.Writer {
+	return io.MultiWriter(w, c)
+}
+
+// Reads the rest of r into the checksum.
+func (c *checksum) drain(r io.Reader) error {
+	_, err := io.Copy(c, r)
+	return err
+}
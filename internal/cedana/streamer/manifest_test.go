@@ -0,0 +1,67 @@
+package streamer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cedana/cedana/internal/cedana/filesystem"
+)
+
+func TestManifest(t *testing.T) {
+	ctx := context.Background()
+	storage := &filesystem.Storage{}
+	dir := t.TempDir()
+
+	_, err := readManifest(ctx, storage, dir)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected no manifest, got %v", err)
+	}
+
+	sum := newChecksum()
+	var shard bytes.Buffer
+	if _, err := sum.writer(&shard).Write([]byte("shard data")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "img-0")
+	if err := os.WriteFile(path, shard.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = writeManifest(ctx, storage, dir, map[string]string{"img-0": sum.String()}, sum.size, 40000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := readManifest(ctx, storage, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Size != int64(len("shard data")) {
+		t.Errorf("expected size %d, got %d", len("shard data"), manifest.Size)
+	}
+	if manifest.CRIUVersion != 40000 {
+		t.Errorf("expected CRIU version 40000, got %d", manifest.CRIUVersion)
+	}
+
+	if err := verifyShards(ctx, storage, []string{path}, manifest); err != nil {
+		t.Errorf("expected shards to be verified, got %v", err)
+	}
+
+	// Shards are verified before being streamed, so corruption is caught before restore
+
+	if err := os.WriteFile(path, []byte("shard dat4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyShards(ctx, storage, []string{path}, manifest); err == nil {
+		t.Error("expected corrupted shard to fail verification")
+	}
+
+	if err := verifyShards(ctx, storage, []string{path, filepath.Join(dir, "img-1")}, manifest); err == nil {
+		t.Error("expected extra shard to fail verification")
+	}
+}
@@ -21,6 +21,9 @@ func ValidateDumpRequest(next types.Dump) types.Dump {
 		if req.GetType() == "" {
 			return nil, status.Errorf(codes.InvalidArgument, "missing type")
 		}
+		if req.GetPreDumps() < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid number of pre-dumps: %d", req.GetPreDumps())
+		}
 
 		return next(ctx, opts, resp, req)
 	}
@@ -18,6 +18,12 @@ func ValidateRunRequest(next types.Run) types.Run {
 		if req.GetDetails() == nil {
 			return nil, status.Errorf(codes.InvalidArgument, "Details are required")
 		}
+		if req.GetTTY() && req.GetType() != "process" {
+			return nil, status.Errorf(codes.Unimplemented, "TTY is only supported for processes")
+		}
+		if req.GetDetails().GetResources() != nil && req.GetType() != "process" {
+			return nil, status.Errorf(codes.Unimplemented, "resource limits are only supported for processes")
+		}
 		// Check if JID already exists
 		return next(ctx, opts, resp, req)
 	}
@@ -13,6 +13,8 @@ type DB interface {
 	Job
 	Host
 	Checkpoint
+	Schedule
+	RestartPolicy
 }
 
 type Job interface {
@@ -35,6 +37,18 @@ type Checkpoint interface {
 	DeleteCheckpoint(ctx context.Context, id string) error
 }
 
+type Schedule interface {
+	PutSchedule(ctx context.Context, schedule *daemon.Schedule) error
+	ListSchedules(ctx context.Context, jids ...string) ([]*daemon.Schedule, error)
+	DeleteSchedule(ctx context.Context, jid string) error
+}
+
+type RestartPolicy interface {
+	PutRestartPolicy(ctx context.Context, policy *daemon.RestartPolicy) error
+	ListRestartPolicies(ctx context.Context, jids ...string) ([]*daemon.RestartPolicy, error)
+	DeleteRestartPolicy(ctx context.Context, jid string) error
+}
+
 /////////////////
 //// Helpers ////
 /////////////////
@@ -80,3 +94,27 @@ func (UnimplementedDB) ListCheckpointsByJID(ctx context.Context, jids ...string)
 func (UnimplementedDB) DeleteCheckpoint(ctx context.Context, id string) error {
 	return errors.New("unimplemented")
 }
+
+func (UnimplementedDB) PutSchedule(ctx context.Context, schedule *daemon.Schedule) error {
+	return errors.New("unimplemented")
+}
+
+func (UnimplementedDB) ListSchedules(ctx context.Context, jids ...string) ([]*daemon.Schedule, error) {
+	return nil, errors.New("unimplemented")
+}
+
+func (UnimplementedDB) DeleteSchedule(ctx context.Context, jid string) error {
+	return errors.New("unimplemented")
+}
+
+func (UnimplementedDB) PutRestartPolicy(ctx context.Context, policy *daemon.RestartPolicy) error {
+	return errors.New("unimplemented")
+}
+
+func (UnimplementedDB) ListRestartPolicies(ctx context.Context, jids ...string) ([]*daemon.RestartPolicy, error) {
+	return nil, errors.New("unimplemented")
+}
+
+func (UnimplementedDB) DeleteRestartPolicy(ctx context.Context, jid string) error {
+	return errors.New("unimplemented")
+}
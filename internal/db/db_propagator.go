@@ -344,3 +344,77 @@ func (db *PropagatorDB) DeleteHost(ctx context.Context, id string) error {
 
 	return nil
 }
+
+////////////////
+/// Schedule ///
+////////////////
+
+// Schedules are only ever run by the local daemon, so are kept in the fallback DBs
+
+func (db *PropagatorDB) PutSchedule(ctx context.Context, schedule *daemon.Schedule) error {
+	if len(db.fallback) == 0 {
+		return fmt.Errorf("no fallback DB for schedules")
+	}
+	for _, fallback := range db.fallback {
+		if err := fallback.PutSchedule(ctx, schedule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *PropagatorDB) ListSchedules(ctx context.Context, jids ...string) ([]*daemon.Schedule, error) {
+	if len(db.fallback) == 0 {
+		return nil, fmt.Errorf("no fallback DB for schedules")
+	}
+	return db.fallback[0].ListSchedules(ctx, jids...)
+}
+
+func (db *PropagatorDB) DeleteSchedule(ctx context.Context, jid string) error {
+	if len(db.fallback) == 0 {
+		return fmt.Errorf("no fallback DB for schedules")
+	}
+	for _, fallback := range db.fallback {
+		if err := fallback.DeleteSchedule(ctx, jid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//////////////////////
+/// Restart Policy ///
+//////////////////////
+
+// Restarts are only ever done by the local daemon, so policies are kept in the fallback DBs
+
+func (db *PropagatorDB) PutRestartPolicy(ctx context.Context, policy *daemon.RestartPolicy) error {
+	if len(db.fallback) == 0 {
+		return fmt.Errorf("no fallback DB for restart policies")
+	}
+	for _, fallback := range db.fallback {
+		if err := fallback.PutRestartPolicy(ctx, policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *PropagatorDB) ListRestartPolicies(ctx context.Context, jids ...string) ([]*daemon.RestartPolicy, error) {
+	if len(db.fallback) == 0 {
+		return nil, fmt.Errorf("no fallback DB for restart policies")
+	}
+	return db.fallback[0].ListRestartPolicies(ctx, jids...)
+}
+
+func (db *PropagatorDB) DeleteRestartPolicy(ctx context.Context, jid string) error {
+	if len(db.fallback) == 0 {
+		return fmt.Errorf("no fallback DB for restart policies")
+	}
+	for _, fallback := range db.fallback {
+		if err := fallback.DeleteRestartPolicy(ctx, jid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -21,6 +21,13 @@ import (
 //go:embed sql/schema.sql
 var Ddl string
 
+// Migrations for tables created by older versions of the schema. Since tables
+// are only created if they don't exist, new columns must be added here as well.
+// Each migration must be safe to re-run, i.e. fail only with an ignorable error.
+var migrations = []string{
+	"ALTER TABLE checkpoints ADD COLUMN ParentID TEXT NOT NULL DEFAULT ''",
+}
+
 type SqliteDB struct {
 	queries *sql.Queries
 	UnimplementedDB
@@ -41,6 +48,12 @@ func NewSqliteDB(ctx context.Context, path string) (*SqliteDB, error) {
 		return nil, err
 	}
 
+	for _, migration := range migrations {
+		if _, err := db.ExecContext(ctx, migration); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return nil, fmt.Errorf("failed to migrate db: %w", err)
+		}
+	}
+
 	return &SqliteDB{
 		queries: sql.New(db),
 	}, nil
@@ -250,20 +263,22 @@ func (db *SqliteDB) DeleteHost(ctx context.Context, id string) error {
 func (db *SqliteDB) PutCheckpoint(ctx context.Context, checkpoint *daemon.Checkpoint) error {
 	if list, _ := db.queries.ListCheckpointsByIDs(ctx, []string{checkpoint.ID}); len(list) > 0 {
 		return db.queries.UpdateCheckpoint(ctx, sql.UpdateCheckpointParams{
-			ID:   checkpoint.ID,
-			Jid:  checkpoint.JID,
-			Path: checkpoint.Path,
-			Time: time.Unix(0, checkpoint.Time*int64(time.Millisecond)),
-			Size: checkpoint.Size,
+			ID:       checkpoint.ID,
+			Jid:      checkpoint.JID,
+			Path:     checkpoint.Path,
+			Time:     time.Unix(0, checkpoint.Time*int64(time.Millisecond)),
+			Size:     checkpoint.Size,
+			Parentid: checkpoint.ParentID,
 		})
 	}
 
 	return db.queries.CreateCheckpoint(ctx, sql.CreateCheckpointParams{
-		ID:   checkpoint.ID,
-		Jid:  checkpoint.JID,
-		Path: checkpoint.Path,
-		Time: time.Unix(0, checkpoint.Time*int64(time.Millisecond)),
-		Size: checkpoint.Size,
+		ID:       checkpoint.ID,
+		Jid:      checkpoint.JID,
+		Path:     checkpoint.Path,
+		Time:     time.Unix(0, checkpoint.Time*int64(time.Millisecond)),
+		Size:     checkpoint.Size,
+		Parentid: checkpoint.ParentID,
 	})
 }
 
@@ -315,17 +330,141 @@ func (db *SqliteDB) DeleteCheckpoint(ctx context.Context, id string) error {
 	return db.queries.DeleteCheckpoint(ctx, id)
 }
 
+////////////////
+/// Schedule ///
+////////////////
+
+func (db *SqliteDB) PutSchedule(ctx context.Context, schedule *daemon.Schedule) error {
+	if list, _ := db.queries.ListSchedulesByJIDs(ctx, []string{schedule.JID}); len(list) > 0 {
+		return db.queries.UpdateSchedule(ctx, sql.UpdateScheduleParams{
+			Jid:         schedule.JID,
+			Interval:    schedule.Interval,
+			Keep:        int64(schedule.Keep),
+			Dir:         schedule.Dir,
+			Compression: schedule.Compression,
+		})
+	}
+
+	return db.queries.CreateSchedule(ctx, sql.CreateScheduleParams{
+		Jid:         schedule.JID,
+		Interval:    schedule.Interval,
+		Keep:        int64(schedule.Keep),
+		Dir:         schedule.Dir,
+		Compression: schedule.Compression,
+	})
+}
+
+func (db *SqliteDB) ListSchedules(ctx context.Context, jids ...string) ([]*daemon.Schedule, error) {
+	var dbSchedules []sql.Schedule
+	var err error
+
+	if len(jids) == 0 {
+		dbSchedules, err = db.queries.ListSchedules(ctx)
+	} else {
+		dbSchedules, err = db.queries.ListSchedulesByJIDs(ctx, jids)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := []*daemon.Schedule{}
+	for _, dbSchedule := range dbSchedules {
+		schedules = append(schedules, fromDBSchedule(&dbSchedule))
+	}
+
+	return schedules, nil
+}
+
+func (db *SqliteDB) DeleteSchedule(ctx context.Context, jid string) error {
+	return db.queries.DeleteSchedule(ctx, jid)
+}
+
+//////////////////////
+/// Restart Policy ///
+//////////////////////
+
+func (db *SqliteDB) PutRestartPolicy(ctx context.Context, policy *daemon.RestartPolicy) error {
+	if list, _ := db.queries.ListRestartPoliciesByJIDs(ctx, []string{policy.JID}); len(list) > 0 {
+		return db.queries.UpdateRestartPolicy(ctx, sql.UpdateRestartPolicyParams{
+			Jid:         policy.JID,
+			Policy:      policy.Policy,
+			Maxrestarts: int64(policy.MaxRestarts),
+			Count:       int64(policy.Count),
+			Reason:      policy.Reason,
+			Time:        policy.Time,
+		})
+	}
+
+	return db.queries.CreateRestartPolicy(ctx, sql.CreateRestartPolicyParams{
+		Jid:         policy.JID,
+		Policy:      policy.Policy,
+		Maxrestarts: int64(policy.MaxRestarts),
+		Count:       int64(policy.Count),
+		Reason:      policy.Reason,
+		Time:        policy.Time,
+	})
+}
+
+func (db *SqliteDB) ListRestartPolicies(ctx context.Context, jids ...string) ([]*daemon.RestartPolicy, error) {
+	var dbPolicies []sql.RestartPolicy
+	var err error
+
+	if len(jids) == 0 {
+		dbPolicies, err = db.queries.ListRestartPolicies(ctx)
+	} else {
+		dbPolicies, err = db.queries.ListRestartPoliciesByJIDs(ctx, jids)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	policies := []*daemon.RestartPolicy{}
+	for _, dbPolicy := range dbPolicies {
+		policies = append(policies, fromDBRestartPolicy(&dbPolicy))
+	}
+
+	return policies, nil
+}
+
+func (db *SqliteDB) DeleteRestartPolicy(ctx context.Context, jid string) error {
+	return db.queries.DeleteRestartPolicy(ctx, jid)
+}
+
 ///////////////
 /// Helpers ///
 ///////////////
 
+func fromDBRestartPolicy(dbPolicy *sql.RestartPolicy) *daemon.RestartPolicy {
+	return &daemon.RestartPolicy{
+		JID:         dbPolicy.Jid,
+		Policy:      dbPolicy.Policy,
+		MaxRestarts: int32(dbPolicy.Maxrestarts),
+		Count:       int32(dbPolicy.Count),
+		Reason:      dbPolicy.Reason,
+		Time:        dbPolicy.Time,
+	}
+}
+
+func fromDBSchedule(dbSchedule *sql.Schedule) *daemon.Schedule {
+	return &daemon.Schedule{
+		JID:         dbSchedule.Jid,
+		Interval:    dbSchedule.Interval,
+		Keep:        int32(dbSchedule.Keep),
+		Dir:         dbSchedule.Dir,
+		Compression: dbSchedule.Compression,
+	}
+}
+
 func fromDBCheckpoint(dbCheckpoint *sql.Checkpoint) *daemon.Checkpoint {
 	return &daemon.Checkpoint{
-		ID:   dbCheckpoint.ID,
-		JID:  dbCheckpoint.Jid,
-		Path: dbCheckpoint.Path,
-		Time: dbCheckpoint.Time.UnixMilli(),
-		Size: dbCheckpoint.Size,
+		ID:       dbCheckpoint.ID,
+		JID:      dbCheckpoint.Jid,
+		Path:     dbCheckpoint.Path,
+		Time:     dbCheckpoint.Time.UnixMilli(),
+		Size:     dbCheckpoint.Size,
+		ParentID: dbCheckpoint.Parentid,
 	}
 }
 
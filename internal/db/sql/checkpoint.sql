@@ -1,12 +1,13 @@
 -- name: CreateCheckpoint :exec
-INSERT INTO checkpoints (ID, JID, Path, Time, Size) VALUES (?, ?, ?, ?, ?);
+INSERT INTO checkpoints (ID, JID, Path, Time, Size, ParentID) VALUES (?, ?, ?, ?, ?, ?);
 
 -- name: UpdateCheckpoint :exec
 UPDATE checkpoints SET
     JID = ?,
     Path = ?,
     Time = ?,
-    Size = ?
+    Size = ?,
+    ParentID = ?
 WHERE ID = ?;
 
 -- name: ListCheckpoints :many
@@ -12,15 +12,16 @@ import (
 )
 
 const createCheckpoint = `-- name: CreateCheckpoint :exec
-INSERT INTO checkpoints (ID, JID, Path, Time, Size) VALUES (?, ?, ?, ?, ?)
+INSERT INTO checkpoints (ID, JID, Path, Time, Size, ParentID) VALUES (?, ?, ?, ?, ?, ?)
 `
 
 type CreateCheckpointParams struct {
-	ID   string
-	Jid  string
-	Path string
-	Time time.Time
-	Size int64
+	ID       string
+	Jid      string
+	Path     string
+	Time     time.Time
+	Size     int64
+	Parentid string
 }
 
 func (q *Queries) CreateCheckpoint(ctx context.Context, arg CreateCheckpointParams) error {
@@ -30,6 +31,7 @@ func (q *Queries) CreateCheckpoint(ctx context.Context, arg CreateCheckpointPara
 		arg.Path,
 		arg.Time,
 		arg.Size,
+		arg.Parentid,
 	)
 	return err
 }
@@ -44,7 +46,7 @@ func (q *Queries) DeleteCheckpoint(ctx context.Context, id string) error {
 }
 
 const listCheckpoints = `-- name: ListCheckpoints :many
-SELECT id, jid, path, time, size FROM checkpoints ORDER BY Time DESC
+SELECT id, jid, path, time, size, parentid FROM checkpoints ORDER BY Time DESC
 `
 
 func (q *Queries) ListCheckpoints(ctx context.Context) ([]Checkpoint, error) {
@@ -62,6 +64,7 @@ func (q *Queries) ListCheckpoints(ctx context.Context) ([]Checkpoint, error) {
 			&i.Path,
 			&i.Time,
 			&i.Size,
+			&i.Parentid,
 		); err != nil {
 			return nil, err
 		}
@@ -77,7 +80,7 @@ func (q *Queries) ListCheckpoints(ctx context.Context) ([]Checkpoint, error) {
 }
 
 const listCheckpointsByIDs = `-- name: ListCheckpointsByIDs :many
-SELECT id, jid, path, time, size FROM checkpoints WHERE ID in (/*SLICE:ids*/?)
+SELECT id, jid, path, time, size, parentid FROM checkpoints WHERE ID in (/*SLICE:ids*/?)
 ORDER BY Time DESC
 `
 
@@ -106,6 +109,7 @@ func (q *Queries) ListCheckpointsByIDs(ctx context.Context, ids []string) ([]Che
 			&i.Path,
 			&i.Time,
 			&i.Size,
+			&i.Parentid,
 		); err != nil {
 			return nil, err
 		}
@@ -121,7 +125,7 @@ func (q *Queries) ListCheckpointsByIDs(ctx context.Context, ids []string) ([]Che
 }
 
 const listCheckpointsByJIDs = `-- name: ListCheckpointsByJIDs :many
-SELECT id, jid, path, time, size FROM checkpoints WHERE JID in (/*SLICE:jids*/?)
+SELECT id, jid, path, time, size, parentid FROM checkpoints WHERE JID in (/*SLICE:jids*/?)
 ORDER BY Time DESC
 `
 
@@ -150,6 +154,7 @@ func (q *Queries) ListCheckpointsByJIDs(ctx context.Context, jids []string) ([]C
 			&i.Path,
 			&i.Time,
 			&i.Size,
+			&i.Parentid,
 		); err != nil {
 			return nil, err
 		}
@@ -169,16 +174,18 @@ UPDATE checkpoints SET
     JID = ?,
     Path = ?,
     Time = ?,
-    Size = ?
+    Size = ?,
+    ParentID = ?
 WHERE ID = ?
 `
 
 type UpdateCheckpointParams struct {
-	Jid  string
-	Path string
-	Time time.Time
-	Size int64
-	ID   string
+	Jid      string
+	Path     string
+	Time     time.Time
+	Size     int64
+	Parentid string
+	ID       string
 }
 
 func (q *Queries) UpdateCheckpoint(ctx context.Context, arg UpdateCheckpointParams) error {
@@ -187,6 +194,7 @@ func (q *Queries) UpdateCheckpoint(ctx context.Context, arg UpdateCheckpointPara
 		arg.Path,
 		arg.Time,
 		arg.Size,
+		arg.Parentid,
 		arg.ID,
 	)
 	return err
@@ -9,11 +9,12 @@ import (
 )
 
 type Checkpoint struct {
-	ID   string
-	Jid  string
-	Path string
-	Time time.Time
-	Size int64
+	ID       string
+	Jid      string
+	Path     string
+	Time     time.Time
+	Size     int64
+	Parentid string
 }
 
 type Host struct {
@@ -48,3 +49,20 @@ type Job struct {
 	Gids       string
 	Groups     string
 }
+
+type RestartPolicy struct {
+	Jid         string
+	Policy      string
+	Maxrestarts int64
+	Count       int64
+	Reason      string
+	Time        int64
+}
+
+type Schedule struct {
+	Jid         string
+	Interval    int64
+	Keep        int64
+	Dir         string
+	Compression string
+}
@@ -0,0 +1,20 @@
+-- name: CreateRestartPolicy :exec
+INSERT INTO restart_policies (JID, Policy, MaxRestarts, Count, Reason, Time) VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: UpdateRestartPolicy :exec
+UPDATE restart_policies SET
+    Policy = ?,
+    MaxRestarts = ?,
+    Count = ?,
+    Reason = ?,
+    Time = ?
+WHERE JID = ?;
+
+-- name: ListRestartPolicies :many
+SELECT * FROM restart_policies;
+
+-- name: ListRestartPoliciesByJIDs :many
+SELECT * FROM restart_policies WHERE JID in (sqlc.slice('jids'));
+
+-- name: DeleteRestartPolicy :exec
+DELETE FROM restart_policies WHERE JID = ?;
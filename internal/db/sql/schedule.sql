@@ -0,0 +1,19 @@
+-- name: CreateSchedule :exec
+INSERT INTO schedules (JID, Interval, Keep, Dir, Compression) VALUES (?, ?, ?, ?, ?);
+
+-- name: UpdateSchedule :exec
+UPDATE schedules SET
+    Interval = ?,
+    Keep = ?,
+    Dir = ?,
+    Compression = ?
+WHERE JID = ?;
+
+-- name: ListSchedules :many
+SELECT * FROM schedules;
+
+-- name: ListSchedulesByJIDs :many
+SELECT * FROM schedules WHERE JID in (sqlc.slice('jids'));
+
+-- name: DeleteSchedule :exec
+DELETE FROM schedules WHERE JID = ?;
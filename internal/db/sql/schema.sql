@@ -44,5 +44,23 @@ CREATE TABLE IF NOT EXISTS checkpoints (
     Path        TEXT NOT NULL,
     Time        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     Size        INTEGER NOT NULL,
+    ParentID    TEXT NOT NULL DEFAULT '', -- Checkpoint this one was dumped incrementally on top of
     FOREIGN KEY(JID) REFERENCES jobs(JID) ON DELETE CASCADE
 );
+
+CREATE TABLE IF NOT EXISTS schedules (
+    JID          TEXT PRIMARY KEY, -- Not tied to jobs, as with a remote DB, jobs are not stored locally
+    Interval     INTEGER NOT NULL CHECK(Interval > 0), -- In milliseconds
+    Keep         INTEGER NOT NULL, -- Number of scheduled checkpoints to retain (0 for all)
+    Dir          TEXT NOT NULL CHECK(Dir != ''),
+    Compression  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS restart_policies (
+    JID          TEXT PRIMARY KEY,
+    Policy       TEXT NOT NULL CHECK(Policy IN ('on-failure', 'always')),
+    MaxRestarts  INTEGER NOT NULL, -- Number of restarts before giving up (0 for unlimited)
+    Count        INTEGER NOT NULL, -- Number of restarts so far
+    Reason       TEXT NOT NULL, -- Reason for the last restart
+    Time         INTEGER NOT NULL -- Time of the last restart, in milliseconds
+);
@@ -9,6 +9,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"buf.build/gen/go/cedana/cedana/grpc/go/daemon/daemongrpc"
@@ -17,6 +18,8 @@ import (
 	cedana_io "github.com/cedana/cedana/pkg/io"
 	"github.com/cedana/cedana/pkg/profiling"
 	"github.com/cedana/cedana/pkg/utils"
+	"github.com/mattn/go-isatty"
+	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health/grpc_health_v1"
@@ -33,6 +36,12 @@ const (
 	DEFAULT_MANAGE_TIMEOUT   = 1 * time.Minute
 	DEFAULT_DB_TIMEOUT       = 20 * time.Second
 	DEFAULT_HEALTH_TIMEOUT   = 1 * time.Minute
+	DEFAULT_MIGRATE_TIMEOUT  = 30 * time.Minute
+	DEFAULT_GC_TIMEOUT       = 5 * time.Minute
+	DEFAULT_EXPORT_TIMEOUT   = 30 * time.Minute
+	DEFAULT_INSPECT_TIMEOUT  = 5 * time.Minute
+
+	MIGRATION_CHUNK_SIZE = 1 << 20 // 1MiB, well within the default max message size
 )
 
 type Client struct {
@@ -120,6 +129,27 @@ func (c *Client) DumpVM(ctx context.Context, args *daemon.DumpVMReq, opts ...grp
 	return resp, data, nil
 }
 
+func (c *Client) RestoreVM(ctx context.Context, args *daemon.RestoreVMReq, opts ...grpc.CallOption) (*daemon.RestoreVMResp, *profiling.Data, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_RESTORE_TIMEOUT)
+	defer cancel()
+	opts = addDefaultOptions(opts)
+
+	var trailer metadata.MD
+	opts = append(opts, grpc.Trailer(&trailer))
+
+	resp, err := c.daemonClient.RestoreVM(ctx, args, opts...)
+	if err != nil {
+		return resp, nil, utils.GRPCErrorColored(err)
+	}
+
+	data, err := profiling.FromTrailer(trailer)
+	if err != nil {
+		return resp, nil, err
+	}
+
+	return resp, data, nil
+}
+
 func (c *Client) Dump(ctx context.Context, args *daemon.DumpReq, opts ...grpc.CallOption) (*daemon.DumpResp, *profiling.Data, error) {
 	ctx, cancel := context.WithTimeout(ctx, DEFAULT_DUMP_TIMEOUT)
 	defer cancel()
@@ -253,6 +283,61 @@ func (c *Client) Manage(ctx context.Context, args *daemon.RunReq, opts ...grpc.C
 	return resp, data, nil
 }
 
+func (c *Client) Migrate(ctx context.Context, args *daemon.MigrateReq, opts ...grpc.CallOption) (*daemon.MigrateResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_MIGRATE_TIMEOUT)
+	defer cancel()
+	opts = addDefaultOptions(opts)
+	resp, err := c.daemonClient.Migrate(ctx, args, opts...)
+	return resp, utils.GRPCErrorColored(err)
+}
+
+// ReceiveMigration streams a checkpoint to the daemon, which restores it as the destination
+// of a migration. The name of the checkpoint must carry its compression extension.
+func (c *Client) ReceiveMigration(
+	ctx context.Context,
+	args *daemon.RestoreReq,
+	name string,
+	checkpoint io.Reader,
+	opts ...grpc.CallOption,
+) (*daemon.RestoreResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_MIGRATE_TIMEOUT)
+	defer cancel()
+	opts = addDefaultOptions(opts)
+
+	stream, err := c.daemonClient.ReceiveMigration(ctx, opts...)
+	if err != nil {
+		return nil, utils.GRPCErrorColored(err)
+	}
+
+	// Send the request first, then the checkpoint in chunks
+	if err := stream.Send(&daemon.MigrationChunk{Restore: args, Name: name}); err != nil {
+		return nil, utils.GRPCErrorColored(err)
+	}
+
+	buf := make([]byte, MIGRATION_CHUNK_SIZE)
+	for {
+		n, err := checkpoint.Read(buf)
+		if n > 0 {
+			err := stream.Send(&daemon.MigrationChunk{Data: buf[:n]})
+			if err == io.EOF {
+				break // daemon stopped receiving, its error is returned below
+			}
+			if err != nil {
+				return nil, utils.GRPCErrorColored(err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	return resp, utils.GRPCErrorColored(err)
+}
+
 func (c *Client) Get(ctx context.Context, args *daemon.GetReq, opts ...grpc.CallOption) (*daemon.GetResp, error) {
 	ctx, cancel := context.WithTimeout(ctx, DEFAULT_DB_TIMEOUT)
 	defer cancel()
@@ -286,8 +371,11 @@ func (c *Client) Delete(ctx context.Context, args *daemon.DeleteReq, opts ...grp
 }
 
 // Attach attaches to a managed process/container. Exits the program
-// with the exit code of the process.
+// with the exit code of the process. If attached read-only, stdin is not forwarded.
 func (c *Client) Attach(ctx context.Context, args *daemon.AttachReq, opts ...grpc.CallOption) error {
+	ctx, detach := context.WithCancel(ctx)
+	defer detach()
+
 	opts = addDefaultOptions(opts)
 	stream, err := c.daemonClient.Attach(ctx, opts...)
 	if err != nil {
@@ -298,14 +386,48 @@ func (c *Client) Attach(ctx context.Context, args *daemon.AttachReq, opts ...grp
 		return utils.GRPCErrorColored(err)
 	}
 
-	stdIn, stdOut, stdErr, exitCode, errors := cedana_io.NewStreamIOMaster(stream)
+	resize := make(chan *daemon.WindowSize, 1)
+
+	stdIn, stdOut, stdErr, exitCode, errors, tty := cedana_io.NewStreamIOMaster(stream, resize)
+
+	// If the process has a terminal, the local one is put in raw mode and its size kept in sync.
+	// As Ctrl+C is then passed through, the detach keys are used to detach instead.
+
+	stdin := cedana_io.NewDetachReader(os.Stdin, detach)
 
-	go io.Copy(stdIn, os.Stdin) // since stdin never closes
+	var terminal struct {
+		sync.Mutex
+		restore func()
+	}
+	go func() {
+		if !<-tty || args.GetReadOnly() || !isatty.IsTerminal(os.Stdin.Fd()) {
+			return
+		}
+		restore, err := cedana_io.ForwardTerminal(ctx, os.Stdin, resize)
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to set up local terminal")
+			return
+		}
+		terminal.Lock()
+		terminal.restore = restore
+		terminal.Unlock()
+		stdin.Armed.Store(true)
+	}()
+
+	if !args.GetReadOnly() {
+		go io.Copy(stdIn, stdin) // since stdin never closes
+	}
 	outDone := cedana_io.CopyNotify(os.Stdout, stdOut)
 	errDone := cedana_io.CopyNotify(os.Stderr, stdErr)
 	<-outDone // wait to capture all out
 	<-errDone // wait to capture all err
 
+	terminal.Lock()
+	if terminal.restore != nil {
+		terminal.restore()
+	}
+	terminal.Unlock()
+
 	if err := <-errors; err != nil {
 		return utils.GRPCErrorColored(err)
 	}
@@ -327,11 +449,18 @@ func (c *Client) AttachIO(ctx context.Context, args *daemon.AttachReq, opts ...g
 		return nil, nil, nil, nil, nil, utils.GRPCErrorColored(err)
 	}
 
-	stdIn, stdOut, stdErr, exitCode, errors := cedana_io.NewStreamIOMaster(stream)
+	stdIn, stdOut, stdErr, exitCode, errors, _ := cedana_io.NewStreamIOMaster(stream, nil)
 
 	return stdIn, stdOut, stdErr, exitCode, errors, nil
 }
 
+// WatchEvents streams job events, until the context is done if following.
+func (c *Client) WatchEvents(ctx context.Context, args *daemon.WatchEventsReq, opts ...grpc.CallOption) (daemongrpc.Daemon_WatchEventsClient, error) {
+	opts = addDefaultOptions(opts)
+	stream, err := c.daemonClient.WatchEvents(ctx, args, opts...)
+	return stream, utils.GRPCErrorColored(err)
+}
+
 func (c *Client) GetCheckpoint(ctx context.Context, args *daemon.GetCheckpointReq, opts ...grpc.CallOption) (*daemon.GetCheckpointResp, error) {
 	ctx, cancel := context.WithTimeout(ctx, DEFAULT_DB_TIMEOUT)
 	defer cancel()
@@ -356,6 +485,62 @@ func (c *Client) DeleteCheckpoint(ctx context.Context, args *daemon.DeleteCheckp
 	return resp, utils.GRPCErrorColored(err)
 }
 
+func (c *Client) GC(ctx context.Context, args *daemon.GCReq, opts ...grpc.CallOption) (*daemon.GCResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_GC_TIMEOUT)
+	defer cancel()
+	opts = addDefaultOptions(opts)
+	resp, err := c.daemonClient.GC(ctx, args, opts...)
+	return resp, utils.GRPCErrorColored(err)
+}
+
+func (c *Client) InspectCheckpoint(ctx context.Context, args *daemon.InspectCheckpointReq, opts ...grpc.CallOption) (*daemon.InspectCheckpointResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_INSPECT_TIMEOUT)
+	defer cancel()
+	opts = addDefaultOptions(opts)
+	resp, err := c.daemonClient.InspectCheckpoint(ctx, args, opts...)
+	return resp, utils.GRPCErrorColored(err)
+}
+
+func (c *Client) ExportCheckpoint(ctx context.Context, args *daemon.ExportCheckpointReq, opts ...grpc.CallOption) (*daemon.ExportCheckpointResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_EXPORT_TIMEOUT)
+	defer cancel()
+	opts = addDefaultOptions(opts)
+	resp, err := c.daemonClient.ExportCheckpoint(ctx, args, opts...)
+	return resp, utils.GRPCErrorColored(err)
+}
+
+func (c *Client) ImportCheckpoint(ctx context.Context, args *daemon.ImportCheckpointReq, opts ...grpc.CallOption) (*daemon.ImportCheckpointResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_EXPORT_TIMEOUT)
+	defer cancel()
+	opts = addDefaultOptions(opts)
+	resp, err := c.daemonClient.ImportCheckpoint(ctx, args, opts...)
+	return resp, utils.GRPCErrorColored(err)
+}
+
+func (c *Client) Schedule(ctx context.Context, args *daemon.ScheduleReq, opts ...grpc.CallOption) (*daemon.ScheduleResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_DB_TIMEOUT)
+	defer cancel()
+	opts = addDefaultOptions(opts)
+	resp, err := c.daemonClient.Schedule(ctx, args, opts...)
+	return resp, utils.GRPCErrorColored(err)
+}
+
+func (c *Client) Unschedule(ctx context.Context, args *daemon.UnscheduleReq, opts ...grpc.CallOption) (*daemon.UnscheduleResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_DB_TIMEOUT)
+	defer cancel()
+	opts = addDefaultOptions(opts)
+	resp, err := c.daemonClient.Unschedule(ctx, args, opts...)
+	return resp, utils.GRPCErrorColored(err)
+}
+
+func (c *Client) ListSchedules(ctx context.Context, args *daemon.ListSchedulesReq, opts ...grpc.CallOption) (*daemon.ListSchedulesResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_DB_TIMEOUT)
+	defer cancel()
+	opts = addDefaultOptions(opts)
+	resp, err := c.daemonClient.ListSchedules(ctx, args, opts...)
+	return resp, utils.GRPCErrorColored(err)
+}
+
 func (c *Client) Query(ctx context.Context, args *daemon.QueryReq, opts ...grpc.CallOption) (*daemon.QueryResp, error) {
 	ctx, cancel := context.WithTimeout(ctx, DEFAULT_DB_TIMEOUT)
 	defer cancel()
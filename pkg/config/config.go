@@ -39,6 +39,11 @@ const (
 	DEFAULT_CHECKPOINT_STREAMS                = 0
 	DEFAULT_CHECKPOINT_ASYNC                  = false
 	DEFAULT_CHECKPOINT_STREAM_MEMORY_LIMIT_MB = 4000
+	DEFAULT_CHECKPOINT_ZSTD_LEVEL             = 3
+	DEFAULT_CHECKPOINT_PRESSURE_INTERVAL      = "1s"
+	DEFAULT_CHECKPOINT_PREEMPTION_INTERVAL    = "5s"
+	DEFAULT_CHECKPOINT_RESTART_BACKOFF        = "1s"
+	DEFAULT_CHECKPOINT_RESTART_MAX_BACKOFF    = "5m"
 
 	DEFAULT_DB_REMOTE = false
 	DEFAULT_DB_PATH   = "/tmp/cedana.db"
@@ -89,6 +94,19 @@ var Global Config = Config{
 		Streams:           DEFAULT_CHECKPOINT_STREAMS,
 		Async:             DEFAULT_CHECKPOINT_ASYNC,
 		StreamMemoryLimit: DEFAULT_CHECKPOINT_STREAM_MEMORY_LIMIT_MB,
+		Zstd: Zstd{
+			Level: DEFAULT_CHECKPOINT_ZSTD_LEVEL,
+		},
+		Pressure: Pressure{
+			Interval: DEFAULT_CHECKPOINT_PRESSURE_INTERVAL,
+		},
+		Preemption: Preemption{
+			Interval: DEFAULT_CHECKPOINT_PREEMPTION_INTERVAL,
+		},
+		Restart: Restart{
+			Backoff:    DEFAULT_CHECKPOINT_RESTART_BACKOFF,
+			MaxBackoff: DEFAULT_CHECKPOINT_RESTART_MAX_BACKOFF,
+		},
 	},
 	DB: DB{
 		Remote: DEFAULT_DB_REMOTE,
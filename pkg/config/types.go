@@ -74,6 +74,8 @@ type (
 		Dir string `json:"dir" key:"dir" yaml:"dir" mapstructure:"dir"`
 		// Compression is the default compression algorithm to use for checkpoints
 		Compression string `json:"compression" key:"compression" yaml:"compression" mapstructure:"compression"`
+		// Zstd sets the zstd encoder options, used when compression is "zstd"
+		Zstd Zstd `json:"zstd" key:"zstd" yaml:"zstd" mapstructure:"zstd"`
 		// Streams specifies the number of parallel streams to use when checkpointing.
 		// Default is 0 for no streaming, a minimum of 2 is required otherwise.
 		Streams int32 `json:"streams" key:"streams" yaml:"streams" mapstructure:"streams"`
@@ -82,6 +84,80 @@ type (
 		// Async defers checkpoint compression and upload (in case of remote dir) to the background, and causes
 		// checkpoint request to return early.
 		Async bool `json:"async" key:"async" yaml:"async" mapstructure:"async"`
+		// EncryptionKey is the source of the key to encrypt checkpoints at rest with (AES-256-GCM).
+		// Checkpoints written to storage (tarballs, streamed shards) are encrypted, and transparently
+		// decrypted on restore. Uncompressed local dumps are directories, and are not encrypted.
+		// - "file://<path>" for a file containing the key
+		// - "env://<name>" for an environment variable containing the key
+		// - "<plugin>://<id>" for a key from a key provider plugin (e.g. a KMS)
+		// The key must be 32 bytes, either raw, or hex or base64 encoded. Empty disables encryption.
+		EncryptionKey string `json:"encryption_key" key:"encryption_key" yaml:"encryption_key" mapstructure:"encryption_key"`
+		// Dedup stores checkpoints as content-addressed chunks under "<dir>/.chunks", so repeated checkpoints
+		// only store the chunks that changed. Chunks are compressed individually, so this works best with
+		// "tar" compression. Uncompressed local dumps are directories, and are not deduplicated.
+		Dedup bool `json:"dedup" key:"dedup" yaml:"dedup" mapstructure:"dedup"`
+		// GC sets the retention policies for checkpoints, and how often to collect them
+		GC GC `json:"gc" key:"gc" yaml:"gc" mapstructure:"gc"`
+		// Pressure sets when managed jobs are dumped under memory pressure, before they are OOM killed or evicted.
+		// Only jobs with a cgroup of their own (e.g. containers, or jobs run with resource limits) are watched
+		Pressure Pressure `json:"pressure" key:"pressure" yaml:"pressure" mapstructure:"pressure"`
+		// Preemption sets where preemption notices are picked up from, to checkpoint jobs that opted in with `--checkpoint-on-signal`
+		Preemption Preemption `json:"preemption" key:"preemption" yaml:"preemption" mapstructure:"preemption"`
+		// Restart sets the backoff between restarts of jobs run with a `--restart` policy
+		Restart Restart `json:"restart" key:"restart" yaml:"restart" mapstructure:"restart"`
+	}
+
+	Zstd struct {
+		// Level is the zstd compression level (1-22), mapped to the closest level supported by the encoder
+		Level int `json:"level" key:"level" yaml:"level" mapstructure:"level"`
+		// Concurrency is the number of threads used for encoding (0 for all CPUs)
+		Concurrency int `json:"concurrency" key:"concurrency" yaml:"concurrency" mapstructure:"concurrency"`
+	}
+
+	GC struct {
+		// Interval is how often the daemon collects checkpoints in the background, e.g. "1h" (empty to disable)
+		Interval string `json:"interval" key:"interval" yaml:"interval" mapstructure:"interval"`
+		// MaxCount is the max number of checkpoints to keep per job (0 for no limit)
+		MaxCount int `json:"max_count" key:"max_count" yaml:"max_count" mapstructure:"max_count"`
+		// MaxAge is the max age of a checkpoint, e.g. "72h" (empty for no limit)
+		MaxAge string `json:"max_age" key:"max_age" yaml:"max_age" mapstructure:"max_age"`
+		// MaxBytes is the max total size in bytes of checkpoints under each storage prefix, e.g. a dir (0 for no limit)
+		MaxBytes int64 `json:"max_bytes" key:"max_bytes" yaml:"max_bytes" mapstructure:"max_bytes"`
+	}
+
+	Pressure struct {
+		// MemoryPercent is the memory working set (usage without inactive page cache), as a percentage of the limit
+		// (memory.max or memory.high) of the job's cgroup, at which a job is dumped, e.g. 90 (0 to disable)
+		MemoryPercent float64 `json:"memory_percent" key:"memory_percent" yaml:"memory_percent" mapstructure:"memory_percent"`
+		// PSI is the memory stall of the job's cgroup ('some' avg10 from memory.pressure), as a percentage,
+		// at which a job is dumped, e.g. 40 (0 to disable)
+		PSI float64 `json:"psi" key:"psi" yaml:"psi" mapstructure:"psi"`
+		// Events sets whether to dump a job when its cgroup hits its hard memory limit
+		// (new 'max' events in memory.events), i.e. when reclaim is on the verge of failing
+		Events bool `json:"events" key:"events" yaml:"events" mapstructure:"events"`
+		// Interval is how often memory of managed jobs is sampled, e.g. "1s"
+		Interval string `json:"interval" key:"interval" yaml:"interval" mapstructure:"interval"`
+		// Dir is the directory to dump jobs into under pressure (defaults to the checkpoint dir)
+		Dir string `json:"dir" key:"dir" yaml:"dir" mapstructure:"dir"`
+		// LeaveRunning leaves a job running after it is dumped, instead of stopping it to be restored elsewhere
+		LeaveRunning bool `json:"leave_running" key:"leave_running" yaml:"leave_running" mapstructure:"leave_running"`
+	}
+
+	Preemption struct {
+		// File is a hook file whose creation is a preemption notice, e.g. by a SLURM or spot termination handler (empty to disable)
+		File string `json:"file" key:"file" yaml:"file" mapstructure:"file"`
+		// URL is an endpoint for which a 200 response (other than 'false') is a preemption notice, e.g.
+		// http://169.254.169.254/latest/meta-data/spot/instance-action on AWS, with an IMDSv2 token if available (empty to disable)
+		URL string `json:"url" key:"url" yaml:"url" mapstructure:"url"`
+		// Interval is how often the file and URL are checked for a notice, e.g. "5s"
+		Interval string `json:"interval" key:"interval" yaml:"interval" mapstructure:"interval"`
+	}
+
+	Restart struct {
+		// Backoff is the delay before the first restart of a job, doubled on every consecutive restart, e.g. "1s"
+		Backoff string `json:"backoff" key:"backoff" yaml:"backoff" mapstructure:"backoff"`
+		// MaxBackoff is the max delay between restarts of a job, after which the delay resets if it stays up, e.g. "5m"
+		MaxBackoff string `json:"max_backoff" key:"max_backoff" yaml:"max_backoff" mapstructure:"max_backoff"`
 	}
 
 	DB struct {
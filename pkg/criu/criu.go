@@ -380,6 +380,38 @@ func (c *Criu) StartPageServerChld(ctx context.Context, opts *criu.CriuOpts) (in
 	return int(resp.GetPs().GetPid()), int(resp.GetPs().GetPort()), nil
 }
 
+// StartLazyPages starts the lazy-pages daemon, which serves memory pages to a process
+// being restored with lazy pages, on demand. Pages are read from the images directory,
+// or fetched from a page server if one is specified in opts. Since there is no RPC
+// for this, the daemon is started as a separate process which the caller must wait on.
+func (c *Criu) StartLazyPages(ctx context.Context, opts *criu.CriuOpts, stdout, stderr io.Writer) (*exec.Cmd, error) {
+	if opts.GetImagesDir() == "" {
+		return nil, errors.New("images directory is required for lazy-pages")
+	}
+
+	args := []string{"lazy-pages", "--images-dir", opts.GetImagesDir()}
+	if opts.LogFile != nil {
+		args = append(args, "--log-file", opts.GetLogFile())
+	}
+	if opts.LogLevel != nil {
+		args = append(args, "-v"+strconv.Itoa(int(opts.GetLogLevel())))
+	}
+	if ps := opts.GetPs(); ps != nil {
+		args = append(args, "--page-server", "--address", ps.GetAddress(), "--port", strconv.Itoa(int(ps.GetPort())))
+	}
+
+	cmd := exec.CommandContext(ctx, c.swrkPath, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
 // GetCriuVersion executes the VERSION RPC call and returns the version
 // as an integer. Major * 10000 + Minor * 100 + SubLevel
 func (c *Criu) GetCriuVersion(ctx context.Context) (int, error) {
@@ -17,16 +17,17 @@ import (
 
 var (
 	// Commands
-	CmdTheme    = plugins.Feature[text.Colors]{Symbol: "CmdTheme", Description: "Theme for commands"}
-	DumpCmd     = plugins.Feature[*cobra.Command]{Symbol: "DumpCmd", Description: "Dump command"}
-	DumpVMCmd   = plugins.Feature[*cobra.Command]{Symbol: "DumpVMCmd", Description: "Dump VM command"}
-	RestoreCmd  = plugins.Feature[*cobra.Command]{Symbol: "RestoreCmd", Description: "Restore command"}
-	FreezeCmd   = plugins.Feature[*cobra.Command]{Symbol: "FreezeCmd", Description: "Freeze command"}
-	UnfreezeCmd = plugins.Feature[*cobra.Command]{Symbol: "UnfreezeCmd", Description: "Unfreeze command"}
-	RunCmd      = plugins.Feature[*cobra.Command]{Symbol: "RunCmd", Description: "Run command"}
-	ManageCmd   = plugins.Feature[*cobra.Command]{Symbol: "ManageCmd", Description: "Manage command"}
-	QueryCmd    = plugins.Feature[*cobra.Command]{Symbol: "QueryCmd", Description: "Query command"}
-	HelperCmds  = plugins.Feature[[]*cobra.Command]{Symbol: "HelperCmds", Description: "Helper command(s)"}
+	CmdTheme     = plugins.Feature[text.Colors]{Symbol: "CmdTheme", Description: "Theme for commands"}
+	DumpCmd      = plugins.Feature[*cobra.Command]{Symbol: "DumpCmd", Description: "Dump command"}
+	DumpVMCmd    = plugins.Feature[*cobra.Command]{Symbol: "DumpVMCmd", Description: "Dump VM command"}
+	RestoreCmd   = plugins.Feature[*cobra.Command]{Symbol: "RestoreCmd", Description: "Restore command"}
+	RestoreVMCmd = plugins.Feature[*cobra.Command]{Symbol: "RestoreVMCmd", Description: "Restore VM command"}
+	FreezeCmd    = plugins.Feature[*cobra.Command]{Symbol: "FreezeCmd", Description: "Freeze command"}
+	UnfreezeCmd  = plugins.Feature[*cobra.Command]{Symbol: "UnfreezeCmd", Description: "Unfreeze command"}
+	RunCmd       = plugins.Feature[*cobra.Command]{Symbol: "RunCmd", Description: "Run command"}
+	ManageCmd    = plugins.Feature[*cobra.Command]{Symbol: "ManageCmd", Description: "Manage command"}
+	QueryCmd     = plugins.Feature[*cobra.Command]{Symbol: "QueryCmd", Description: "Query command"}
+	HelperCmds   = plugins.Feature[[]*cobra.Command]{Symbol: "HelperCmds", Description: "Helper command(s)"}
 
 	// Dump/Restore
 	DumpMiddleware        = plugins.Feature[types.Middleware[types.Dump]]{Symbol: "DumpMiddleware", Description: "Dump middleware"}
@@ -66,5 +67,9 @@ var (
 	HealthChecks = plugins.Feature[types.Checks]{Symbol: "HealthChecks", Description: "Health checks"}
 
 	// Storage
-	Storage = plugins.Feature[func(context.Context) (io.Storage, error)]{Symbol: "NewStorage", Description: "Checkpoint storage"}
+	Storage     = plugins.Feature[func(context.Context) (io.Storage, error)]{Symbol: "NewStorage", Description: "Checkpoint storage"}
+	KeyProvider = plugins.Feature[func(context.Context) (io.KeyProvider, error)]{Symbol: "NewKeyProvider", Description: "Checkpoint encryption key provider"}
+
+	// Export
+	ExportRootfs = plugins.Feature[func(ctx context.Context, imagesDirectory string, dir string) error]{Symbol: "ExportRootfs", Description: "Rootfs diff export"}
 )
@@ -28,6 +28,7 @@ var (
 	GpuIdFlag       = Flag{Full: "gpu-id"}
 	AttachFlag      = Flag{Full: "attach", Short: "a"}
 	AttachableFlag  = Flag{Full: "attachable"}
+	TtyFlag         = Flag{Full: "tty", Short: "t"}
 	AllFlag         = Flag{Full: "all", Short: "a"}
 	OutFlag         = Flag{Full: "out", Short: "o"}
 	ExternalFlag    = Flag{Full: "external"}
@@ -40,6 +41,33 @@ var (
 	UpcomingFlag    = Flag{Full: "upcoming"}
 	TreeFlag        = Flag{Full: "tree", Short: "t"}
 	InspectFlag     = Flag{Full: "inspect", Short: "i"}
+	PreDumpFlag     = Flag{Full: "pre-dump"}
+	ParentFlag      = Flag{Full: "parent"}
+	LazyFlag        = Flag{Full: "lazy"}
+	ToFlag          = Flag{Full: "to"}
+	ToProtocolFlag  = Flag{Full: "to-protocol"}
+	EveryFlag       = Flag{Full: "every"}
+	KeepFlag        = Flag{Full: "keep"}
+	DryRunFlag      = Flag{Full: "dry-run"}
+	MaxCountFlag    = Flag{Full: "max-count"}
+	MaxAgeFlag      = Flag{Full: "max-age"}
+	MaxBytesFlag    = Flag{Full: "max-bytes"}
+	OCIFlag         = Flag{Full: "oci"}
+	UsernameFlag    = Flag{Full: "username"}
+	SecretFlag      = Flag{Full: "secret"}
+	JsonFlag        = Flag{Full: "json"}
+	YamlFlag        = Flag{Full: "yaml"}
+	SignalFlag      = Flag{Full: "signal", Short: "s"}
+	ReadOnlyFlag    = Flag{Full: "read-only"}
+	TailFlag        = Flag{Full: "tail"}
+	FollowFlag      = Flag{Full: "follow", Short: "f"}
+	CpusFlag        = Flag{Full: "cpus"}
+	MemoryFlag      = Flag{Full: "memory"}
+	PidsLimitFlag   = Flag{Full: "pids-limit"}
+	IOWeightFlag    = Flag{Full: "io-weight"}
+
+	CheckpointOnSignalFlag = Flag{Full: "checkpoint-on-signal"}
+	RestartFlag            = Flag{Full: "restart"}
 
 	// CRIU
 	CriuOptsFlag        = Flag{Full: "criu-opts"}
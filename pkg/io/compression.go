@@ -8,7 +8,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 )
 
@@ -20,8 +22,16 @@ var SUPPORTED_COMPRESSIONS = map[string]bool{
 	"gz":   true,
 	"lz4":  true,
 	"zlib": true,
+	"zstd": true,
+	"zst":  true,
 }
 
+// Zstd encoder settings, set once at startup (e.g. from config)
+var (
+	ZstdLevel       = 3 // standard zstd level (1-22), mapped to the closest level supported by the encoder
+	ZstdConcurrency = 0 // number of goroutines used for encoding, 0 for GOMAXPROCS
+)
+
 type AsyncVirtioWriter struct {
 	file  *os.File
 	pipeW *io.PipeWriter
@@ -82,6 +92,14 @@ func NewOptimizedCompressionWriter(baseWriter io.Writer, compression string, isF
 		compressor = lz4.NewWriter(bufferedPipeWriter) // Points to buffer
 	case "gzip", "gz":
 		compressor = gzip.NewWriter(bufferedPipeWriter) // Points to buffer
+	case "zstd", "zst":
+		var err error
+		compressor, err = newZstdWriter(bufferedPipeWriter) // Points to buffer
+		if err != nil {
+			pw.CloseWithError(err)
+			<-done
+			return nil, err
+		}
 	default:
 		compressor = NopWriteCloser{bufferedPipeWriter} // Points to buffer
 	}
@@ -128,6 +146,8 @@ func NewCompressionWriter(writer io.Writer, compression string) (io.WriteCloser,
 		return gzip.NewWriter(writer), nil
 	case "zlib":
 		return zlib.NewWriter(writer), nil
+	case "zstd", "zst":
+		return newZstdWriter(writer)
 	case "tar", "none", "":
 		return NopWriteCloser{writer}, nil
 	default:
@@ -143,6 +163,12 @@ func NewCompressionReader(reader io.Reader, compression string) (io.ReadCloser,
 		return gzip.NewReader(reader)
 	case "zlib":
 		return zlib.NewReader(reader)
+	case "zstd", "zst":
+		decoder, err := zstd.NewReader(reader, zstd.WithDecoderConcurrency(0))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
 	case "tar", "none", "":
 		return NopReadCloser{reader}, nil
 	default:
@@ -159,6 +185,8 @@ func CompressionFromExt(path string) (string, error) {
 		return "gzip", nil
 	case ".zlib":
 		return "zlib", nil
+	case ".zst", ".zstd":
+		return "zstd", nil
 	case ".tar":
 		return "tar", nil
 	case "":
@@ -176,6 +204,8 @@ func ExtForCompression(compression string) (string, error) {
 		return ".gz", nil
 	case "zlib":
 		return ".zlib", nil
+	case "zstd", "zst":
+		return ".zst", nil
 	case "tar", "none", "":
 		return "", nil
 	default:
@@ -187,6 +217,18 @@ func ExtForCompression(compression string) (string, error) {
 /// Helpers ///
 ///////////////
 
+func newZstdWriter(writer io.Writer) (io.WriteCloser, error) {
+	concurrency := ZstdConcurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	return zstd.NewWriter(
+		writer,
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(ZstdLevel)),
+		zstd.WithEncoderConcurrency(concurrency),
+	)
+}
+
 type NopWriteCloser struct {
 	io.Writer
 }
@@ -0,0 +1,585 @@
+package io
+
+// Content-addressed deduplication of checkpoints. Written streams are split into content-defined
+// chunks (using a gear rolling hash), and each chunk is stored once under the chunk store of the
+// storage root, keyed by its SHA-256. In place of the stream, a small index listing its chunks is
+// written. So, repeated checkpoints of the same job only store the chunks that changed.
+// If the store is encrypted, chunks are instead keyed by an HMAC-SHA-256 with a key derived from
+// the encryption key, as plain hashes would reveal which checkpoints share content.
+//
+// Layout under the root:
+//   <root>/.chunks/<id>            zstd-compressed chunk
+//   <root>/.chunks/refs/<sha256>   path of an index referencing chunks, keyed by the SHA-256 of the path
+//
+// Index format: MAGIC | JSON
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	DEDUP_MAGIC     = "CEDANAD1"
+	DEDUP_CHUNK_DIR = ".chunks"
+	DEDUP_REF_DIR   = "refs"
+
+	DEDUP_MIN_CHUNK_SIZE = 64 * KIBIBYTE
+	DEDUP_MAX_CHUNK_SIZE = 1 * MEBIBYTE
+	DEDUP_CONCURRENCY    = 8 // max chunks uploaded in parallel
+	DEDUP_KEY_INFO       = "cedana dedup chunk id"
+
+	dedupChunkBits = 18 // average chunk size of 256 KiB (on top of the min)
+	dedupChunkMask = ((1 << dedupChunkBits) - 1) << (64 - dedupChunkBits)
+)
+
+// Chunk stores may be shared by concurrent writers and sweeps. Chunks referenced by writers not
+// yet closed, or by indexes written while a sweep is in progress, are never swept.
+var dedupState = struct {
+	sync.Mutex
+	inflight  map[string]int  // chunks referenced by writers not yet closed
+	committed map[string]bool // chunks referenced by indexes written during sweeps
+	sweeping  int             // sweeps in progress
+	swept     uint64          // sweeps done, as chunks listed before may since be gone
+}{
+	inflight:  make(map[string]int),
+	committed: make(map[string]bool),
+}
+
+// Chunks are compressed individually, by encoders shared across all writers
+var (
+	dedupEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil,
+			zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(ZstdLevel)),
+			zstd.WithEncoderConcurrency(DEDUP_CONCURRENCY),
+		)
+	})
+	dedupDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+)
+
+// Random, but fixed, as it decides the chunk boundaries
+var dedupGear = func() (gear [256]uint64) {
+	seed := uint64(0x6365_6461_6e61_6464) // splitmix64
+	for i := range gear {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+	return gear
+}()
+
+type DedupIndex struct {
+	Root   string       `json:"root"`            // root of the chunk store
+	Size   int64        `json:"size"`            // total size of the stream
+	Keyed  bool         `json:"keyed,omitempty"` // whether chunks are keyed by an HMAC
+	Chunks []DedupChunk `json:"chunks"`
+}
+
+type DedupChunk struct {
+	Hash string `json:"hash"` // SHA-256 of the uncompressed chunk, or its HMAC-SHA-256 if keyed
+	Size int64  `json:"size"`
+}
+
+// DedupStorage wraps a storage to deduplicate all files created, using a chunk store under the root.
+// Deduplicated files are transparently reassembled when opened, and other files are opened as is.
+// If the root is empty, files are created as is.
+type DedupStorage struct {
+	Storage
+	root string
+	key  []byte // for keyed chunk IDs, if the storage is encrypted
+}
+
+func NewDedupStorage(storage Storage, root string) *DedupStorage {
+	s := &DedupStorage{Storage: storage, root: root}
+	if encrypted, ok := storage.(*EncryptedStorage); ok && encrypted.IsEncrypting() {
+		s.key, _ = hkdf.Key(sha256.New, encrypted.key, nil, DEDUP_KEY_INFO, sha256.Size)
+	}
+	return s
+}
+
+func (s *DedupStorage) Create(ctx context.Context, path string) (io.WriteCloser, error) {
+	if s.root == "" {
+		return s.Storage.Create(ctx, path)
+	}
+
+	dedupState.Lock()
+	swept := dedupState.swept
+	dedupState.Unlock()
+
+	// Chunks already in the store are not uploaded again
+	existing := make(map[string]bool)
+	list, err := s.Storage.ReadDir(ctx, chunkDir(s.root))
+	if err == nil {
+		for _, entry := range list {
+			existing[filepath.Base(entry)] = true
+		}
+	}
+
+	return &dedupWriter{
+		ctx:      ctx,
+		storage:  s.Storage,
+		path:     path,
+		key:      s.key,
+		index:    &DedupIndex{Root: s.root, Keyed: s.key != nil},
+		existing: existing,
+		uploaded: make(map[string]bool),
+		swept:    swept,
+		buf:      make([]byte, 0, DEDUP_MAX_CHUNK_SIZE),
+		sem:      make(chan struct{}, DEDUP_CONCURRENCY),
+	}, nil
+}
+
+func (s *DedupStorage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	file, err := s.Storage.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(file)
+	if !isDedupIndex(buffered) {
+		return &stackReadCloser{NopReadCloser{buffered}, file}, nil
+	}
+
+	index, err := readDedupIndex(buffered)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dedup index: %w", err)
+	}
+	if index.Keyed && s.key == nil {
+		return nil, ErrEncrypted
+	}
+
+	return &dedupReader{
+		ctx:     ctx,
+		storage: s.Storage,
+		key:     s.key,
+		index:   index,
+	}, nil
+}
+
+// Delete deletes the file, and drops the references it holds to any chunks, which are
+// deleted on the next sweep if no longer referenced.
+func (s *DedupStorage) Delete(ctx context.Context, path string) error {
+	var paths []string
+
+	if isDir, _ := s.Storage.IsDir(ctx, path); isDir {
+		list, _ := s.Storage.ReadDir(ctx, path)
+		for _, entry := range list {
+			paths = append(paths, path+"/"+filepath.Base(entry)) // do not use filepath.Join as it removes a slash
+		}
+	} else {
+		paths = append(paths, path)
+	}
+
+	for _, p := range paths {
+		index, err := s.readIndex(ctx, p)
+		if err != nil || index == nil {
+			continue
+		}
+		s.Storage.Delete(ctx, refPath(index.Root, p))
+	}
+
+	return s.Storage.Delete(ctx, path)
+}
+
+// Sweep deletes the chunks under the root that are no longer referenced by any index.
+// Returns the number of chunks deleted. Safe to call while files are being created.
+func (s *DedupStorage) Sweep(ctx context.Context, root string) (int, error) {
+	dedupState.Lock()
+	dedupState.sweeping++
+	dedupState.Unlock()
+
+	defer func() {
+		dedupState.Lock()
+		defer dedupState.Unlock()
+		dedupState.sweeping--
+		dedupState.swept++
+		if dedupState.sweeping == 0 {
+			clear(dedupState.committed)
+		}
+	}()
+
+	chunks, err := s.Storage.ReadDir(ctx, chunkDir(root))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil // no chunk store
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to list chunks: %w", err)
+	}
+
+	refs, err := s.Storage.ReadDir(ctx, refDir(root))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	referenced := make(map[string]bool)
+	for _, ref := range refs {
+		ref = refDir(root) + "/" + filepath.Base(ref)
+
+		indexPath, err := s.readRef(ctx, ref)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read ref %s: %w", ref, err)
+		}
+
+		index, err := s.readIndex(ctx, indexPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			s.Storage.Delete(ctx, ref) // index was deleted without its ref
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read index %s: %w", indexPath, err)
+		}
+		if index == nil {
+			continue
+		}
+		for _, chunk := range index.Chunks {
+			referenced[chunk.Hash] = true
+		}
+	}
+
+	var deleted int
+	var errs []error
+	for _, chunk := range chunks {
+		if strings.Contains(chunk, DEDUP_REF_DIR+"/") {
+			continue // listed recursively
+		}
+		chunk = filepath.Base(chunk)
+		if chunk == DEDUP_REF_DIR || referenced[chunk] {
+			continue
+		}
+		ok, err := s.sweepChunk(ctx, root, chunk)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			deleted++
+		}
+	}
+
+	return deleted, errors.Join(errs...)
+}
+
+///////////////
+/// Helpers ///
+///////////////
+
+func chunkDir(root string) string {
+	return root + "/" + DEDUP_CHUNK_DIR // do not use filepath.Join as it removes a slash (for remote)
+}
+
+func chunkPath(root, hash string) string {
+	return chunkDir(root) + "/" + hash
+}
+
+// Returns the ID of a chunk, its SHA-256, or its HMAC-SHA-256 if a key is given
+func chunkID(key, chunk []byte) string {
+	if key == nil {
+		sum := sha256.Sum256(chunk)
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(chunk)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func refDir(root string) string {
+	return chunkDir(root) + "/" + DEDUP_REF_DIR
+}
+
+func refPath(root, indexPath string) string {
+	sum := sha256.Sum256([]byte(indexPath))
+	return refDir(root) + "/" + hex.EncodeToString(sum[:])
+}
+
+func isDedupIndex(reader *bufio.Reader) bool {
+	magic, err := reader.Peek(len(DEDUP_MAGIC))
+	return err == nil && string(magic) == DEDUP_MAGIC
+}
+
+func readDedupIndex(reader io.Reader) (*DedupIndex, error) {
+	magic := make([]byte, len(DEDUP_MAGIC))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return nil, err
+	}
+	index := &DedupIndex{}
+	err := json.NewDecoder(reader).Decode(index)
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Reads the index at the path, or returns nil if the file is not one
+func (s *DedupStorage) readIndex(ctx context.Context, path string) (*DedupIndex, error) {
+	file, err := s.Storage.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buffered := bufio.NewReader(file)
+	if !isDedupIndex(buffered) {
+		return nil, nil
+	}
+	return readDedupIndex(buffered)
+}
+
+// Deletes the chunk, unless it has since been referenced by a writer.
+func (s *DedupStorage) sweepChunk(ctx context.Context, root, hash string) (bool, error) {
+	dedupState.Lock()
+	defer dedupState.Unlock()
+
+	if dedupState.inflight[hash] > 0 || dedupState.committed[hash] {
+		return false, nil
+	}
+
+	return true, s.Storage.Delete(ctx, chunkPath(root, hash))
+}
+
+func (s *DedupStorage) readRef(ctx context.Context, ref string) (string, error) {
+	file, err := s.Storage.Open(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+type dedupWriter struct {
+	ctx      context.Context
+	storage  Storage
+	path     string
+	key      []byte
+	index    *DedupIndex
+	existing map[string]bool // chunks in the store when created
+	uploaded map[string]bool // chunks uploaded by this writer
+	swept    uint64          // sweeps done when created
+	hashes   []string        // chunks marked in-flight by this writer
+
+	buf    []byte
+	hash   uint64
+	closed bool
+
+	sem   chan struct{}
+	wg    sync.WaitGroup
+	errMu sync.Mutex
+	err   error
+}
+
+func (w *dedupWriter) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, fmt.Errorf("write to closed dedup writer")
+	}
+	if err := w.uploadErr(); err != nil {
+		return 0, err
+	}
+	for _, b := range p {
+		w.buf = append(w.buf, b)
+		w.hash = (w.hash << 1) + dedupGear[b]
+		if len(w.buf) >= DEDUP_MIN_CHUNK_SIZE && (w.hash&dedupChunkMask == 0 || len(w.buf) >= DEDUP_MAX_CHUNK_SIZE) {
+			w.cut()
+		}
+	}
+	return len(p), nil
+}
+
+// Writes the index once all chunks are uploaded. Nothing is written if any chunk fails.
+// Chunks stay in-flight until the index and its ref are written, so they are never swept before.
+func (w *dedupWriter) Close() (err error) {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.release()
+
+	if len(w.buf) > 0 {
+		w.cut()
+	}
+	w.wg.Wait()
+	if err := w.uploadErr(); err != nil {
+		return err
+	}
+
+	var data bytes.Buffer
+	data.WriteString(DEDUP_MAGIC)
+	if err := json.NewEncoder(&data).Encode(w.index); err != nil {
+		return err
+	}
+
+	if err := w.put(w.path, &data); err != nil {
+		return fmt.Errorf("failed to write dedup index: %w", err)
+	}
+
+	ref := bytes.NewBufferString(w.path)
+	if err := w.put(refPath(w.index.Root, w.path), ref); err != nil {
+		return fmt.Errorf("failed to write dedup ref: %w", err)
+	}
+
+	return nil
+}
+
+// Cuts a chunk from the buffer, and uploads it in the background if not already in the store
+func (w *dedupWriter) cut() {
+	chunk := bytes.Clone(w.buf)
+	w.buf = w.buf[:0]
+	w.hash = 0
+
+	hash := chunkID(w.key, chunk)
+
+	w.index.Chunks = append(w.index.Chunks, DedupChunk{Hash: hash, Size: int64(len(chunk))})
+	w.index.Size += int64(len(chunk))
+
+	// Chunks that existed on create can only be relied on if none were swept since
+
+	dedupState.Lock()
+	dedupState.inflight[hash]++
+	trusted := dedupState.sweeping == 0 && dedupState.swept == w.swept
+	dedupState.Unlock()
+	w.hashes = append(w.hashes, hash)
+
+	if w.uploaded[hash] || (trusted && w.existing[hash]) {
+		return
+	}
+	w.uploaded[hash] = true
+
+	w.sem <- struct{}{}
+	w.wg.Go(func() {
+		defer func() { <-w.sem }()
+
+		encoder, err := dedupEncoder()
+		if err != nil {
+			w.setErr(err)
+			return
+		}
+		err = w.put(chunkPath(w.index.Root, hash), bytes.NewReader(encoder.EncodeAll(chunk, nil)))
+		if err != nil {
+			w.setErr(fmt.Errorf("failed to write chunk %s: %w", hash, err))
+		}
+	})
+}
+
+// Unmarks the chunks of the writer as in-flight. A sweep in progress may have missed the
+// index, so its chunks are marked as committed instead, until all sweeps are done.
+func (w *dedupWriter) release() {
+	dedupState.Lock()
+	defer dedupState.Unlock()
+
+	if dedupState.sweeping > 0 {
+		for _, hash := range w.hashes {
+			dedupState.committed[hash] = true
+		}
+	}
+
+	for _, hash := range w.hashes {
+		if dedupState.inflight[hash]--; dedupState.inflight[hash] <= 0 {
+			delete(dedupState.inflight, hash)
+		}
+	}
+	w.hashes = nil
+}
+
+func (w *dedupWriter) put(path string, data io.Reader) error {
+	file, err := w.storage.Create(w.ctx, path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, data)
+	return errors.Join(err, file.Close())
+}
+
+func (w *dedupWriter) setErr(err error) {
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+	w.err = errors.Join(w.err, err)
+}
+
+func (w *dedupWriter) uploadErr() error {
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+	return w.err
+}
+
+type dedupReader struct {
+	ctx     context.Context
+	storage Storage
+	key     []byte
+	index   *DedupIndex
+	next    int // next chunk to read
+	buf     []byte
+}
+
+func (r *dedupReader) Read(p []byte) (n int, err error) {
+	for len(r.buf) == 0 {
+		if r.next >= len(r.index.Chunks) {
+			return 0, io.EOF
+		}
+		r.buf, err = r.readChunk(r.index.Chunks[r.next])
+		if err != nil {
+			return 0, err
+		}
+		r.next++
+	}
+	n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *dedupReader) Close() error {
+	return nil
+}
+
+// Reads and verifies a whole chunk, as they are small enough
+func (r *dedupReader) readChunk(chunk DedupChunk) ([]byte, error) {
+	file, err := r.storage.Open(r.ctx, chunkPath(r.index.Root, chunk.Hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chunk %s: %w", chunk.Hash, err)
+	}
+	compressed, err := io.ReadAll(file)
+	err = errors.Join(err, file.Close())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk %s: %w", chunk.Hash, err)
+	}
+
+	decoder, err := dedupDecoder()
+	if err != nil {
+		return nil, err
+	}
+	data, err := decoder.DecodeAll(compressed, make([]byte, 0, chunk.Size))
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s is corrupted: %w", chunk.Hash, err)
+	}
+
+	var key []byte
+	if r.index.Keyed {
+		key = r.key
+	}
+	if int64(len(data)) != chunk.Size || chunkID(key, data) != chunk.Hash {
+		return nil, fmt.Errorf("chunk %s is corrupted: checksum mismatch", chunk.Hash)
+	}
+
+	return data, nil
+}
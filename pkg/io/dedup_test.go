@@ -0,0 +1,280 @@
+package io
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// In-memory storage, that counts the files created
+type memStorage struct {
+	sync.Mutex
+	files   map[string][]byte
+	created int
+}
+
+type memFile struct {
+	bytes.Buffer
+	storage *memStorage
+	path    string
+}
+
+func (f *memFile) Close() error {
+	f.storage.Lock()
+	defer f.storage.Unlock()
+	f.storage.files[f.path] = f.Bytes()
+	return nil
+}
+
+func (s *memStorage) Open(_ context.Context, path string) (io.ReadCloser, error) {
+	s.Lock()
+	defer s.Unlock()
+	data, ok := s.files[path]
+	if !ok {
+		return nil, fmt.Errorf("failed to open %s: %w", path, fs.ErrNotExist)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memStorage) Create(_ context.Context, path string) (io.WriteCloser, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.created++
+	return &memFile{storage: s, path: path}, nil
+}
+
+func (s *memStorage) Delete(_ context.Context, path string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.files, path)
+	return nil
+}
+
+func (s *memStorage) IsDir(_ context.Context, path string) (bool, error) {
+	return false, nil
+}
+
+func (s *memStorage) ReadDir(_ context.Context, path string) ([]string, error) {
+	s.Lock()
+	defer s.Unlock()
+	var list []string
+	for name := range s.files {
+		if rest, ok := strings.CutPrefix(name, path+"/"); ok && !strings.Contains(rest, "/") {
+			list = append(list, rest)
+		}
+	}
+	if len(list) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	return list, nil
+}
+
+func (s *memStorage) IsRemote() bool {
+	return true
+}
+
+func dedupWrite(t *testing.T, storage Storage, path string, data []byte) {
+	t.Helper()
+	file, err := storage.Create(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", path, err)
+	}
+	if _, err := file.Write(data); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("failed to close %s: %v", path, err)
+	}
+}
+
+func dedupRead(t *testing.T, storage Storage, path string) []byte {
+	t.Helper()
+	file, err := storage.Open(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return data
+}
+
+func TestDedup_RoundTrip(t *testing.T) {
+	base := &memStorage{files: make(map[string][]byte)}
+	storage := NewDedupStorage(base, "mem://root")
+
+	for _, size := range []int{0, 1, DEDUP_MIN_CHUNK_SIZE, 5*DEDUP_MAX_CHUNK_SIZE + 7} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		path := fmt.Sprintf("mem://root/%d.tar", size)
+		dedupWrite(t, storage, path, data)
+
+		if !bytes.Equal(dedupRead(t, storage, path), data) {
+			t.Errorf("size %d: read data does not match", size)
+		}
+	}
+}
+
+func TestDedup_Repeated(t *testing.T) {
+	base := &memStorage{files: make(map[string][]byte)}
+	storage := NewDedupStorage(base, "mem://root")
+
+	data := make([]byte, 8*DEDUP_MAX_CHUNK_SIZE)
+	rand.Read(data)
+	dedupWrite(t, storage, "mem://root/first.tar", data)
+	first := base.created
+
+	// Change a few bytes in the middle, and shift the rest
+	changed := append(bytes.Clone(data[:4*DEDUP_MAX_CHUNK_SIZE]), []byte("changed")...)
+	changed = append(changed, data[4*DEDUP_MAX_CHUNK_SIZE:]...)
+	base.created = 0
+	dedupWrite(t, storage, "mem://root/second.tar", changed)
+
+	if base.created >= first/2 {
+		t.Errorf("expected few new chunks for a small change, created %d files (vs %d)", base.created, first)
+	}
+	if !bytes.Equal(dedupRead(t, storage, "mem://root/second.tar"), changed) {
+		t.Error("read data does not match")
+	}
+}
+
+func TestDedup_Sweep(t *testing.T) {
+	base := &memStorage{files: make(map[string][]byte)}
+	storage := NewDedupStorage(base, "mem://root")
+	ctx := context.Background()
+
+	shared := make([]byte, 4*DEDUP_MAX_CHUNK_SIZE)
+	rand.Read(shared)
+	unique := make([]byte, 4*DEDUP_MAX_CHUNK_SIZE)
+	rand.Read(unique)
+
+	dedupWrite(t, storage, "mem://root/first.tar", shared)
+	dedupWrite(t, storage, "mem://root/second.tar", append(bytes.Clone(shared), unique...))
+
+	if deleted, err := storage.Sweep(ctx, "mem://root"); err != nil || deleted != 0 {
+		t.Fatalf("expected nothing to sweep, deleted %d: %v", deleted, err)
+	}
+
+	if err := storage.Delete(ctx, "mem://root/second.tar"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	deleted, err := storage.Sweep(ctx, "mem://root")
+	if err != nil {
+		t.Fatalf("failed to sweep: %v", err)
+	}
+	if deleted == 0 {
+		t.Error("expected chunks only referenced by the deleted file to be swept")
+	}
+
+	if !bytes.Equal(dedupRead(t, storage, "mem://root/first.tar"), shared) {
+		t.Error("read data does not match after sweep")
+	}
+}
+
+func TestDedup_SweepWhileWriting(t *testing.T) {
+	base := &memStorage{files: make(map[string][]byte)}
+	storage := NewDedupStorage(base, "mem://root")
+	ctx := context.Background()
+
+	data := make([]byte, 4*DEDUP_MAX_CHUNK_SIZE)
+	rand.Read(data)
+	dedupWrite(t, storage, "mem://root/first.tar", data)
+	if err := storage.Delete(ctx, "mem://root/first.tar"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	// Chunks of the deleted file are written again, while a sweep runs
+
+	file, err := storage.Create(ctx, "mem://root/second.tar")
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	if _, err := storage.Sweep(ctx, "mem://root"); err != nil {
+		t.Fatalf("failed to sweep: %v", err)
+	}
+	dedupWrite(t, storage, "mem://root/third.tar", data) // must not block on the open writer
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if !bytes.Equal(dedupRead(t, storage, "mem://root/second.tar"), data) {
+		t.Error("read data does not match after sweep")
+	}
+	if !bytes.Equal(dedupRead(t, storage, "mem://root/third.tar"), data) {
+		t.Error("read data does not match after sweep")
+	}
+}
+
+func TestDedup_Corrupted(t *testing.T) {
+	base := &memStorage{files: make(map[string][]byte)}
+	storage := NewDedupStorage(base, "mem://root")
+
+	data := make([]byte, 2*DEDUP_MAX_CHUNK_SIZE)
+	rand.Read(data)
+	dedupWrite(t, storage, "mem://root/first.tar", data)
+
+	chunks, _ := base.ReadDir(context.Background(), "mem://root/.chunks")
+	delete(base.files, "mem://root/.chunks/"+chunks[0])
+
+	file, err := storage.Open(context.Background(), "mem://root/first.tar")
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	defer file.Close()
+	if _, err := io.ReadAll(file); err == nil {
+		t.Error("expected error for missing chunk")
+	}
+}
+
+func TestDedup_Encrypted(t *testing.T) {
+	base := &memStorage{files: make(map[string][]byte)}
+	key := make([]byte, ENCRYPTION_KEY_SIZE)
+	rand.Read(key)
+	storage := NewDedupStorage(NewEncryptedStorage(base, key), "mem://root")
+
+	data := make([]byte, 2*DEDUP_MAX_CHUNK_SIZE)
+	rand.Read(data)
+	dedupWrite(t, storage, "mem://root/first.tar", data)
+
+	if !bytes.Equal(dedupRead(t, storage, "mem://root/first.tar"), data) {
+		t.Error("read data does not match")
+	}
+
+	// Chunks must not be named by the plain hashes of their content
+	index, err := storage.readIndex(context.Background(), "mem://root/first.tar")
+	if err != nil || index == nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	if !index.Keyed {
+		t.Error("expected chunks to be keyed")
+	}
+	rest := data
+	for _, chunk := range index.Chunks {
+		if _, ok := base.files[chunkPath("mem://root", chunk.Hash)]; !ok {
+			t.Errorf("expected chunk %s in the store", chunk.Hash)
+		}
+		if chunk.Hash == chunkID(nil, rest[:chunk.Size]) {
+			t.Error("expected chunk ID to not be its plain hash")
+		}
+		rest = rest[chunk.Size:]
+	}
+
+	// Without the key, keyed chunks can't be read
+	if _, err := NewDedupStorage(NewEncryptedStorage(base, nil), "").Open(context.Background(), "mem://root/first.tar"); err == nil {
+		t.Error("expected error opening without the key")
+	}
+}
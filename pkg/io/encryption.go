@@ -0,0 +1,337 @@
+package io
+
+// Streaming authenticated encryption of checkpoints with AES-256-GCM. The stream is split into
+// chunks that are sealed individually, so it never has to be held in memory. Each chunk's nonce
+// is derived from a random per-stream prefix and the chunk's index, and the last chunk is marked
+// as final, so reordered, truncated or appended chunks are detected.
+//
+// Format: MAGIC | nonce prefix (12 bytes) | chunk...
+// Chunk:  header (4 bytes, big endian: final flag in the top bit, sealed length in the rest) | sealed data
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	ENCRYPTION_MAGIC      = "CEDANAE1"
+	ENCRYPTION_KEY_SIZE   = 32 // AES-256
+	ENCRYPTION_CHUNK_SIZE = 64 * KIBIBYTE
+
+	encryptionFinalFlag = 1 << 31
+)
+
+var ErrEncrypted = errors.New("checkpoint is encrypted, but no encryption key is configured")
+
+// KeyProvider provides encryption keys by ID, e.g. from a KMS.
+type KeyProvider interface {
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// ParseKey parses a 256-bit key, either raw, or hex or base64 encoded.
+func ParseKey(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == ENCRYPTION_KEY_SIZE:
+		return data, nil
+	case len(data) == hex.EncodedLen(ENCRYPTION_KEY_SIZE):
+		return hex.DecodeString(string(data))
+	default:
+		key, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil || len(key) != ENCRYPTION_KEY_SIZE {
+			return nil, fmt.Errorf("key must be %d bytes, either raw, or hex or base64 encoded", ENCRYPTION_KEY_SIZE)
+		}
+		return key, nil
+	}
+}
+
+// NewEncryptionWriter returns a writer that encrypts everything written to it, before
+// writing to the provided writer. Must be closed to write the final chunk.
+// Does not close the provided writer.
+func NewEncryptionWriter(writer io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	if _, err := writer.Write(append([]byte(ENCRYPTION_MAGIC), prefix...)); err != nil {
+		return nil, err
+	}
+
+	return &encryptionWriter{
+		dst:    writer,
+		aead:   aead,
+		prefix: prefix,
+		buf:    make([]byte, 0, ENCRYPTION_CHUNK_SIZE),
+	}, nil
+}
+
+// NewDecryptionReader returns a reader that decrypts a stream written by an encryption writer.
+// Returns an error if the stream is tampered with or truncated.
+func NewDecryptionReader(reader io.Reader, key []byte) (io.ReadCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(ENCRYPTION_MAGIC)+aead.NonceSize())
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+	if string(header[:len(ENCRYPTION_MAGIC)]) != ENCRYPTION_MAGIC {
+		return nil, fmt.Errorf("not an encrypted stream")
+	}
+
+	return &decryptionReader{
+		src:    reader,
+		aead:   aead,
+		prefix: header[len(ENCRYPTION_MAGIC):],
+	}, nil
+}
+
+// IsEncrypted checks if the buffered stream is encrypted, without consuming it.
+func IsEncrypted(reader *bufio.Reader) bool {
+	magic, err := reader.Peek(len(ENCRYPTION_MAGIC))
+	return err == nil && string(magic) == ENCRYPTION_MAGIC
+}
+
+// EncryptedStorage wraps a storage to encrypt all files created, and transparently
+// decrypt files opened. Files that are not encrypted are opened as is. If the key is nil,
+// files are created unencrypted, and opening an encrypted file returns ErrEncrypted.
+type EncryptedStorage struct {
+	Storage
+	key []byte
+}
+
+func NewEncryptedStorage(storage Storage, key []byte) *EncryptedStorage {
+	return &EncryptedStorage{Storage: storage, key: key}
+}
+
+func (s *EncryptedStorage) Create(ctx context.Context, path string) (io.WriteCloser, error) {
+	file, err := s.Storage.Create(ctx, path)
+	if err != nil || s.key == nil {
+		return file, err
+	}
+
+	writer, err := NewEncryptionWriter(file, s.key)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &stackWriteCloser{writer, file}, nil
+}
+
+func (s *EncryptedStorage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	file, err := s.Storage.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(file)
+	if !IsEncrypted(buffered) {
+		return &stackReadCloser{NopReadCloser{buffered}, file}, nil
+	}
+	if s.key == nil {
+		file.Close()
+		return nil, ErrEncrypted
+	}
+
+	reader, err := NewDecryptionReader(buffered, s.key)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &stackReadCloser{reader, file}, nil
+}
+
+// IsEncrypting returns whether files created are encrypted.
+func (s *EncryptedStorage) IsEncrypting() bool {
+	return s.key != nil
+}
+
+///////////////
+/// Helpers ///
+///////////////
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != ENCRYPTION_KEY_SIZE {
+		return nil, fmt.Errorf("invalid key size %d, must be %d bytes", len(key), ENCRYPTION_KEY_SIZE)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Derives the nonce of a chunk from the stream's nonce prefix and the chunk's index
+func chunkNonce(prefix []byte, index uint64) []byte {
+	nonce := bytes.Clone(prefix)
+	tail := nonce[len(nonce)-8:]
+	binary.BigEndian.PutUint64(tail, binary.BigEndian.Uint64(tail)^index)
+	return nonce
+}
+
+func chunkAAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+type encryptionWriter struct {
+	dst    io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	index  uint64
+	buf    []byte
+	closed bool
+}
+
+func (w *encryptionWriter) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, fmt.Errorf("write to closed encryption writer")
+	}
+	for len(p) > 0 {
+		// Only flush a full chunk once there's more data, as the last chunk must be marked final
+		if len(w.buf) == ENCRYPTION_CHUNK_SIZE {
+			if err := w.flush(false); err != nil {
+				return n, err
+			}
+		}
+		k := min(ENCRYPTION_CHUNK_SIZE-len(w.buf), len(p))
+		w.buf = append(w.buf, p[:k]...)
+		p = p[k:]
+		n += k
+	}
+	return n, nil
+}
+
+func (w *encryptionWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
+
+func (w *encryptionWriter) flush(final bool) error {
+	sealed := w.aead.Seal(nil, chunkNonce(w.prefix, w.index), w.buf, chunkAAD(final))
+	w.index++
+	w.buf = w.buf[:0]
+
+	header := uint32(len(sealed))
+	if final {
+		header |= encryptionFinalFlag
+	}
+	if err := binary.Write(w.dst, binary.BigEndian, header); err != nil {
+		return err
+	}
+	_, err := w.dst.Write(sealed)
+	return err
+}
+
+type decryptionReader struct {
+	src    io.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	index  uint64
+	buf    []byte
+	final  bool
+}
+
+func (r *decryptionReader) Read(p []byte) (n int, err error) {
+	for len(r.buf) == 0 {
+		if r.final {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *decryptionReader) Close() error {
+	return nil
+}
+
+func (r *decryptionReader) next() error {
+	var header uint32
+	err := binary.Read(r.src, binary.BigEndian, &header)
+	if err == io.EOF {
+		return fmt.Errorf("encrypted stream is truncated: %w", io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return err
+	}
+
+	final := header&encryptionFinalFlag != 0
+	size := int(header &^ encryptionFinalFlag)
+	if size > ENCRYPTION_CHUNK_SIZE+r.aead.Overhead() {
+		return fmt.Errorf("encrypted stream is corrupted: invalid chunk size %d", size)
+	}
+
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(r.src, sealed); err != nil {
+		return fmt.Errorf("encrypted stream is truncated: %w", err)
+	}
+
+	r.buf, err = r.aead.Open(sealed[:0], chunkNonce(r.prefix, r.index), sealed, chunkAAD(final))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt, wrong key or corrupted stream: %w", err)
+	}
+	r.index++
+	r.final = final
+
+	if final {
+		n, err := r.src.Read(make([]byte, 1))
+		if n > 0 {
+			return fmt.Errorf("encrypted stream is corrupted: data after final chunk")
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Closes both the wrapping writer and the underlying one, in order
+type stackWriteCloser struct {
+	io.WriteCloser
+	base io.Closer
+}
+
+func (s *stackWriteCloser) Close() error {
+	return errors.Join(s.WriteCloser.Close(), s.base.Close())
+}
+
+// Closes both the wrapping reader and the underlying one, in order
+type stackReadCloser struct {
+	io.ReadCloser
+	base io.Closer
+}
+
+func (s *stackReadCloser) Close() error {
+	return errors.Join(s.ReadCloser.Close(), s.base.Close())
+}
@@ -0,0 +1,124 @@
+package io
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func encrypt(t *testing.T, key, data []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	writer, err := NewEncryptionWriter(&out, key)
+	if err != nil {
+		t.Fatalf("failed to create encryption writer: %v", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	return out.Bytes()
+}
+
+func decrypt(key, data []byte) ([]byte, error) {
+	reader, err := NewDecryptionReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func TestEncryption_RoundTrip(t *testing.T) {
+	key := make([]byte, ENCRYPTION_KEY_SIZE)
+	rand.Read(key)
+
+	for _, size := range []int{0, 1, ENCRYPTION_CHUNK_SIZE, ENCRYPTION_CHUNK_SIZE + 1, 3*ENCRYPTION_CHUNK_SIZE + 7} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		encrypted := encrypt(t, key, data)
+		if !IsEncrypted(bufio.NewReader(bytes.NewReader(encrypted))) {
+			t.Errorf("size %d: expected stream to be detected as encrypted", size)
+		}
+
+		decrypted, err := decrypt(key, encrypted)
+		if err != nil {
+			t.Fatalf("size %d: failed to decrypt: %v", size, err)
+		}
+		if !bytes.Equal(decrypted, data) {
+			t.Errorf("size %d: decrypted data does not match", size)
+		}
+	}
+}
+
+func TestEncryption_Truncated(t *testing.T) {
+	key := make([]byte, ENCRYPTION_KEY_SIZE)
+	rand.Read(key)
+
+	data := make([]byte, 2*ENCRYPTION_CHUNK_SIZE+1)
+	encrypted := encrypt(t, key, data)
+
+	// Cut at a chunk boundary, so only the missing final chunk gives it away
+	header := len(ENCRYPTION_MAGIC) + 12
+	chunk := 4 + ENCRYPTION_CHUNK_SIZE + 16
+
+	for _, length := range []int{len(encrypted) - 1, header + chunk, header + 2*chunk} {
+		if _, err := decrypt(key, encrypted[:length]); err == nil {
+			t.Errorf("length %d: expected error for truncated stream", length)
+		}
+	}
+}
+
+func TestEncryption_Appended(t *testing.T) {
+	key := make([]byte, ENCRYPTION_KEY_SIZE)
+	rand.Read(key)
+
+	encrypted := encrypt(t, key, []byte("secret"))
+	appended := encrypt(t, key, []byte("appended"))
+	header := len(ENCRYPTION_MAGIC) + 12
+
+	if _, err := decrypt(key, append(encrypted, appended[header:]...)); err == nil {
+		t.Error("expected error for chunks appended after the final chunk")
+	}
+}
+
+func TestEncryption_WrongKey(t *testing.T) {
+	key := make([]byte, ENCRYPTION_KEY_SIZE)
+	rand.Read(key)
+	wrongKey := make([]byte, ENCRYPTION_KEY_SIZE)
+	rand.Read(wrongKey)
+
+	encrypted := encrypt(t, key, []byte("secret"))
+
+	if _, err := decrypt(wrongKey, encrypted); err == nil {
+		t.Error("expected error when decrypting with wrong key")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	raw := bytes.Repeat([]byte{0xab}, ENCRYPTION_KEY_SIZE)
+
+	for _, encoded := range []string{
+		string(raw),
+		"abababababababababababababababababababababababababababababababab\n",
+		"q6urq6urq6urq6urq6urq6urq6urq6urq6urq6urq6s=",
+	} {
+		key, err := ParseKey([]byte(encoded))
+		if err != nil {
+			t.Errorf("failed to parse key %q: %v", encoded, err)
+			continue
+		}
+		if !bytes.Equal(key, raw) {
+			t.Errorf("parsed key %q does not match", encoded)
+		}
+	}
+
+	if _, err := ParseKey([]byte("too short")); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
@@ -8,9 +8,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"sync"
+	"sync/atomic"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -20,7 +23,9 @@ const (
 	channelBufLen      = 32 // pending byte arrays in channel
 	readFromBufLen     = 512
 	streamDoneExitCode = 254
-	maxPendingMasters  = 0 // UNTESTED: DO NOT CHANGE
+	maxPendingMasters  = 0             // UNTESTED: DO NOT CHANGE
+	scrollbackLen      = 64 * KIBIBYTE // latest out/err kept, to replay to masters that attach later
+	masterBufLen       = 256           // pending out/err per master, before a read-only one is dropped
 )
 
 // Map of PID to Slave
@@ -33,18 +38,36 @@ type StreamIOMaster struct {
 	out      chan<- []byte
 	err      chan<- []byte
 	exitCode chan<- int
+	resize   <-chan *daemon.WindowSize
 }
 
 type StreamIOSlave struct {
 	PID uint32
 
 	// Channel of masters waiting to attach
-	master chan grpc.BidiStreamingServer[daemon.AttachReq, daemon.AttachResp]
+	master chan *attachment
 
 	in       chan<- []byte
 	out      <-chan []byte
 	err      <-chan []byte
 	exitCode <-chan int
+
+	pty    *os.File    // master end of the PTY owned by the slave, if any
+	writer atomic.Bool // whether a read-write master is attached
+}
+
+// A master attached to the slave, which gets the given number of lines of scrollback
+// replayed first (all if negative). Out/err is queued for it by the slave, and sent on the
+// master's own goroutine, so that a slow master does not hold up the others.
+type attachment struct {
+	stream   grpc.BidiStreamingServer[daemon.AttachReq, daemon.AttachResp]
+	readOnly bool
+	tail     int
+
+	replay chan []*daemon.AttachResp // scrollback to send before anything queued
+	sends  chan *daemon.AttachResp   // closed once the slave is done with the master
+	done   chan struct{}             // closed once the master is detached
+	err    error                     // why the master was dropped, if it was
 }
 
 type StreamIOReader struct {
@@ -61,16 +84,21 @@ type StreamIOWriter struct {
 	bytes chan<- []byte
 }
 
+// NewStreamIOMaster creates a master for the slave stream. Window sizes received on the resize
+// channel (can be nil) are sent to the slave. If the slave owns a PTY, true is received on the
+// returned tty channel, so the caller can set up its own terminal.
 func NewStreamIOMaster(
 	slave grpc.BidiStreamingClient[daemon.AttachReq, daemon.AttachResp],
-) (stdIn *StreamIOWriter, stdOut *StreamIOReader, stdErr *StreamIOReader, exitCode chan int, errors chan error) {
+	resize <-chan *daemon.WindowSize,
+) (stdIn *StreamIOWriter, stdOut *StreamIOReader, stdErr *StreamIOReader, exitCode chan int, errors chan error, tty chan bool) {
 	in := make(chan []byte, channelBufLen)
 	out := make(chan []byte, channelBufLen)
 	err := make(chan []byte, channelBufLen)
 	exitCode = make(chan int, 1)
 	errors = make(chan error, 1)
+	tty = make(chan bool, 1)
 
-	master := &StreamIOMaster{slave, in, out, err, exitCode}
+	master := &StreamIOMaster{slave, in, out, err, exitCode, resize}
 
 	// Receive out/err from slave
 	go func() {
@@ -86,6 +114,11 @@ func NewStreamIOMaster(
 				break
 			}
 			switch resp.Output.(type) {
+			case *daemon.AttachResp_TTY:
+				select {
+				case tty <- resp.GetTTY():
+				default:
+				}
 			case *daemon.AttachResp_Stdout:
 				out <- resp.GetStdout()
 			case *daemon.AttachResp_Stderr:
@@ -100,6 +133,7 @@ func NewStreamIOMaster(
 		close(err)
 		close(exitCode)
 		close(errors)
+		close(tty)
 	}()
 
 	// Send in to slave
@@ -118,6 +152,11 @@ func NewStreamIOMaster(
 				if error != nil {
 					break loop
 				}
+			case size := <-master.resize:
+				error := master.slave.Send(&daemon.AttachReq{Input: &daemon.AttachReq_Resize{Resize: size}})
+				if error != nil {
+					break loop
+				}
 			}
 		}
 	}()
@@ -126,7 +165,7 @@ func NewStreamIOMaster(
 	stdOut = &StreamIOReader{bytes: out}
 	stdErr = &StreamIOReader{bytes: err}
 
-	return stdIn, stdOut, stdErr, exitCode, errors
+	return stdIn, stdOut, stdErr, exitCode, errors, tty
 }
 
 func NewStreamIOSlave(
@@ -134,27 +173,63 @@ func NewStreamIOSlave(
 	wg *sync.WaitGroup,
 	pid uint32,
 	waitFirstMaster bool,
+) (stdIn *StreamIOReader, stdOut *StreamIOWriter, stdErr *StreamIOWriter) {
+	return newStreamIOSlave(ctx, wg, pid, waitFirstMaster, nil)
+}
+
+// NewStreamIOSlaveTTY creates a slave that owns the master end of a PTY, copying input from
+// masters into it and its output to masters. Masters can also resize the PTY.
+// The PTY is closed once all its slave ends are closed.
+func NewStreamIOSlaveTTY(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	pid uint32,
+	waitFirstMaster bool,
+	pty *os.File,
+) {
+	stdIn, stdOut, stdErr := newStreamIOSlave(ctx, wg, pid, waitFirstMaster, pty)
+
+	stdErr.Close() // a terminal only has a single output
+
+	wg.Go(func() {
+		io.Copy(pty, stdIn)
+	})
+	wg.Go(func() {
+		defer pty.Close()
+		stdOut.ReadFrom(pty) // EIO once all slave ends are closed
+	})
+}
+
+func newStreamIOSlave(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	pid uint32,
+	waitFirstMaster bool,
+	pty *os.File,
 ) (stdIn *StreamIOReader, stdOut *StreamIOWriter, stdErr *StreamIOWriter) {
 	in := make(chan []byte, channelBufLen)
 	out := make(chan []byte, channelBufLen)
 	err := make(chan []byte, channelBufLen)
 
 	slave := &StreamIOSlave{
-		pid,
-		make(chan grpc.BidiStreamingServer[daemon.AttachReq, daemon.AttachResp], maxPendingMasters),
-		in,
-		out,
-		err,
-		make(chan int, 1),
+		PID:      pid,
+		master:   make(chan *attachment, maxPendingMasters),
+		in:       in,
+		out:      out,
+		err:      err,
+		exitCode: make(chan int, 1),
+		pty:      pty,
 	}
 
+	scrollback := newScrollback(scrollbackLen)
+
 	SetIOSlave(pid, slave)
 
 	// Send out/err to master
 	wg.Go(func() {
 		defer DeleteIOSlave(&slave.PID)
 
-		masters := map[grpc.BidiStreamingServer[daemon.AttachReq, daemon.AttachResp]]any{}
+		masters := map[*attachment]any{}
 		if waitFirstMaster {
 			// Wait for first master before doing anything, so that no out/err is lost
 		wait_first_master:
@@ -164,7 +239,7 @@ func NewStreamIOSlave(
 					close(in)
 					return
 				case master := <-slave.master:
-					masters[master] = nil
+					slave.add(masters, master, scrollback)
 					break wait_first_master
 				}
 			}
@@ -175,29 +250,21 @@ func NewStreamIOSlave(
 			case <-ctx.Done():
 				break exit
 			case master := <-slave.master: // wait for a new master to attach
-				masters[master] = nil
+				slave.add(masters, master, scrollback)
 			case b, ok := <-out:
 				if !ok {
 					out = nil
 					break
 				}
-				for master := range masters {
-					err := master.Send(&daemon.AttachResp{Output: &daemon.AttachResp_Stdout{Stdout: b}})
-					if err != nil {
-						delete(masters, master)
-					}
-				}
+				scrollback.write(false, b)
+				slave.broadcast(masters, &daemon.AttachResp{Output: &daemon.AttachResp_Stdout{Stdout: b}})
 			case b, ok := <-err:
 				if !ok {
 					err = nil
 					break
 				}
-				for master := range masters {
-					err := master.Send(&daemon.AttachResp{Output: &daemon.AttachResp_Stderr{Stderr: b}})
-					if err != nil {
-						delete(masters, master)
-					}
-				}
+				scrollback.write(true, b)
+				slave.broadcast(masters, &daemon.AttachResp{Output: &daemon.AttachResp_Stderr{Stderr: b}})
 			}
 			if out == nil && err == nil { // exit once we've sent all out/err
 				break exit
@@ -206,10 +273,9 @@ func NewStreamIOSlave(
 
 		close(in)
 		code := <-slave.exitCode
+		slave.broadcast(masters, &daemon.AttachResp{Output: &daemon.AttachResp_ExitCode{ExitCode: int32(code)}})
 		for master := range masters {
-			master.Send(
-				&daemon.AttachResp{Output: &daemon.AttachResp_ExitCode{ExitCode: int32(code)}},
-			)
+			close(master.sends)
 		}
 	})
 
@@ -220,11 +286,33 @@ func NewStreamIOSlave(
 	return stdIn, stdOut, stdErr
 }
 
-// Attach attaches a master stream to the slave.
+// Attach attaches a master stream to the slave, replaying the given number of lines of
+// scrollback first (all if negative). Any number of read-only masters can be attached,
+// but only one read-write master at a time. Input from read-only masters is ignored, and
+// they are disconnected if they fall too far behind on output.
 func (s *StreamIOSlave) Attach(
 	ctx context.Context,
 	master grpc.BidiStreamingServer[daemon.AttachReq, daemon.AttachResp],
+	readOnly bool,
+	tail int,
 ) error {
+	if !readOnly {
+		if !s.writer.CompareAndSwap(false, true) {
+			return status.Errorf(codes.FailedPrecondition, "another master is attached read-write, attach read-only instead")
+		}
+		defer s.writer.Store(false)
+	}
+
+	attachment := &attachment{
+		stream:   master,
+		readOnly: readOnly,
+		tail:     tail,
+		replay:   make(chan []*daemon.AttachResp, 1),
+		sends:    make(chan *daemon.AttachResp, masterBufLen),
+		done:     make(chan struct{}),
+	}
+	defer close(attachment.done)
+
 wait:
 	for {
 		select {
@@ -232,26 +320,120 @@ wait:
 			return ctx.Err()
 		case <-master.Context().Done():
 			return master.Context().Err()
-		case s.master <- master:
+		case s.master <- attachment:
 			break wait
 		}
 	}
 
 	// Receive in from master
-loop:
+	detached := make(chan struct{})
+	go func() {
+		defer close(detached)
+		for {
+			req, error := master.Recv()
+			if error != nil {
+				return
+			}
+			if readOnly {
+				continue // only to know when the master detaches
+			}
+			if size := req.GetResize(); size != nil {
+				if s.pty == nil {
+					continue // not a terminal, nothing to resize
+				}
+				if error := SetWinsize(s.pty, size); error != nil {
+					log.Debug().Err(error).Uint32("PID", s.PID).Msg("failed to resize PTY")
+				}
+				continue
+			}
+			select {
+			case <-master.Context().Done():
+				return
+			case <-attachment.done:
+				return
+			case s.in <- req.GetStdin():
+			}
+		}
+	}()
+
+	// Send out/err to master, starting with the scrollback
+	for _, resp := range <-attachment.replay {
+		if error := master.Send(resp); error != nil {
+			return error
+		}
+	}
 	for {
-		req, error := master.Recv()
-		if error != nil {
-			break
+		select {
+		case <-detached:
+			return nil
+		case resp, ok := <-attachment.sends:
+			if !ok {
+				return attachment.err
+			}
+			if error := master.Send(resp); error != nil {
+				return error
+			}
+		}
+	}
+}
+
+// IsTTY returns whether the slave owns a PTY, i.e. the process' stdio is a terminal.
+func (s *StreamIOSlave) IsTTY() bool {
+	return s.pty != nil
+}
+
+// Adds a master to the set of attached masters, handing it whether the slave owns a PTY
+// and the requested scrollback, to be sent on its own goroutine before any new out/err.
+func (s *StreamIOSlave) add(masters map[*attachment]any, master *attachment, scrollback *scrollback) {
+	var replay []*daemon.AttachResp
+	if s.pty != nil {
+		replay = append(replay, &daemon.AttachResp{Output: &daemon.AttachResp_TTY{TTY: true}})
+	}
+
+	for _, chunk := range scrollback.tail(master.tail) {
+		resp := &daemon.AttachResp{Output: &daemon.AttachResp_Stdout{Stdout: chunk.data}}
+		if chunk.stderr {
+			resp = &daemon.AttachResp{Output: &daemon.AttachResp_Stderr{Stderr: chunk.data}}
+		}
+		replay = append(replay, resp)
+	}
+
+	master.replay <- replay
+	masters[master] = nil
+}
+
+// Queues the response for all attached masters, to be sent on their own goroutines. Masters that
+// are detached, or read-only ones that have fallen too far behind, are dropped. The read-write
+// master is waited on instead, so that its output is never lost.
+func (s *StreamIOSlave) broadcast(masters map[*attachment]any, resp *daemon.AttachResp) {
+	for master := range masters {
+		if !master.queue(resp) {
+			delete(masters, master)
+			close(master.sends)
 		}
+	}
+}
+
+// Queues the response for the master, returning false if it should be dropped.
+func (a *attachment) queue(resp *daemon.AttachResp) bool {
+	if !a.readOnly {
 		select {
-		case <-master.Context().Done():
-			break loop
-		case s.in <- req.GetStdin():
+		case a.sends <- resp:
+			return true
+		case <-a.done:
+			return false
 		}
 	}
 
-	return nil
+	select {
+	case a.sends <- resp:
+		return true
+	case <-a.done:
+		return false
+	default:
+		a.err = status.Errorf(codes.ResourceExhausted, "fell too far behind on output")
+		return false
+	}
 }
 
 func (s *StreamIOReader) Read(p []byte) (n int, err error) {
@@ -0,0 +1,207 @@
+package io
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeMaster is a master stream on the slave's side, recording what is sent to it
+type fakeMaster struct {
+	grpc.ServerStream
+
+	ctx   context.Context
+	recv  chan *daemon.AttachReq
+	block chan struct{} // if set, sends wait until it's closed
+
+	mu     sync.Mutex
+	stdout []byte
+	stderr []byte
+	code   *int32
+}
+
+func newFakeMaster(ctx context.Context) *fakeMaster {
+	return &fakeMaster{ctx: ctx, recv: make(chan *daemon.AttachReq)}
+}
+
+func (f *fakeMaster) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeMaster) Recv() (*daemon.AttachReq, error) {
+	select {
+	case req, ok := <-f.recv:
+		if !ok {
+			return nil, io.EOF
+		}
+		return req, nil
+	case <-f.ctx.Done():
+		return nil, f.ctx.Err()
+	}
+}
+
+func (f *fakeMaster) Send(resp *daemon.AttachResp) error {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch resp.Output.(type) {
+	case *daemon.AttachResp_Stdout:
+		f.stdout = append(f.stdout, resp.GetStdout()...)
+	case *daemon.AttachResp_Stderr:
+		f.stderr = append(f.stderr, resp.GetStderr()...)
+	case *daemon.AttachResp_ExitCode:
+		code := resp.GetExitCode()
+		f.code = &code
+	}
+	return nil
+}
+
+func (f *fakeMaster) output() (stdout string, stderr string, code *int32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return string(f.stdout), string(f.stderr), f.code
+}
+
+func TestStreamIOSlaveAttach(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	pid := uint32(4242)
+
+	stdIn, stdOut, stdErr := NewStreamIOSlave(ctx, wg, pid, false)
+	slave := GetIOSlave(pid)
+
+	exitCode := make(chan int, 1)
+	exitCode <- 42
+	SetIOSlaveExitCode(pid, exitCode)
+
+	// Output while no one is attached is kept in the scrollback
+	stdOut.Write([]byte("one\ntwo\n"))
+	time.Sleep(50 * time.Millisecond) // out and err are not ordered with respect to each other
+	stdErr.Write([]byte("three\n"))
+	time.Sleep(50 * time.Millisecond)
+
+	writer := newFakeMaster(ctx)
+	writerDone := make(chan error, 1)
+	go func() { writerDone <- slave.Attach(ctx, writer, false, -1) }()
+
+	observer := newFakeMaster(ctx)
+	observerDone := make(chan error, 1)
+	go func() { observerDone <- slave.Attach(ctx, observer, true, 1) }()
+
+	time.Sleep(100 * time.Millisecond)
+
+	// Only one read-write master at a time
+	err := slave.Attach(ctx, newFakeMaster(ctx), false, 0)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected second read-write attach to fail, got %v", err)
+	}
+
+	// Input from read-only masters is ignored
+	observer.recv <- &daemon.AttachReq{Input: &daemon.AttachReq_Stdin{Stdin: []byte("ignored")}}
+	writer.recv <- &daemon.AttachReq{Input: &daemon.AttachReq_Stdin{Stdin: []byte("input")}}
+	buf := make([]byte, 16)
+	n, _ := stdIn.Read(buf)
+	if string(buf[:n]) != "input" {
+		t.Errorf("expected input from read-write master, got %q", buf[:n])
+	}
+
+	stdOut.Write([]byte("four\n"))
+	stdOut.Close()
+	stdErr.Close()
+	wg.Wait()
+
+	// Masters are done once they've been sent everything
+	if err := <-writerDone; err != nil {
+		t.Errorf("expected writer to be done, got %v", err)
+	}
+	if err := <-observerDone; err != nil {
+		t.Errorf("expected observer to be done, got %v", err)
+	}
+
+	stdout, stderr, code := writer.output()
+	if stdout != "one\ntwo\nfour\n" || stderr != "three\n" {
+		t.Errorf("expected full scrollback and new output for writer, got %q and %q", stdout, stderr)
+	}
+	if code == nil || *code != 42 {
+		t.Errorf("expected exit code 42 for writer, got %v", code)
+	}
+
+	stdout, stderr, code = observer.output()
+	if stdout != "four\n" || stderr != "three\n" {
+		t.Errorf("expected last line of scrollback and new output for observer, got %q and %q", stdout, stderr)
+	}
+	if code == nil || *code != 42 {
+		t.Errorf("expected exit code 42 for observer, got %v", code)
+	}
+}
+
+func TestStreamIOSlaveSlowObserver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	pid := uint32(4343)
+
+	_, stdOut, stdErr := NewStreamIOSlave(ctx, wg, pid, false)
+	slave := GetIOSlave(pid)
+
+	exitCode := make(chan int, 1)
+	exitCode <- 0
+	SetIOSlaveExitCode(pid, exitCode)
+
+	writer := newFakeMaster(ctx)
+	writerDone := make(chan error, 1)
+	go func() { writerDone <- slave.Attach(ctx, writer, false, 0) }()
+
+	observer := newFakeMaster(ctx)
+	observer.block = make(chan struct{})
+	observerDone := make(chan error, 1)
+	go func() { observerDone <- slave.Attach(ctx, observer, true, 0) }()
+
+	time.Sleep(100 * time.Millisecond)
+
+	// One more than the observer can take, with one being sent and the rest queued
+	n := masterBufLen + 2
+	for range n {
+		stdOut.Write([]byte("x"))
+	}
+	stdOut.Close()
+	stdErr.Close()
+	wg.Wait()
+
+	// The writer is not held up by the observer
+	select {
+	case err := <-writerDone:
+		if err != nil {
+			t.Errorf("expected writer to be done, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for writer, held up by the observer")
+	}
+	stdout, _, code := writer.output()
+	if len(stdout) != n || code == nil {
+		t.Errorf("expected all %d bytes and exit code for writer, got %d bytes and %v", n, len(stdout), code)
+	}
+
+	close(observer.block)
+
+	err := <-observerDone
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected observer to be dropped, got %v", err)
+	}
+	stdout, _, code = observer.output()
+	if len(stdout) != n-1 || code != nil {
+		t.Errorf("expected %d bytes and no exit code for observer, got %d bytes and %v", n-1, len(stdout), code)
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"archive/tar"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
 )
 
 // CopyNotify asynchronously does io.Copy, notifying when done.
@@ -23,6 +29,21 @@ func CopyNotify(dst io.Writer, src io.Reader) chan error {
 // Tar creates a tarball from the provided sources and writes it to the destination.
 // FIXME: Works only with files, not directories in the tarball.
 func Tar(src string, dst io.Writer, compression string, isFuse bool) (err error) {
+	return TarWith(src, dst, compression, isFuse, nil, nil)
+}
+
+// TarWith is like Tar, but also copies each regular file archived to the writer returned by tee
+// for its path relative to the source (if not nil), e.g. to compute checksums without reading the
+// files again. Once all files are archived, the files returned by trailer are appended, by path
+// relative to the source. Either function can be nil.
+func TarWith(
+	src string,
+	dst io.Writer,
+	compression string,
+	isFuse bool,
+	tee func(name string) io.Writer,
+	trailer func() (map[string][]byte, error),
+) (err error) {
 	writer, err := NewOptimizedCompressionWriter(dst, compression, isFuse)
 	if err != nil {
 		return err
@@ -32,54 +53,92 @@ func Tar(src string, dst io.Writer, compression string, isFuse bool) (err error)
 	tarWriter := tar.NewWriter(writer)
 	defer tarWriter.Close()
 
-	err = filepath.Walk(src, func(file string, fi os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	err = tarDir(tarWriter, src, src, nil, tee)
+	if err != nil || trailer == nil {
+		return err
+	}
 
-		header, err := tar.FileInfoHeader(fi, file)
-		if err != nil {
-			return err
-		}
+	files, err := trailer()
+	if err != nil {
+		return err
+	}
 
-		// Adjust the file's path to exclude the base directory
-		relPath, err := filepath.Rel(src, file)
-		if err != nil {
-			return err
+	names := slices.Sorted(maps.Keys(files))
+	for _, name := range names {
+		header := &tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(files[name])),
+			Typeflag: tar.TypeReg,
+			ModTime:  time.Now(),
 		}
-		header.Name = relPath
-
 		if err := tarWriter.WriteHeader(header); err != nil {
 			return err
 		}
-
-		if !fi.Mode().IsRegular() {
-			return nil
+		if _, err := tarWriter.Write(files[name]); err != nil {
+			return err
 		}
+	}
 
-		srcFile, err := os.Open(file)
+	return nil
+}
+
+// TarIncremental is like Tar, but archives each directory received on parts (within the source)
+// as soon as it's received, e.g. while the rest of the source is still being written. Once parts
+// is closed, the rest of the source is archived. Parts are drained on failure, so senders never block.
+func TarIncremental(src string, dst io.Writer, compression string, parts <-chan string) (err error) {
+	defer func() {
 		if err != nil {
-			return err
+			go func() {
+				for range parts {
+				}
+			}()
 		}
-		defer srcFile.Close()
+	}()
 
-		_, err = io.Copy(tarWriter, srcFile)
+	writer, err := NewOptimizedCompressionWriter(dst, compression, false)
+	if err != nil {
 		return err
-	})
+	}
+	defer writer.Close()
 
-	return err
+	tarWriter := tar.NewWriter(writer)
+	defer tarWriter.Close()
+
+	archived := make(map[string]bool)
+	for part := range parts {
+		if err := tarDir(tarWriter, src, part, nil, nil); err != nil {
+			return err
+		}
+		archived[filepath.Clean(part)] = true
+	}
+
+	return tarDir(tarWriter, src, src, archived, nil)
 }
 
 // Untar decompresses the provided tarball to the destination directory.
 // The destination directory should already exist.
 // FIXME: Works only with files, not directories in the tarball.
 func Untar(src io.Reader, dest string, compression string) (err error) {
+	return UntarFiltered(src, dest, compression, nil)
+}
+
+// UntarFiltered is like Untar, but only extracts the files for which filter returns true, given
+// their header (e.g. to record the sizes of files not extracted). A nil filter extracts all files. As tarballs may come from outside (e.g. migrations, imports),
+// nothing is ever written outside the destination: paths are resolved within it, files are not
+// written through symlinks, and symlinks that point outside of it are rejected.
+func UntarFiltered(src io.Reader, dest string, compression string, filter func(header *tar.Header) bool) (err error) {
 	reader, err := NewCompressionReader(src, compression)
 	if err != nil {
 		return err
 	}
 	defer reader.Close()
 
+	dest, err = filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+
 	tarReader := tar.NewReader(reader)
 
 	// Iterate through the files in the tarball
@@ -94,12 +153,19 @@ func Untar(src io.Reader, dest string, compression string) (err error) {
 
 		// Clean and validate the path
 		cleanedPath := filepath.Clean(header.Name)
-		if strings.Contains(cleanedPath, "..") {
-			return fmt.Errorf("invalid file path: %s", cleanedPath)
+		if filepath.IsAbs(cleanedPath) || cleanedPath == ".." || strings.HasPrefix(cleanedPath, "../") {
+			return fmt.Errorf("invalid file path: %s", header.Name)
 		}
 
-		// Construct the full path for the file
-		target := filepath.Join(dest, cleanedPath)
+		if filter != nil && !filter(header) {
+			continue
+		}
+
+		// Construct the full path for the file, resolving any symlinks within the destination
+		target, err := securejoin.SecureJoin(dest, cleanedPath)
+		if err != nil {
+			return fmt.Errorf("invalid file path %s: %w", header.Name, err)
+		}
 
 		// Check the type of the file
 		switch header.Typeflag {
@@ -109,14 +175,21 @@ func Untar(src io.Reader, dest string, compression string) (err error) {
 				return err
 			}
 		case tar.TypeReg:
-			// Create file and write data into it
-			outFile, err := os.Create(target)
+			// Create file and write data into it, never through a symlink
+			outFile, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|syscall.O_NOFOLLOW, os.FileMode(header.Mode).Perm())
 			if err != nil {
 				return err
 			}
-			defer outFile.Close()
-
-			if _, err := io.Copy(outFile, tarReader); err != nil {
+			_, err = io.Copy(outFile, tarReader)
+			outFile.Close()
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := checkLink(dest, target, header.Linkname); err != nil {
+				return fmt.Errorf("invalid symlink %s: %w", header.Name, err)
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
 				return err
 			}
 		}
@@ -148,3 +221,104 @@ func ReadFrom(src io.Reader, dst *os.File, compression string) (n int64, err err
 
 	return dst.ReadFrom(reader)
 }
+
+// Archives the directory, within the source, with paths relative to the source. Directories in
+// skip (e.g. already archived) are not walked.
+func tarDir(tarWriter *tar.Writer, src string, dir string, skip map[string]bool, tee func(name string) io.Writer) error {
+	return filepath.Walk(dir, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() && skip[file] {
+			return filepath.SkipDir
+		}
+
+		var link string
+		if fi.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			// Links outside of the source (e.g. to the parent images of incremental dumps)
+			// are not archived, as they are refused on extraction anyway
+			if checkLink(src, file, link) != nil {
+				return nil
+			}
+		}
+
+		header, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+
+		// Adjust the file's path to exclude the base directory
+		relPath, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		header.Name = relPath
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		srcFile, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer srcFile.Close()
+
+		var fileWriter io.Writer = tarWriter
+		if tee != nil {
+			if w := tee(relPath); w != nil {
+				fileWriter = io.MultiWriter(tarWriter, w)
+			}
+		}
+
+		_, err = io.Copy(fileWriter, srcFile)
+		return err
+	})
+}
+
+// Checks that the symlink at path, with the given target, does not point outside of root.
+// The target must be relative, and may only go up (with leading '..') through the real
+// directories the link is in, so the check doesn't depend on other links in the tree.
+func checkLink(root string, path string, target string) error {
+	if filepath.IsAbs(target) {
+		return fmt.Errorf("absolute target %s", target)
+	}
+
+	dir, err := filepath.Rel(root, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	depth := 0
+	if dir != "." {
+		depth = len(strings.Split(dir, string(filepath.Separator)))
+	}
+
+	// Not cleaned, as 'link/..' is resolved by following the link, not lexically
+	up := true
+	for _, component := range strings.Split(target, string(filepath.Separator)) {
+		if component == "" || component == "." {
+			continue
+		}
+		if component != ".." {
+			up = false
+			continue
+		}
+		if !up {
+			return fmt.Errorf("target %s goes up after going down", target)
+		}
+		depth--
+		if depth < 0 {
+			return fmt.Errorf("target %s is outside of %s", target, root)
+		}
+	}
+
+	return nil
+}
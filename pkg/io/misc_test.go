@@ -0,0 +1,196 @@
+package io
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name string
+	link string // if set, a symlink to this target
+	dir  bool
+	body string
+}
+
+func makeTar(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		if e.link != "" {
+			header = &tar.Header{Name: e.name, Mode: 0o777, Typeflag: tar.TypeSymlink, Linkname: e.link}
+		}
+		if e.dir {
+			header = &tar.Header{Name: e.name, Mode: 0o755, Typeflag: tar.TypeDir}
+		}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestUntarRejectsEscapes(t *testing.T) {
+	cases := map[string][]tarEntry{
+		"absolute symlink then file": {
+			{name: "evil", link: "OUTSIDE"},
+			{name: "evil/pwned", body: "pwned"},
+		},
+		"relative symlink then file": {
+			{name: "evil", link: "../outside"},
+			{name: "evil/pwned", body: "pwned"},
+		},
+		"chained symlinks": {
+			{name: "a/b", link: ".."},
+			{name: "evil", link: "a/b/../outside"},
+			{name: "evil/pwned", body: "pwned"},
+		},
+		"parent path": {
+			{name: "../outside/pwned", body: "pwned"},
+		},
+	}
+
+	for name, entries := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "dest")
+			outside := filepath.Join(dir, "outside")
+			for _, path := range []string{dest, outside, filepath.Join(dest, "a")} {
+				if err := os.MkdirAll(path, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := range entries {
+				if entries[i].link == "OUTSIDE" {
+					entries[i].link = outside
+				}
+			}
+
+			err := Untar(makeTar(t, entries...), dest, "none")
+			if err == nil {
+				t.Error("expected untar to fail")
+			}
+
+			files, _ := os.ReadDir(outside)
+			if len(files) != 0 {
+				t.Errorf("expected nothing written outside of destination, got %v", files)
+			}
+		})
+	}
+}
+
+func TestUntarInternalSymlink(t *testing.T) {
+	dest := t.TempDir()
+
+	err := Untar(makeTar(t,
+		tarEntry{name: "images", dir: true},
+		tarEntry{name: "images/inventory.img", body: "inventory"},
+		tarEntry{name: "link", link: "images/inventory.img"},
+		tarEntry{name: "images/up", link: "../link"},
+	), dest, "none")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, "images", "up"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "inventory" {
+		t.Errorf("expected to read through the links, got %q", data)
+	}
+}
+
+func TestTarWithTrailer(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "pages-1.img"), []byte("pages"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	teed := make(map[string]*bytes.Buffer)
+	tee := func(name string) io.Writer {
+		teed[name] = &bytes.Buffer{}
+		return teed[name]
+	}
+	trailer := func() (map[string][]byte, error) {
+		return map[string][]byte{"trailer": []byte(teed["pages-1.img"].String())}, nil
+	}
+
+	var tarball bytes.Buffer
+	if err := TarWith(src, &tarball, "gzip", false, tee, trailer); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := t.TempDir()
+	if err := Untar(&tarball, dest, "gzip"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, "trailer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "pages" {
+		t.Errorf("expected trailer with the teed file content, got %q", data)
+	}
+}
+
+func TestTarIncremental(t *testing.T) {
+	src := t.TempDir()
+	part := filepath.Join(src, "pre-1")
+	if err := os.Mkdir(part, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(part, "pages-1.img"), []byte("pre"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	parts := make(chan string)
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(TarIncremental(src, writer, "gzip", parts))
+	}()
+
+	dest := t.TempDir()
+	extracted := make(chan error, 1)
+	go func() {
+		extracted <- Untar(reader, dest, "gzip")
+	}()
+
+	parts <- part
+
+	// The rest of the source is written after the part is handed over
+	if err := os.WriteFile(filepath.Join(src, "pages-1.img"), []byte("final"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("pre-1", filepath.Join(src, "parent")); err != nil {
+		t.Fatal(err)
+	}
+	close(parts)
+
+	if err := <-extracted; err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"pre-1/pages-1.img": "pre", "pages-1.img": "final", "parent/pages-1.img": "pre"} {
+		data, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("expected %s to be %q, got %q", name, want, data)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package io
+
+//////////////////////////
+//// Pseudo-terminals ////
+//////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"golang.org/x/sys/unix"
+)
+
+const ptmxPath = "/dev/ptmx"
+
+// Keys to detach from a terminal, as Ctrl+C is passed through to the process (Ctrl+P, Ctrl+Q)
+var DetachKeys = [2]byte{0x10, 0x11}
+
+// DetachReader passes input through, until the detach keys are read once armed.
+type DetachReader struct {
+	Armed atomic.Bool
+
+	reader  io.Reader
+	detach  func()
+	pending bool // first detach key was the last byte read
+}
+
+// OpenPTY allocates a new pseudo-terminal, returning its master and slave ends.
+// Neither becomes the controlling terminal of the caller.
+func OpenPTY() (master *os.File, slave *os.File, err error) {
+	master, err = os.OpenFile(ptmxPath, os.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", ptmxPath, err)
+	}
+	defer func() {
+		if err != nil {
+			master.Close()
+		}
+	}()
+
+	fd := int(master.Fd())
+
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unlock PTY: %w", err)
+	}
+
+	n, err := unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get PTY number: %w", err)
+	}
+
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open PTY slave: %w", err)
+	}
+
+	return master, slave, nil
+}
+
+// GetWinsize returns the window size of the terminal.
+func GetWinsize(terminal *os.File) (*daemon.WindowSize, error) {
+	ws, err := unix.IoctlGetWinsize(int(terminal.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return nil, err
+	}
+	return &daemon.WindowSize{Rows: uint32(ws.Row), Cols: uint32(ws.Col)}, nil
+}
+
+// SetWinsize sets the window size of the terminal, which also signals its foreground
+// process group with SIGWINCH.
+func SetWinsize(terminal *os.File, size *daemon.WindowSize) error {
+	return unix.IoctlSetWinsize(int(terminal.Fd()), unix.TIOCSWINSZ, &unix.Winsize{
+		Row: uint16(size.GetRows()),
+		Col: uint16(size.GetCols()),
+	})
+}
+
+// MakeRaw puts the terminal in raw mode, so input is passed through as is, returning
+// a function to restore its previous state.
+func MakeRaw(terminal *os.File) (restore func() error, err error) {
+	fd := int(terminal.Fd())
+
+	old, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *old
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+
+	err = unix.IoctlSetTermios(fd, unix.TCSETS, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return unix.IoctlSetTermios(fd, unix.TCSETS, old)
+	}, nil
+}
+
+// ForwardTerminal puts the local terminal in raw mode, and sends its window size, and any
+// later changes to it, to the resize channel until the context is done or restored.
+// Returns a function to stop forwarding and restore the terminal.
+func ForwardTerminal(ctx context.Context, terminal *os.File, resize chan<- *daemon.WindowSize) (restore func(), err error) {
+	restoreMode, err := MakeRaw(terminal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put terminal in raw mode: %w", err)
+	}
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	winch <- syscall.SIGWINCH // send the initial size
+
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-winch:
+			}
+			size, err := GetWinsize(terminal)
+			if err != nil {
+				continue
+			}
+			select {
+			case resize <- size:
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(winch)
+			close(stop)
+			restoreMode()
+		})
+	}, nil
+}
+
+// NewDetachReader creates a reader that calls detach, and returns EOF, once the detach keys
+// are read from the reader. It only looks for them once armed.
+func NewDetachReader(reader io.Reader, detach func()) *DetachReader {
+	return &DetachReader{reader: reader, detach: detach}
+}
+
+func (d *DetachReader) Read(p []byte) (n int, err error) {
+	if !d.Armed.Load() || len(p) < 2 {
+		return d.reader.Read(p)
+	}
+
+	buf := make([]byte, len(p)-1) // room for a held back first detach key
+	nr, err := d.reader.Read(buf)
+
+	for _, b := range buf[:nr] {
+		if d.pending {
+			d.pending = false
+			if b == DetachKeys[1] {
+				d.detach()
+				return n, io.EOF
+			}
+			p[n] = DetachKeys[0]
+			n++
+		}
+		if b == DetachKeys[0] {
+			d.pending = true
+			continue
+		}
+		p[n] = b
+		n++
+	}
+
+	return n, err
+}
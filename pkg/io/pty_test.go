@@ -0,0 +1,77 @@
+package io
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+)
+
+func TestOpenPTY(t *testing.T) {
+	master, slave, err := OpenPTY()
+	if err != nil {
+		t.Skipf("PTYs not available: %v", err)
+	}
+	defer master.Close()
+	defer slave.Close()
+
+	if _, err := MakeRaw(slave); err != nil { // no echo or line discipline in the way
+		t.Fatalf("failed to make slave raw: %v", err)
+	}
+
+	if _, err := slave.Write([]byte("hello")); err != nil {
+		t.Fatalf("failed to write to slave: %v", err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(master, buf); err != nil {
+		t.Fatalf("failed to read from master: %v", err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("expected 'hello' from master, got %q", buf)
+	}
+
+	if err := SetWinsize(master, &daemon.WindowSize{Rows: 24, Cols: 80}); err != nil {
+		t.Fatalf("failed to set window size: %v", err)
+	}
+	size, err := GetWinsize(slave)
+	if err != nil {
+		t.Fatalf("failed to get window size: %v", err)
+	}
+	if size.GetRows() != 24 || size.GetCols() != 80 {
+		t.Errorf("expected 24x80 window, got %dx%d", size.GetRows(), size.GetCols())
+	}
+}
+
+func TestDetachReader(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    []byte
+		armed    bool
+		expected []byte
+		detached bool
+	}{
+		{"unarmed", []byte{'a', 0x10, 0x11, 'b'}, false, []byte{'a', 0x10, 0x11, 'b'}, false},
+		{"detach", []byte{'a', 0x10, 0x11, 'b'}, true, []byte{'a'}, true},
+		{"not followed", []byte{'a', 0x10, 'b', 0x10}, true, []byte{'a', 0x10, 'b'}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			detached := false
+			reader := NewDetachReader(bytes.NewReader(c.input), func() { detached = true })
+			reader.Armed.Store(c.armed)
+
+			out, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, c.expected) {
+				t.Errorf("expected %q, got %q", c.expected, out)
+			}
+			if detached != c.detached {
+				t.Errorf("expected detached %v, got %v", c.detached, detached)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package io
+
+// Scrollback is a bounded buffer of the latest output of a slave, kept as chunks tagged with
+// the stream they were written to, so it can be replayed to masters that attach later.
+// Oldest output is dropped first once the buffer is full.
+
+type scrollback struct {
+	chunks []chunk
+	size   int
+	limit  int
+}
+
+type chunk struct {
+	stderr bool
+	data   []byte
+}
+
+func newScrollback(limit int) *scrollback {
+	return &scrollback{limit: limit}
+}
+
+// Appends output to the scrollback, dropping the oldest output if over the limit.
+func (s *scrollback) write(stderr bool, data []byte) {
+	if s.limit <= 0 || len(data) == 0 {
+		return
+	}
+	if len(data) > s.limit {
+		data = data[len(data)-s.limit:]
+	}
+
+	s.chunks = append(s.chunks, chunk{stderr, append([]byte(nil), data...)}) // writers may reuse their buffer
+	s.size += len(data)
+
+	for s.size > s.limit {
+		over := s.size - s.limit
+		first := s.chunks[0]
+		if over < len(first.data) {
+			s.chunks[0].data = first.data[over:]
+			s.size -= over
+			break
+		}
+		s.chunks[0] = chunk{}
+		s.chunks = s.chunks[1:]
+		s.size -= len(first.data)
+	}
+}
+
+// Returns the chunks holding the last n lines of output, or all of it if n is negative.
+func (s *scrollback) tail(n int) []chunk {
+	if n < 0 {
+		return append([]chunk(nil), s.chunks...)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	lines := 0
+	for i := len(s.chunks) - 1; i >= 0; i-- {
+		data := s.chunks[i].data
+		for j := len(data) - 1; j >= 0; j-- {
+			if data[j] != '\n' {
+				continue
+			}
+			if i == len(s.chunks)-1 && j == len(data)-1 {
+				continue // trailing newline ends the last line, instead of starting a new one
+			}
+			lines++
+			if lines == n {
+				var tail []chunk
+				if j+1 < len(data) {
+					tail = append(tail, chunk{s.chunks[i].stderr, data[j+1:]})
+				}
+				return append(tail, s.chunks[i+1:]...)
+			}
+		}
+	}
+
+	return append([]chunk(nil), s.chunks...)
+}
@@ -0,0 +1,67 @@
+package io
+
+import (
+	"strings"
+	"testing"
+)
+
+func joined(chunks []chunk) string {
+	var b strings.Builder
+	for _, c := range chunks {
+		b.Write(c.data)
+	}
+	return b.String()
+}
+
+func TestScrollbackLimit(t *testing.T) {
+	s := newScrollback(8)
+
+	s.write(false, []byte("hello "))
+	s.write(true, []byte("world"))
+
+	if s.size != 8 {
+		t.Errorf("expected size 8, got %d", s.size)
+	}
+	if got := joined(s.tail(-1)); got != "lo world" {
+		t.Errorf("expected 'lo world', got %q", got)
+	}
+
+	chunks := s.tail(-1)
+	if chunks[0].stderr || !chunks[1].stderr {
+		t.Errorf("expected stdout then stderr chunk, got %+v", chunks)
+	}
+
+	s.write(false, []byte("a much longer line"))
+	if got := joined(s.tail(-1)); got != "ger line" {
+		t.Errorf("expected 'ger line', got %q", got)
+	}
+	if len(s.chunks) != 1 {
+		t.Errorf("expected 1 chunk, got %d", len(s.chunks))
+	}
+}
+
+func TestScrollbackTail(t *testing.T) {
+	s := newScrollback(1024)
+
+	s.write(false, []byte("one\ntwo\nthr"))
+	s.write(true, []byte("ee\nfour\n"))
+
+	cases := map[int]string{
+		-1: "one\ntwo\nthree\nfour\n",
+		0:  "",
+		1:  "four\n",
+		2:  "three\nfour\n",
+		3:  "two\nthree\nfour\n",
+		10: "one\ntwo\nthree\nfour\n",
+	}
+	for n, expected := range cases {
+		if got := joined(s.tail(n)); got != expected {
+			t.Errorf("tail(%d): expected %q, got %q", n, expected, got)
+		}
+	}
+
+	s.write(false, []byte("partial"))
+	if got := joined(s.tail(1)); got != "partial" {
+		t.Errorf("expected unterminated last line, got %q", got)
+	}
+}
@@ -15,10 +15,15 @@ const (
 	QUERY_RESP_CONTEXT_KEY
 	FREEZE_REQ_CONTEXT_KEY
 	UNFREEZE_REQ_CONTEXT_KEY
+	MIGRATE_REQ_CONTEXT_KEY
 
 	GPU_ID_CONTEXT_KEY
 	GPU_LOG_DIR_CONTEXT_KEY
 	EXIT_CODE_CHANNEL_CONTEXT_KEY
+	PRE_DUMP_CHANNEL_CONTEXT_KEY
+	LAZY_PAGES_DONE_CONTEXT_KEY
+	RESTART_CONTEXT_KEY
+	VM_SNAPSHOT_PARENT_CONTEXT_KEY
 
 	CLIENT_CONTEXT_KEY
 	PLUGIN_MANAGER_CONTEXT_KEY
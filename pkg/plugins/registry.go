@@ -17,6 +17,11 @@ var Registry = []Plugin{
 		Type:      SUPPORTED,
 		Libraries: []Binary{{Name: "libcedana-cloud-hypervisor.so"}},
 	},
+	{
+		Name:      "firecracker",
+		Type:      SUPPORTED,
+		Libraries: []Binary{{Name: "libcedana-firecracker.so"}},
+	},
 	{
 		Name:      "criu/cuda",
 		Type:      EXTERNAL,
@@ -84,6 +84,21 @@ func Clean(data *Data) {
 	data.Components = newComponents
 }
 
+// TotalIO returns the total IO of the profiling data and its components, excluding
+// redundant IO, which is already accounted for by another component.
+func TotalIO(data *Data) int64 {
+	if data == nil || data.Redundant || data.IORedundant {
+		return 0
+	}
+
+	total := data.IO
+	for _, component := range data.Components {
+		total += TotalIO(component)
+	}
+
+	return total
+}
+
 // Print prints the profiling data in a very readable format.
 func Print(data *Data, categoryColors ...map[string]text.Colors) {
 	var totalDuration time.Duration
@@ -33,9 +33,11 @@ type (
 			Stdin  io.Reader
 			Stdout io.Writer
 			Stderr io.Writer
+			TTY    *os.File // slave end of the PTY allocated by the daemon, if any
 		}
 		ExtraFiles   []*os.File
 		InheritFdMap map[string]int32
+		Cgroup       *os.File // cgroup v2 directory to start the process in, if any
 	}
 
 	Dump      = Handler[daemon.DumpReq, daemon.DumpResp]
@@ -107,6 +109,17 @@ func WaitFirstMaster[REQ any](req *REQ) bool {
 	}
 }
 
+func TTY[REQ any](req *REQ) bool {
+	switch r := any(req).(type) {
+	case *daemon.RunReq:
+		return r.TTY
+	case *daemon.RestoreReq:
+		return r.TTY
+	default:
+		panic("unsupported type for TTY extraction")
+	}
+}
+
 func Log[REQ any](req *REQ) string {
 	switch r := any(req).(type) {
 	case *daemon.RunReq:
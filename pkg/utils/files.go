@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -93,6 +95,29 @@ func SizeStr(bytes int64) string {
 	return fmt.Sprintf("%s %s", stringValue, unit)
 }
 
+// ParseSize parses a size in bytes, with an optional binary unit, e.g. "1024", "512M",
+// "1.5GiB" or "64k".
+func ParseSize(size string) (int64, error) {
+	s := strings.TrimSpace(size)
+	number := strings.TrimRight(s, "kKmMgGiIbB")
+
+	unit := strings.ToUpper(s[len(number):])
+	unit = strings.TrimSuffix(strings.TrimSuffix(unit, "B"), "I")
+
+	multiplier := map[string]float64{"": BYTE, "K": KIBIBYTE, "M": MEBIBYTE, "G": GIBIBYTE}
+	m, ok := multiplier[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in '%s'", size)
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+
+	return int64(value * m), nil
+}
+
 func CopyFile(src, dest string) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	valid := map[string]int64{
+		"1024":   1024,
+		"64k":    64 * KIBIBYTE,
+		"512M":   512 * MEBIBYTE,
+		"512MB":  512 * MEBIBYTE,
+		"1.5GiB": 3 * GIBIBYTE / 2,
+		" 2g ":   2 * GIBIBYTE,
+	}
+	for s, want := range valid {
+		got, err := ParseSize(s)
+		if err != nil {
+			t.Errorf("ParseSize(%q) failed: %v", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseSize(%q) = %d, want %d", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"", "M", "-1M", "10T", "1KM", "NaN", "ten"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q) should have failed", s)
+		}
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/moby/sys/mountinfo"
 	"github.com/rs/zerolog/log"
 	"github.com/shirou/gopsutil/v4/process"
+	"golang.org/x/sys/unix"
 )
 
 type FdInfo struct {
@@ -429,6 +430,50 @@ func PidRunning(pid uint32) bool {
 	return !slices.Contains(s, "zombie")
 }
 
+// ParseSignal parses a signal from its name (with or without the SIG prefix, in any case),
+// or its number, e.g. "SIGTERM", "term" or "15".
+func ParseSignal(s string) (syscall.Signal, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if n <= 0 || n > 64 { // real-time signals end at 64 on Linux
+			return 0, fmt.Errorf("invalid signal number %d", n)
+		}
+		return syscall.Signal(n), nil
+	}
+
+	name := strings.ToUpper(s)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	signal := unix.SignalNum(name)
+	if signal == 0 {
+		return 0, fmt.Errorf("unknown signal '%s'", s)
+	}
+
+	return signal, nil
+}
+
+// SignalProcessTree sends the signal to the process and all of its descendants
+// in the given process state, e.g. to resume a tree left stopped by CRIU.
+func SignalProcessTree(state *daemon.ProcessState, signal syscall.Signal) error {
+	if state == nil {
+		return fmt.Errorf("state is nil")
+	}
+
+	errs := []error{}
+
+	if state.GetPID() != 0 {
+		if err := syscall.Kill(int(state.GetPID()), signal); err != nil && err != syscall.ESRCH {
+			errs = append(errs, fmt.Errorf("failed to signal %d: %w", state.GetPID(), err))
+		}
+	}
+	for _, child := range state.GetChildren() {
+		errs = append(errs, SignalProcessTree(child, signal))
+	}
+
+	return errors.Join(errs...)
+}
+
 // FdInfo returns file descriptor information for the provided process and file descriptor.
 func GetFdInfo(pid uint32, fd int) (*FdInfo, error) {
 	path := fmt.Sprintf("/proc/%d/fdinfo/%d", pid, fd)
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"strconv"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -247,3 +248,29 @@ func TestFillProcessState_WithoutTree(t *testing.T) {
 		t.Error("expected state.Children to be nil when tree is false, got non-nil")
 	}
 }
+
+func TestParseSignal(t *testing.T) {
+	valid := map[string]syscall.Signal{
+		"SIGTERM": syscall.SIGTERM,
+		"term":    syscall.SIGTERM,
+		" usr1 ":  syscall.SIGUSR1,
+		"9":       syscall.SIGKILL,
+		"34":      syscall.Signal(34),
+	}
+	for s, want := range valid {
+		got, err := ParseSignal(s)
+		if err != nil {
+			t.Errorf("ParseSignal(%q) failed: %v", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseSignal(%q) = %v, want %v", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"", "SIGFOO", "0", "-1", "65"} {
+		if _, err := ParseSignal(s); err == nil {
+			t.Errorf("ParseSignal(%q) should have failed", s)
+		}
+	}
+}
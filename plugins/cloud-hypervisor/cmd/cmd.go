@@ -11,11 +11,18 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const vmType = "cloud-hypervisor"
+
+func init() {
+	DumpCmd.Flags().StringP(clh_flags.VmSocketFlag.Full, clh_flags.VmSocketFlag.Short, "", "API socket of the VM")
+	RestoreCmd.Flags().StringP(clh_flags.VmSocketFlag.Full, clh_flags.VmSocketFlag.Short, "", "API socket of the VMM to restore into")
+}
+
 var DumpCmd = &cobra.Command{
 	Use:   "cloud-hypervisor <vm-id>",
 	Short: "Dump a clh vm",
 	Long:  "Dump a cloud-hypervisor virtual machine",
-	Args:  cobra.ArbitraryArgs,
+	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		req, ok := cmd.Context().Value(keys.DUMP_REQ_CONTEXT_KEY).(*daemon.DumpVMReq)
 		if !ok {
@@ -24,12 +31,19 @@ var DumpCmd = &cobra.Command{
 
 		vmSocket, _ := cmd.Flags().GetString(clh_flags.VmSocketFlag.Full)
 
-		id := args[0]
-
-		req.Details = &daemon.Details{Kata: &kata.Kata{
-			VmSocket: vmSocket,
-			VmID:     id,
-		}}
+		req.Type = vmType
+		if req.Details == nil {
+			req.Details = &daemon.Details{}
+		}
+		if req.Details.Kata == nil {
+			req.Details.Kata = &kata.Kata{}
+		}
+		if vmSocket != "" {
+			req.Details.Kata.VmSocket = vmSocket
+		}
+		if len(args) > 0 { // not passed when called for a job
+			req.Details.Kata.VmID = args[0]
+		}
 
 		ctx := context.WithValue(cmd.Context(), keys.DUMP_REQ_CONTEXT_KEY, req)
 		cmd.SetContext(ctx)
@@ -37,3 +51,28 @@ var DumpCmd = &cobra.Command{
 		return nil
 	},
 }
+
+var RestoreCmd = &cobra.Command{
+	Use:   "cloud-hypervisor",
+	Short: "Restore a clh vm",
+	Long:  "Restore a cloud-hypervisor virtual machine into a VMM listening on the given socket",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		req, ok := cmd.Context().Value(keys.RESTORE_REQ_CONTEXT_KEY).(*daemon.RestoreVMReq)
+		if !ok {
+			return fmt.Errorf("invalid restore request in context")
+		}
+
+		vmSocket, _ := cmd.Flags().GetString(clh_flags.VmSocketFlag.Full)
+
+		req.Type = vmType
+		if vmSocket != "" {
+			req.VMSocketPath = vmSocket
+		}
+
+		ctx := context.WithValue(cmd.Context(), keys.RESTORE_REQ_CONTEXT_KEY, req)
+		cmd.SetContext(ctx)
+
+		return nil
+	},
+}
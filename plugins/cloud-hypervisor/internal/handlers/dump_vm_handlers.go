@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"strings"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"github.com/cedana/cedana/pkg/channel"
@@ -21,6 +22,10 @@ func dump(ctx context.Context, opts types.Opts, resp *daemon.DumpVMResp, req *da
 
 	snapshotter = &clh.CloudHypervisorVM{}
 
+	if req.GetDiff() {
+		return nil, status.Errorf(codes.Unimplemented, "diff snapshots are not supported for cloud-hypervisor")
+	}
+
 	err = snapshotter.Pause(req.Details.Kata.VmSocket)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Checkpoint task failed: %v", err)
@@ -47,5 +52,8 @@ func dump(ctx context.Context, opts types.Opts, resp *daemon.DumpVMResp, req *da
 		return nil, status.Errorf(codes.Internal, "Failed to get PID: %v", err)
 	}
 
+	resp.PID = pid
+	resp.TarDumpDir = strings.TrimPrefix(req.Dir, "file://")
+
 	return channel.Broadcaster(utils.WaitForPidCtx(opts.Lifetime, pid)), nil
 }
@@ -31,39 +31,52 @@ func restore(ctx context.Context, opts types.Opts, resp *daemon.RestoreVMResp, r
 
 	snapshotter = &clh.CloudHypervisorVM{}
 
+	if req.GetDiff() {
+		return nil, status.Errorf(codes.Unimplemented, "diff snapshots are not supported for cloud-hypervisor")
+	}
+
 	if len(requestIDs) > 1 {
 		return nil, status.Errorf(codes.Internal, "Restore vm task failed: more than one request ID provided, we do not support this yet")
 	}
 
-	requestID := requestIDs[0]
-	var id string
+	// Network FDs are only handed over by the runtime (e.g. kata) for sandboxes,
+	// a standalone VM can be restored without any.
 
-	opts.FdStore.Range(func(key, value any) bool {
-		id = key.(string) // Adjust the type to match the actual key type
+	if len(requestIDs) == 1 {
+		requestID := requestIDs[0]
+		var id string
 
-		if requestID != id {
-			return true
-		}
+		opts.FdStore.Range(func(key, value any) bool {
+			id = key.(string) // Adjust the type to match the actual key type
 
-		netFds = value.([]int) // Adjust the type to match the actual value type
+			if requestID != id {
+				return true
+			}
 
-		log.Logger.Info().Msgf("Request ID: %v, FDs: %v\n", requestID, netFds)
+			netFds = value.([]int) // Adjust the type to match the actual value type
 
-		return false
-	})
+			log.Logger.Info().Msgf("Request ID: %v, FDs: %v\n", requestID, netFds)
 
-	if id == "" {
-		return nil, status.Errorf(codes.Internal, "Restore task failed: request ID not found in FD store")
-	}
+			return false
+		})
+
+		if id == "" {
+			return nil, status.Errorf(codes.Internal, "Restore task failed: request ID not found in FD store")
+		}
+
+		opts.FdStore.Delete(id)
 
-	opts.FdStore.Delete(id)
+		netFdsInt64 = make([]int64, len(netFds))
+		for i, fd := range netFds {
+			netFdsInt64[i] = int64(fd)
+		}
 
-	netFdsInt64 = make([]int64, len(netFds))
-	for i, fd := range netFds {
-		netFdsInt64[i] = int64(fd)
+		if len(restoredNetConfig) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Restore task failed: no net config provided for request ID %s", requestID)
+		}
+		restoredNetConfig[0].Fds = netFdsInt64
 	}
 
-	restoredNetConfig[0].Fds = netFdsInt64
 	err = snapshotter.Restore(snapshot, socketPath, restoredNetConfig)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Restore task failed during vmSnapshotter Restore: %v", err)
@@ -85,5 +98,7 @@ func restore(ctx context.Context, opts types.Opts, resp *daemon.RestoreVMResp, r
 		}
 	}()
 
+	resp.PID = pid
+
 	return channel.Broadcaster(utils.WaitForPidCtx(opts.Lifetime, pid)), nil
 }
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	utils "github.com/cedana/cedana/plugins/cloud-hypervisor/pkg/utils"
+	"github.com/cedana/cedana/plugins/cloud-hypervisor/pkg/vm"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 )
@@ -161,16 +162,21 @@ func (u *CloudHypervisorVM) Restore(snapshotPath, vmSocketPath string, netConfig
 	}()
 
 	var fds []int
-	for _, fd := range data.NetFDs[0].Fds {
-		fds = append(fds, int(fd))
-
-		file := os.NewFile(uintptr(fd), fmt.Sprintf("fd-%d", fd))
-		if file != nil {
-			files = append(files, file)
+	for _, netFD := range data.NetFDs {
+		for _, fd := range netFD.Fds {
+			fds = append(fds, int(fd))
+
+			file := os.NewFile(uintptr(fd), fmt.Sprintf("fd-%d", fd))
+			if file != nil {
+				files = append(files, file)
+			}
 		}
 	}
 
-	oob := syscall.UnixRights(fds...)
+	var oob []byte
+	if len(fds) > 0 {
+		oob = syscall.UnixRights(fds...)
+	}
 	payloadn, oobn, err := conn.WriteMsgUnix([]byte(payload), oob, nil)
 	if err != nil {
 		return err
@@ -261,9 +267,56 @@ func (u *CloudHypervisorVM) Resume(vmSocketPath string) error {
 	return nil
 }
 
+type PingResponse struct {
+	BuildVersion string `json:"build_version"`
+	Version      string `json:"version"`
+	PID          int64  `json:"pid"`
+}
+
+// GetPID returns the PID of the Cloud Hypervisor process serving the API socket.
+// Newer versions report it in the vmm.ping response, for older ones we fall back
+// to the credentials of the socket owner.
 func (u *CloudHypervisorVM) GetPID(vmSocketPath string) (uint32, error) {
-	// TODO BS unimplemented
-	return 0, nil
+	ping, err := u.Ping(vmSocketPath)
+	if err == nil && ping.PID > 0 {
+		return uint32(ping.PID), nil
+	}
+
+	pid, ownerErr := vm.SocketOwnerPID(vmSocketPath)
+	if ownerErr != nil {
+		return 0, fmt.Errorf("failed to get PID from ping (%v) or socket owner: %w", err, ownerErr)
+	}
+
+	return pid, nil
+}
+
+func (u *CloudHypervisorVM) Ping(vmSocketPath string) (*PingResponse, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", vmSocketPath)
+			},
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	resp, err := client.Get("http://localhost/api/v1/vmm.ping")
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute ping request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("error pinging VMM: %d, %v", resp.StatusCode, string(respBody))
+	}
+
+	ping := &PingResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(ping); err != nil {
+		return nil, fmt.Errorf("failed to decode ping response: %w", err)
+	}
+
+	return ping, nil
 }
 
 // NewUnixSocketVMSnapshot creates a new UnixSocketVMSnapshot with the given socket path
@@ -0,0 +1,40 @@
+package vm
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// SocketOwnerPID returns the PID of the process listening on a VMM API socket,
+// using the peer credentials of a connection to it.
+func SocketOwnerPID(socketPath string) (uint32, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	raw, err := conn.(*net.UnixConn).SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if credErr != nil {
+		return 0, fmt.Errorf("failed to get peer credentials: %w", credErr)
+	}
+	if cred.Pid <= 0 {
+		return 0, fmt.Errorf("invalid peer PID %d", cred.Pid)
+	}
+
+	return uint32(cred.Pid), nil
+}
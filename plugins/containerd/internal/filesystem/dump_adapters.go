@@ -20,7 +20,9 @@ import (
 	"github.com/containerd/errdefs"
 	"github.com/containerd/platforms"
 	"github.com/opencontainers/image-spec/identity"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/afero"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -78,7 +80,7 @@ func DumpRootfs(next types.Dump) types.Dump {
 
 			_, end := profiling.StartTimingCategory(ctx, "rootfs", dumpRootfs)
 			defer end()
-			err = dumpRootfs(ctx, client, container, image.Name, image.Username, image.Secret)
+			_, err = dumpRootfs(ctx, client, container, image.Name, image.Username, image.Secret)
 			if err != nil {
 				return nil, status.Errorf(codes.Internal, "failed to dump rootfs: %v", err)
 			}
@@ -89,18 +91,30 @@ func DumpRootfs(next types.Dump) types.Dump {
 		// When doing a full dump, we instead start a rootfs dump async with CRIU and wait for it to finish
 
 		rootfsErr := make(chan error, 1)
+		var rootfsDiff v1.Descriptor
+		dumpFs := opts.DumpFs
 
 		opts.CRIUCallback.Include(&criu.NotifyCallback{
 			Name: "rootfs",
 			PreDumpFunc: func(ctx context.Context, opts *criu_proto.CriuOpts) error {
 				go func() {
-					rootfsErr <- dumpRootfs(ctx, client, container, image.Name, image.Username, image.Secret)
+					var err error
+					rootfsDiff, err = dumpRootfs(ctx, client, container, image.Name, image.Username, image.Secret)
+					rootfsErr <- err
 					close(rootfsErr)
 				}()
 				return nil
 			},
 			PostDumpFunc: func(ctx context.Context, opts *criu_proto.CriuOpts) error {
-				return <-rootfsErr
+				if err := <-rootfsErr; err != nil {
+					return err
+				}
+				// Only a reference to the diff is saved with the dump, for export
+				return saveRootfsDiffRef(&RootfsDiffRef{
+					Address:    details.Address,
+					Namespace:  details.Namespace,
+					Descriptor: rootfsDiff,
+				}, dumpFs)
 			},
 			FinalizeDumpFunc: func(ctx context.Context, opts *criu_proto.CriuOpts, criuErr error) error {
 				return <-rootfsErr
@@ -182,7 +196,8 @@ func DumpSnapshotter(next types.Dump) types.Dump {
 	}
 }
 
-func dumpRootfs(ctx context.Context, client *containerd.Client, container containerd.Container, ref, username, secret string) (err error) {
+// Dumps the container's rootfs as a new image, and returns the descriptor of the diff layer.
+func dumpRootfs(ctx context.Context, client *containerd.Client, container containerd.Container, ref, username, secret string) (diff v1.Descriptor, err error) {
 	log.Info().Str("ref", ref).Str("container", container.ID()).Msg("rootfs dump started")
 	defer func() {
 		if err != nil {
@@ -194,18 +209,18 @@ func dumpRootfs(ctx context.Context, client *containerd.Client, container contai
 
 	info, err := container.Info(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get container info: %v", err)
+		return diff, fmt.Errorf("failed to get container info: %v", err)
 	}
 
 	baseImgNoPlatform, err := client.ImageService().Get(ctx, info.Image)
 	if err != nil {
-		return fmt.Errorf("failed to get container base image: %v", err)
+		return diff, fmt.Errorf("failed to get container base image: %v", err)
 	}
 
 	platformLabel := platforms.DefaultString()
 	ocispecPlatform, err := platforms.Parse(platformLabel)
 	if err != nil {
-		return err
+		return diff, err
 	}
 	platform := platforms.Only(ocispecPlatform)
 
@@ -213,7 +228,7 @@ func dumpRootfs(ctx context.Context, client *containerd.Client, container contai
 
 	baseImgConfig, _, err := readImageConfig(ctx, baseImg)
 	if err != nil {
-		return err
+		return diff, err
 	}
 
 	var (
@@ -224,22 +239,22 @@ func dumpRootfs(ctx context.Context, client *containerd.Client, container contai
 
 	diffLayerDesc, diffID, err := createDiff(ctx, info.SnapshotKey, contentStore, snapshotter, differ)
 	if err != nil {
-		return fmt.Errorf("failed to export layer: %w", err)
+		return diff, fmt.Errorf("failed to export layer: %w", err)
 	}
 
 	imageConfig, err := generateCommitImageConfig(ctx, container, baseImgConfig, diffID)
 	if err != nil {
-		return fmt.Errorf("failed to generate commit image config: %w", err)
+		return diff, fmt.Errorf("failed to generate commit image config: %w", err)
 	}
 	rootfsID := identity.ChainID(imageConfig.RootFS.DiffIDs).String()
 
 	if err := applyDiffLayer(ctx, rootfsID, baseImgConfig, snapshotter, differ, diffLayerDesc); err != nil {
-		return fmt.Errorf("failed to apply diff: %w", err)
+		return diff, fmt.Errorf("failed to apply diff: %w", err)
 	}
 
 	commitManifestDesc, _, err := writeContentsForImage(ctx, info.Snapshotter, baseImg, imageConfig, diffLayerDesc)
 	if err != nil {
-		return err
+		return diff, err
 	}
 
 	img := images.Image{
@@ -250,11 +265,11 @@ func dumpRootfs(ctx context.Context, client *containerd.Client, container contai
 
 	if _, err := client.ImageService().Update(ctx, img); err != nil {
 		if !errdefs.IsNotFound(err) {
-			return err
+			return diff, err
 		}
 
 		if _, err := client.ImageService().Create(ctx, img); err != nil {
-			return fmt.Errorf("failed to create new image %s: %w", ref, err)
+			return diff, fmt.Errorf("failed to create new image %s: %w", ref, err)
 		}
 	}
 
@@ -264,7 +279,7 @@ func dumpRootfs(ctx context.Context, client *containerd.Client, container contai
 		username, secret, err = readDockerConfig(ref)
 		if err != nil {
 			log.Warn().Msgf("failed to read docker config: %v", err)
-			return nil
+			return diffLayerDesc, nil
 		}
 	}
 
@@ -274,6 +289,25 @@ func dumpRootfs(ctx context.Context, client *containerd.Client, container contai
 		log.Info().Msgf("pushed image %s successful", ref)
 	}
 
+	return diffLayerDesc, nil
+}
+
+// Saves the reference to the rootfs diff layer to the dump directory.
+func saveRootfsDiffRef(ref *RootfsDiffRef, dumpFs afero.Fs) error {
+	if dumpFs == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return err
+	}
+	if err := afero.WriteFile(dumpFs, containerd_keys.DUMP_ROOTFS_DIFF_KEY, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write rootfs diff reference: %w", err)
+	}
+
+	log.Debug().Str("digest", ref.Descriptor.Digest.String()).Int64("size", ref.Descriptor.Size).Msg("saved rootfs diff reference")
+
 	return nil
 }
 
@@ -0,0 +1,76 @@
+package filesystem
+
+// Export of the container rootfs diff of a checkpoint, from the containerd content store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	containerd_keys "github.com/cedana/cedana/plugins/containerd/pkg/keys"
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/errdefs"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/rs/zerolog/log"
+)
+
+// Reference to the rootfs diff layer of a dump, in the content store of a containerd
+type RootfsDiffRef struct {
+	Address    string        `json:"address"`
+	Namespace  string        `json:"namespace"`
+	Descriptor v1.Descriptor `json:"descriptor"`
+}
+
+// ExportRootfs writes the rootfs diff layer of the checkpoint in the images directory to
+// the directory, fetched from the content store it was saved to on dump. Does nothing if
+// the checkpoint has no rootfs.
+func ExportRootfs(ctx context.Context, imagesDirectory string, dir string) error {
+	data, err := os.ReadFile(filepath.Join(imagesDirectory, containerd_keys.DUMP_ROOTFS_DIFF_KEY))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read rootfs diff reference: %w", err)
+	}
+
+	ref := &RootfsDiffRef{}
+	if err := json.Unmarshal(data, ref); err != nil {
+		return fmt.Errorf("invalid rootfs diff reference: %w", err)
+	}
+
+	ctx = namespaces.WithNamespace(ctx, ref.Namespace)
+
+	client, err := containerd.New(ref.Address, containerd.WithDefaultNamespace(ref.Namespace))
+	if err != nil {
+		return fmt.Errorf("failed to create containerd client: %w", err)
+	}
+	defer client.Close()
+
+	reader, err := client.ContentStore().ReaderAt(ctx, ref.Descriptor)
+	if errdefs.IsNotFound(err) {
+		return fmt.Errorf("rootfs diff %s is no longer in the content store, e.g. if its image was updated by a later dump and pruned", ref.Descriptor.Digest)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open rootfs diff: %w", err)
+	}
+	defer reader.Close()
+
+	file, err := os.Create(filepath.Join(dir, containerd_keys.EXPORT_ROOTFS_DIFF_NAME))
+	if err != nil {
+		return fmt.Errorf("failed to create rootfs diff file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, content.NewReader(reader)); err != nil {
+		return fmt.Errorf("failed to write rootfs diff file: %w", err)
+	}
+
+	log.Debug().Str("digest", ref.Descriptor.Digest.String()).Int64("size", ref.Descriptor.Size).Msg("exported rootfs diff")
+
+	return nil
+}
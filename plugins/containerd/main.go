@@ -86,3 +86,5 @@ var (
 		client.SetAdditionalEnv[daemon.RestoreReq, daemon.RestoreResp],
 	}
 )
+
+var ExportRootfs = filesystem.ExportRootfs
@@ -5,4 +5,9 @@ const (
 	DUMP_SNAPSHOT_KEY    = "containerd.snapshot"
 	DUMP_SNAPSHOTTER_KEY = "containerd.snapshotter"
 	DUMP_RUNTIME_KEY     = "containerd.runtime"
+
+	// Reference to the rootfs diff layer in the containerd content store, from which
+	// it's fetched on export, named *.rootfs.tar.gz so it's exported as a separate layer
+	DUMP_ROOTFS_DIFF_KEY    = "containerd.rootfs.json"
+	EXPORT_ROOTFS_DIFF_NAME = "containerd.rootfs.tar.gz"
 )
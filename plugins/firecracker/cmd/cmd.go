@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/plugins/kata"
+	"github.com/cedana/cedana/pkg/keys"
+	fc_flags "github.com/cedana/cedana/plugins/firecracker/pkg/flags"
+	"github.com/spf13/cobra"
+)
+
+const vmType = "firecracker"
+
+func init() {
+	DumpCmd.Flags().StringP(fc_flags.VmSocketFlag.Full, fc_flags.VmSocketFlag.Short, "", "API socket of the VM")
+	DumpCmd.Flags().BoolP(fc_flags.DiffFlag.Full, fc_flags.DiffFlag.Short, false, "only dump memory dirtied since the job's last checkpoint, into a new directory (needs dirty page tracking)")
+	RestoreCmd.Flags().StringP(fc_flags.VmSocketFlag.Full, fc_flags.VmSocketFlag.Short, "", "API socket of the VMM to restore into")
+	RestoreCmd.Flags().BoolP(fc_flags.DiffFlag.Full, fc_flags.DiffFlag.Short, false, "enable dirty page tracking in the restored VM, so that diff snapshots can be taken of it")
+}
+
+var DumpCmd = &cobra.Command{
+	Use:   "firecracker <vm-id>",
+	Short: "Dump a firecracker vm",
+	Long:  "Dump a firecracker microVM, with a full or diff snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		req, ok := cmd.Context().Value(keys.DUMP_REQ_CONTEXT_KEY).(*daemon.DumpVMReq)
+		if !ok {
+			return fmt.Errorf("invalid dump request in context")
+		}
+
+		vmSocket, _ := cmd.Flags().GetString(fc_flags.VmSocketFlag.Full)
+		diff, _ := cmd.Flags().GetBool(fc_flags.DiffFlag.Full)
+
+		req.Type = vmType
+		req.Diff = diff
+		if req.Details == nil {
+			req.Details = &daemon.Details{}
+		}
+		if req.Details.Kata == nil {
+			req.Details.Kata = &kata.Kata{}
+		}
+		if vmSocket != "" {
+			req.Details.Kata.VmSocket = vmSocket
+		}
+		if len(args) > 0 { // not passed when called for a job
+			req.Details.Kata.VmID = args[0]
+		}
+
+		ctx := context.WithValue(cmd.Context(), keys.DUMP_REQ_CONTEXT_KEY, req)
+		cmd.SetContext(ctx)
+
+		return nil
+	},
+}
+
+var RestoreCmd = &cobra.Command{
+	Use:   "firecracker",
+	Short: "Restore a firecracker vm",
+	Long:  "Restore a firecracker microVM into a fresh firecracker process listening on the given socket",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		req, ok := cmd.Context().Value(keys.RESTORE_REQ_CONTEXT_KEY).(*daemon.RestoreVMReq)
+		if !ok {
+			return fmt.Errorf("invalid restore request in context")
+		}
+
+		vmSocket, _ := cmd.Flags().GetString(fc_flags.VmSocketFlag.Full)
+		diff, _ := cmd.Flags().GetBool(fc_flags.DiffFlag.Full)
+
+		req.Type = vmType
+		req.Diff = diff
+		if vmSocket != "" {
+			req.VMSocketPath = vmSocket
+		}
+
+		ctx := context.WithValue(cmd.Context(), keys.RESTORE_REQ_CONTEXT_KEY, req)
+		cmd.SetContext(ctx)
+
+		return nil
+	},
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/pkg/channel"
+	"github.com/cedana/cedana/pkg/keys"
+	"github.com/cedana/cedana/pkg/types"
+	"github.com/cedana/cedana/pkg/utils"
+	"github.com/cedana/cedana/plugins/cloud-hypervisor/pkg/vm"
+	"github.com/cedana/cedana/plugins/firecracker/pkg/firecracker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var Dump types.DumpVM = dump
+
+// Returns a VM dump handler for the server
+func dump(ctx context.Context, opts types.Opts, resp *daemon.DumpVMResp, req *daemon.DumpVMReq) (code func() <-chan int, err error) {
+	var snapshotter vm.Snapshotter
+
+	// The base of a diff snapshot is resolved from the job's checkpoints
+	parent, _ := ctx.Value(keys.VM_SNAPSHOT_PARENT_CONTEXT_KEY).(string)
+
+	snapshotter = &firecracker.FirecrackerVM{Diff: req.GetDiff(), Parent: parent}
+
+	socket := req.GetDetails().GetKata().GetVmSocket()
+	if socket == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing VM socket")
+	}
+
+	err = snapshotter.Pause(socket)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Checkpoint task failed: %v", err)
+	}
+
+	// Always resume, even if the snapshot failed
+
+	err = snapshotter.Snapshot(req.Dir, socket, req.GetDetails().GetKata().GetVmID())
+	if resumeErr := snapshotter.Resume(socket); resumeErr != nil {
+		return nil, status.Errorf(codes.Internal, "Checkpoint task failed during resume: %v", resumeErr)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Checkpoint task failed during snapshot: %v", err)
+	}
+
+	pid, err := snapshotter.GetPID(socket)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get PID: %v", err)
+	}
+
+	resp.PID = pid
+	resp.TarDumpDir = strings.TrimPrefix(req.Dir, "file://")
+
+	return channel.Broadcaster(utils.WaitForPidCtx(opts.Lifetime, pid)), nil
+}
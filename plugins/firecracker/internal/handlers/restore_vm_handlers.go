@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/pkg/channel"
+	"github.com/cedana/cedana/pkg/types"
+	"github.com/cedana/cedana/pkg/utils"
+	"github.com/cedana/cedana/plugins/cloud-hypervisor/pkg/vm"
+	"github.com/cedana/cedana/plugins/firecracker/pkg/firecracker"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var Restore types.RestoreVM = restore
+
+// Returns a VM restore handler for the server
+func restore(ctx context.Context, opts types.Opts, resp *daemon.RestoreVMResp, req *daemon.RestoreVMReq) (code func() <-chan int, err error) {
+	var snapshotter vm.Snapshotter
+	var netFds []int
+
+	snapshotter = &firecracker.FirecrackerVM{Diff: req.GetDiff()}
+
+	socketPath := req.GetVMSocketPath()
+	restoredNetConfig := req.GetRestoredNetConfig()
+	requestIDs := req.GetRequestIDs()
+
+	if len(requestIDs) > 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "Restore vm task failed: more than one request ID provided, we do not support this yet")
+	}
+
+	// Tap device FDs are handed over by the runtime (e.g. kata) under the request ID,
+	// a standalone VM is restored with the tap devices it was snapshotted with.
+
+	if len(requestIDs) == 1 {
+		value, ok := opts.FdStore.LoadAndDelete(requestIDs[0])
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "Restore task failed: request ID not found in FD store")
+		}
+		netFds = value.([]int)
+
+		log.Debug().Str("request", requestIDs[0]).Ints("fds", netFds).Msg("using network FDs from store")
+
+		if len(restoredNetConfig) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Restore task failed: no net config provided for request ID %s", requestIDs[0])
+		}
+		restoredNetConfig[0].Fds = make([]int64, len(netFds))
+		for i, fd := range netFds {
+			restoredNetConfig[0].Fds[i] = int64(fd)
+		}
+	}
+
+	defer func() {
+		for _, fd := range netFds {
+			unix.Close(fd)
+		}
+	}()
+
+	err = snapshotter.Restore(req.GetVMSnapshotPath(), socketPath, restoredNetConfig)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Restore task failed during snapshot load: %v", err)
+	}
+
+	err = snapshotter.Resume(socketPath)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Restore task failed during resume: %v", err)
+	}
+
+	pid, err := snapshotter.GetPID(socketPath)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get PID: %v", err)
+	}
+
+	resp.PID = pid
+
+	return channel.Broadcaster(utils.WaitForPidCtx(opts.Lifetime, pid)), nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/cedana/cedana/pkg/types"
+	"github.com/cedana/cedana/plugins/firecracker/cmd"
+	"github.com/cedana/cedana/plugins/firecracker/internal/handlers"
+	"github.com/spf13/cobra"
+)
+
+///////////////////////////
+//// Exported Features ////
+///////////////////////////
+
+// loaded from ldflag definitions
+var Version string = "dev"
+
+var (
+	DumpVMCmd    *cobra.Command = cmd.DumpCmd
+	RestoreVMCmd *cobra.Command = cmd.RestoreCmd
+)
+
+var (
+	DumpVMHandler       types.DumpVM                      = handlers.Dump
+	RestoreVMHandler    types.RestoreVM                   = handlers.Restore
+	DumpVMMiddleware    types.Middleware[types.DumpVM]    = types.Middleware[types.DumpVM]{}
+	RestoreVMMiddleware types.Middleware[types.RestoreVM] = types.Middleware[types.RestoreVM]{}
+)
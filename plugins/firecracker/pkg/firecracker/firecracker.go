@@ -0,0 +1,402 @@
+package firecracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"github.com/cedana/cedana/plugins/cloud-hypervisor/pkg/vm"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	STATE_FILE         = "vmstate"
+	MEMORY_FILE        = "memory"
+	MERGED_MEMORY_FILE = "memory.merged"
+	PARENT_FILE        = "parent" // of a diff snapshot, the directory of its base, relative to its own
+
+	API_TIMEOUT = 20 * time.Minute
+)
+
+type SnapshotType string
+
+const (
+	SNAPSHOT_FULL SnapshotType = "Full"
+	SNAPSHOT_DIFF SnapshotType = "Diff"
+)
+
+type FirecrackerVM struct {
+	// Diff snapshots only contain the memory pages dirtied since the previous snapshot,
+	// and require the VM to have been started with dirty page tracking. The memory file
+	// of a diff snapshot must be layered over that of its base before it can be restored.
+	// On restore, enables dirty page tracking so that diff snapshots can be taken after.
+	Diff bool
+
+	// Directory of the snapshot a diff snapshot is taken on top of, i.e. the last one
+	// taken or restored of the VM.
+	Parent string
+}
+
+var _ vm.Snapshotter = &FirecrackerVM{}
+
+type SnapshotCreateParams struct {
+	SnapshotType SnapshotType `json:"snapshot_type"`
+	SnapshotPath string       `json:"snapshot_path"`
+	MemFilePath  string       `json:"mem_file_path"`
+}
+
+type MemoryBackend struct {
+	BackendType string `json:"backend_type"`
+	BackendPath string `json:"backend_path"`
+}
+
+type NetworkOverride struct {
+	IfaceID     string `json:"iface_id"`
+	HostDevName string `json:"host_dev_name"`
+}
+
+type SnapshotLoadParams struct {
+	SnapshotPath        string            `json:"snapshot_path"`
+	MemBackend          *MemoryBackend    `json:"mem_backend"`
+	EnableDiffSnapshots bool              `json:"enable_diff_snapshots"`
+	ResumeVM            bool              `json:"resume_vm"`
+	NetworkOverrides    []NetworkOverride `json:"network_overrides,omitempty"`
+}
+
+type VMState struct {
+	State string `json:"state"`
+}
+
+type Fault struct {
+	FaultMessage string `json:"fault_message"`
+}
+
+func (f *FirecrackerVM) Snapshot(destinationURL, vmSocketPath, vmID string) error {
+	dir, err := filepath.Abs(strings.TrimPrefix(destinationURL, "file://"))
+	if err != nil {
+		return fmt.Errorf("invalid destination directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	snapshotType := SNAPSHOT_FULL
+	var parent string
+	if f.Diff {
+		snapshotType = SNAPSHOT_DIFF
+
+		if f.Parent == "" {
+			return fmt.Errorf("no previous snapshot of the VM to take a diff snapshot on, take a full snapshot first")
+		}
+		base, err := filepath.Abs(strings.TrimPrefix(f.Parent, "file://"))
+		if err != nil {
+			return fmt.Errorf("invalid base snapshot directory: %w", err)
+		}
+		if base == dir {
+			return fmt.Errorf("diff snapshot must be taken into a different directory than its base %s", dir)
+		}
+		// Relative, so that the snapshots can be moved together
+		parent, err = filepath.Rel(dir, base)
+		if err != nil {
+			return fmt.Errorf("invalid base snapshot directory: %w", err)
+		}
+	}
+
+	data := SnapshotCreateParams{
+		SnapshotType: snapshotType,
+		SnapshotPath: filepath.Join(dir, STATE_FILE),
+		MemFilePath:  filepath.Join(dir, MEMORY_FILE),
+	}
+
+	err = request(vmSocketPath, http.MethodPut, "/snapshot/create", data)
+	if err != nil {
+		return fmt.Errorf("error snapshotting vm: %w", err)
+	}
+
+	if parent != "" {
+		err = os.WriteFile(filepath.Join(dir, PARENT_FILE), []byte(parent), 0o644)
+	} else {
+		err = os.Remove(filepath.Join(dir, PARENT_FILE))
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record base snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Restore loads the snapshot into a fresh Firecracker process listening on the socket.
+// Network devices of the snapshot are pointed to the tap devices of the given FDs, as
+// the ones it was taken with are usually gone. A diff snapshot is first merged onto its
+// bases, and cannot be restored without them.
+func (f *FirecrackerVM) Restore(snapshotPath, vmSocketPath string, netConfigs []*daemon.RestoredNetConfig) error {
+	dir := strings.TrimPrefix(snapshotPath, "file://")
+
+	overrides, err := networkOverrides(netConfigs)
+	if err != nil {
+		return err
+	}
+
+	memory, err := memoryFile(dir)
+	if err != nil {
+		return err
+	}
+
+	data := SnapshotLoadParams{
+		SnapshotPath: filepath.Join(dir, STATE_FILE),
+		MemBackend: &MemoryBackend{
+			BackendType: "File",
+			BackendPath: memory,
+		},
+		EnableDiffSnapshots: f.Diff,
+		NetworkOverrides:    overrides,
+	}
+
+	if err := request(vmSocketPath, http.MethodPut, "/snapshot/load", data); err != nil {
+		return fmt.Errorf("error restoring vm: %w", err)
+	}
+
+	return nil
+}
+
+func (f *FirecrackerVM) Pause(vmSocketPath string) error {
+	if err := request(vmSocketPath, http.MethodPatch, "/vm", VMState{State: "Paused"}); err != nil {
+		return fmt.Errorf("error pausing VM: %w", err)
+	}
+	return nil
+}
+
+func (f *FirecrackerVM) Resume(vmSocketPath string) error {
+	if err := request(vmSocketPath, http.MethodPatch, "/vm", VMState{State: "Resumed"}); err != nil {
+		return fmt.Errorf("error resuming VM: %w", err)
+	}
+	return nil
+}
+
+// GetPID returns the PID of the Firecracker process serving the API socket. The API
+// does not report it, so we use the credentials of the socket owner.
+func (f *FirecrackerVM) GetPID(vmSocketPath string) (uint32, error) {
+	return vm.SocketOwnerPID(vmSocketPath)
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Sends a request to the Firecracker API, which replies with no content on success,
+// and a fault message otherwise.
+func request(vmSocketPath, method, path string, data any) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request data: %w", err)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", vmSocketPath)
+			},
+		},
+		Timeout: API_TIMEOUT,
+	}
+
+	req, err := http.NewRequest(method, "http://localhost"+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	fault := Fault{}
+	if json.Unmarshal(respBody, &fault) == nil && fault.FaultMessage != "" {
+		return fmt.Errorf("%d: %s", resp.StatusCode, fault.FaultMessage)
+	}
+
+	return fmt.Errorf("%d: %s", resp.StatusCode, string(respBody))
+}
+
+// Returns the memory file to load for the snapshot in the directory. For a diff snapshot,
+// merges it onto the memory of its bases, down to the full snapshot they start from.
+func memoryFile(dir string) (string, error) {
+	chain := []string{filepath.Clean(dir)}
+	for {
+		parent, err := os.ReadFile(filepath.Join(chain[len(chain)-1], PARENT_FILE))
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read base of diff snapshot: %w", err)
+		}
+		base := string(parent)
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(chain[len(chain)-1], base)
+		}
+		base = filepath.Clean(base)
+		if slices.Contains(chain, base) {
+			return "", fmt.Errorf("diff snapshot %s is its own base", base)
+		}
+		chain = append(chain, base)
+	}
+
+	if len(chain) == 1 {
+		return filepath.Join(dir, MEMORY_FILE), nil
+	}
+
+	for _, snapshot := range chain[1:] {
+		if _, err := os.Stat(filepath.Join(snapshot, MEMORY_FILE)); err != nil {
+			return "", fmt.Errorf("base %s of diff snapshot %s is missing: %w", snapshot, dir, err)
+		}
+	}
+
+	merged := filepath.Join(dir, MERGED_MEMORY_FILE)
+
+	err := copyFile(filepath.Join(chain[len(chain)-1], MEMORY_FILE), merged)
+	if err != nil {
+		return "", fmt.Errorf("failed to copy base memory: %w", err)
+	}
+	for i := len(chain) - 2; i >= 0; i-- {
+		err = mergeDiff(filepath.Join(chain[i], MEMORY_FILE), merged)
+		if err != nil {
+			os.Remove(merged)
+			return "", fmt.Errorf("failed to merge diff snapshot %s: %w", chain[i], err)
+		}
+	}
+
+	return merged, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Writes the dirty pages of the diff memory file onto the memory file. The diff is a
+// sparse file, with only the dirty pages written, so only its data regions are copied.
+func mergeDiff(diff, memory string) error {
+	in, err := os.Open(diff)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(memory, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	info, err := in.Stat()
+	if err != nil {
+		out.Close()
+		return err
+	}
+	size := info.Size()
+
+	fd := int(in.Fd())
+	for offset := int64(0); offset < size; {
+		start, err := unix.Seek(fd, offset, unix.SEEK_DATA)
+		if errors.Is(err, unix.ENXIO) {
+			break // no more data
+		}
+		if err != nil {
+			out.Close()
+			return err
+		}
+		end, err := unix.Seek(fd, start, unix.SEEK_HOLE)
+		if err != nil {
+			out.Close()
+			return err
+		}
+
+		_, err = io.Copy(io.NewOffsetWriter(out, start), io.NewSectionReader(in, start, end-start))
+		if err != nil {
+			out.Close()
+			return err
+		}
+
+		offset = end
+	}
+
+	return out.Close()
+}
+
+// Maps each network config to the tap device behind its FD, as Firecracker takes
+// device names instead of FDs.
+func networkOverrides(netConfigs []*daemon.RestoredNetConfig) ([]NetworkOverride, error) {
+	var overrides []NetworkOverride
+
+	for _, netConfig := range netConfigs {
+		fds := netConfig.GetFds()
+		if len(fds) == 0 {
+			continue
+		}
+		if len(fds) > 1 {
+			return nil, fmt.Errorf("multi-queue network device %s is not supported", netConfig.GetID())
+		}
+
+		name, err := tapName(int(fds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tap device for network device %s: %w", netConfig.GetID(), err)
+		}
+
+		overrides = append(overrides, NetworkOverride{
+			IfaceID:     netConfig.GetID(),
+			HostDevName: name,
+		})
+	}
+
+	return overrides, nil
+}
+
+func tapName(fd int) (string, error) {
+	ifr, err := unix.NewIfreq("")
+	if err != nil {
+		return "", err
+	}
+	if err := unix.IoctlIfreq(fd, unix.TUNGETIFF, ifr); err != nil {
+		return "", err
+	}
+	return ifr.Name(), nil
+}
@@ -0,0 +1,313 @@
+package firecracker
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+)
+
+type call struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+// stubAPI serves a minimal Firecracker API on a UNIX socket, recording every call.
+// Calls to paths in `faults` are answered with a fault message.
+func stubAPI(t *testing.T, faults map[string]string) (socket string, calls func() []call) {
+	t.Helper()
+
+	socket = filepath.Join(t.TempDir(), "fc.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", socket, err)
+	}
+
+	var mu sync.Mutex
+	var recorded []call
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		recorded = append(recorded, call{r.Method, r.URL.Path, string(body)})
+		mu.Unlock()
+
+		if msg, ok := faults[r.URL.Path]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Fault{FaultMessage: msg})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	return socket, func() []call {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]call(nil), recorded...)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	tests := []struct {
+		name string
+		diff bool
+		want SnapshotType
+	}{
+		{"full", false, SNAPSHOT_FULL},
+		{"diff", true, SNAPSHOT_DIFF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socket, calls := stubAPI(t, nil)
+			dir := filepath.Join(t.TempDir(), "dump")
+			base := filepath.Join(t.TempDir(), "base")
+			fc := &FirecrackerVM{Diff: tt.diff, Parent: base}
+
+			if err := fc.Pause(socket); err != nil {
+				t.Fatalf("pause: %v", err)
+			}
+			if err := fc.Snapshot("file://"+dir, socket, "vm"); err != nil {
+				t.Fatalf("snapshot: %v", err)
+			}
+			if err := fc.Resume(socket); err != nil {
+				t.Fatalf("resume: %v", err)
+			}
+
+			if _, err := os.Stat(dir); err != nil {
+				t.Errorf("expected snapshot directory to be created: %v", err)
+			}
+
+			got := calls()
+			if len(got) != 3 {
+				t.Fatalf("expected 3 API calls, got %d: %v", len(got), got)
+			}
+
+			if got[0].Method != http.MethodPatch || got[0].Path != "/vm" || !strings.Contains(got[0].Body, `"Paused"`) {
+				t.Errorf("unexpected pause call: %+v", got[0])
+			}
+			if got[2].Method != http.MethodPatch || got[2].Path != "/vm" || !strings.Contains(got[2].Body, `"Resumed"`) {
+				t.Errorf("unexpected resume call: %+v", got[2])
+			}
+
+			if got[1].Method != http.MethodPut || got[1].Path != "/snapshot/create" {
+				t.Fatalf("unexpected snapshot call: %+v", got[1])
+			}
+			params := SnapshotCreateParams{}
+			if err := json.Unmarshal([]byte(got[1].Body), &params); err != nil {
+				t.Fatalf("invalid snapshot body: %v", err)
+			}
+			want := SnapshotCreateParams{
+				SnapshotType: tt.want,
+				SnapshotPath: filepath.Join(dir, STATE_FILE),
+				MemFilePath:  filepath.Join(dir, MEMORY_FILE),
+			}
+			if params != want {
+				t.Errorf("expected snapshot params %+v, got %+v", want, params)
+			}
+
+			parent, err := os.ReadFile(filepath.Join(dir, PARENT_FILE))
+			if rel, _ := filepath.Rel(dir, base); tt.diff && string(parent) != rel {
+				t.Errorf("expected base %s to be recorded, got %q (%v)", rel, parent, err)
+			}
+			if !tt.diff && err == nil {
+				t.Errorf("expected no base recorded for a full snapshot, got %q", parent)
+			}
+		})
+	}
+}
+
+func TestSnapshotDiffWithoutBase(t *testing.T) {
+	socket, calls := stubAPI(t, nil)
+	fc := &FirecrackerVM{Diff: true}
+
+	if err := fc.Snapshot(t.TempDir(), socket, "vm"); err == nil {
+		t.Fatalf("expected diff snapshot without a previous snapshot to fail")
+	}
+	if len(calls()) != 0 {
+		t.Errorf("expected no API calls, got %+v", calls())
+	}
+}
+
+func TestRestore(t *testing.T) {
+	socket, calls := stubAPI(t, nil)
+	fc := &FirecrackerVM{}
+
+	// Configs without FDs keep the tap devices the snapshot was taken with
+	netConfigs := []*daemon.RestoredNetConfig{{ID: "eth0"}}
+
+	if err := fc.Restore("file:///snapshots/vm", socket, netConfigs); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	got := calls()
+	if len(got) != 1 || got[0].Method != http.MethodPut || got[0].Path != "/snapshot/load" {
+		t.Fatalf("unexpected API calls: %+v", got)
+	}
+
+	params := SnapshotLoadParams{}
+	if err := json.Unmarshal([]byte(got[0].Body), &params); err != nil {
+		t.Fatalf("invalid load body: %v", err)
+	}
+	if params.SnapshotPath != "/snapshots/vm/"+STATE_FILE {
+		t.Errorf("unexpected snapshot path %s", params.SnapshotPath)
+	}
+	if params.MemBackend == nil || params.MemBackend.BackendType != "File" || params.MemBackend.BackendPath != "/snapshots/vm/"+MEMORY_FILE {
+		t.Errorf("unexpected memory backend %+v", params.MemBackend)
+	}
+	if params.ResumeVM {
+		t.Errorf("expected VM to be resumed separately")
+	}
+	if len(params.NetworkOverrides) != 0 {
+		t.Errorf("expected no network overrides, got %+v", params.NetworkOverrides)
+	}
+}
+
+const pageSize = 4096
+
+// Writes a memory file of the pages, leaving a hole for each empty one, like a diff snapshot.
+func writeMemory(t *testing.T, dir string, parent string, pages ...string) {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, MEMORY_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for i, page := range pages {
+		if page == "" {
+			continue
+		}
+		if _, err := f.WriteAt([]byte(strings.Repeat(page, pageSize)), int64(i*pageSize)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Truncate(int64(len(pages) * pageSize)); err != nil {
+		t.Fatal(err)
+	}
+
+	if parent != "" {
+		if err := os.WriteFile(filepath.Join(dir, PARENT_FILE), []byte(parent), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRestoreDiff(t *testing.T) {
+	socket, calls := stubAPI(t, nil)
+	fc := &FirecrackerVM{Diff: true}
+
+	root := filepath.Join(t.TempDir(), "dumped")
+	writeMemory(t, filepath.Join(root, "full"), "", "a", "a", "a")
+	writeMemory(t, filepath.Join(root, "diff1"), "../full", "", "b", "")
+	writeMemory(t, filepath.Join(root, "diff2"), "../diff1", "", "", "c")
+
+	// Bases are found relative to the diff snapshots, wherever they are moved together
+	moved := filepath.Join(t.TempDir(), "moved")
+	if err := os.Rename(root, moved); err != nil {
+		t.Fatal(err)
+	}
+	diff2 := filepath.Join(moved, "diff2")
+
+	if err := fc.Restore(diff2, socket, nil); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	got := calls()
+	if len(got) != 1 {
+		t.Fatalf("unexpected API calls: %+v", got)
+	}
+	params := SnapshotLoadParams{}
+	if err := json.Unmarshal([]byte(got[0].Body), &params); err != nil {
+		t.Fatalf("invalid load body: %v", err)
+	}
+	if params.MemBackend.BackendPath != filepath.Join(diff2, MERGED_MEMORY_FILE) {
+		t.Fatalf("expected merged memory to be loaded, got %s", params.MemBackend.BackendPath)
+	}
+	if !params.EnableDiffSnapshots {
+		t.Errorf("expected dirty page tracking to be enabled")
+	}
+
+	memory, err := os.ReadFile(params.MemBackend.BackendPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Repeat("a", pageSize) + strings.Repeat("b", pageSize) + strings.Repeat("c", pageSize)
+	if string(memory) != want {
+		t.Errorf("expected diffs to be merged onto base memory")
+	}
+}
+
+func TestRestoreDiffWithoutBase(t *testing.T) {
+	socket, calls := stubAPI(t, nil)
+	fc := &FirecrackerVM{}
+
+	root := t.TempDir()
+	diff := filepath.Join(root, "diff")
+	writeMemory(t, diff, filepath.Join(root, "gone"), "", "b")
+
+	if err := fc.Restore(diff, socket, nil); err == nil {
+		t.Fatalf("expected restore of a diff snapshot without its base to fail")
+	}
+	if len(calls()) != 0 {
+		t.Errorf("expected no API calls, got %+v", calls())
+	}
+}
+
+func TestRestoreInvalidFD(t *testing.T) {
+	socket, calls := stubAPI(t, nil)
+	fc := &FirecrackerVM{}
+
+	f, err := os.CreateTemp(t.TempDir(), "not-a-tap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	netConfigs := []*daemon.RestoredNetConfig{{ID: "eth0", NumFDs: 1, Fds: []int64{int64(f.Fd())}}}
+
+	if err := fc.Restore("/snapshots/vm", socket, netConfigs); err == nil {
+		t.Fatalf("expected restore to fail for a non-tap FD")
+	}
+	if len(calls()) != 0 {
+		t.Errorf("expected no API calls, got %+v", calls())
+	}
+}
+
+func TestFault(t *testing.T) {
+	socket, _ := stubAPI(t, map[string]string{"/vm": "The requested operation is not supported after starting the microVM."})
+	fc := &FirecrackerVM{}
+
+	err := fc.Pause(socket)
+	if err == nil || !strings.Contains(err.Error(), "not supported after starting") {
+		t.Fatalf("expected fault message in error, got %v", err)
+	}
+}
+
+func TestGetPID(t *testing.T) {
+	socket, _ := stubAPI(t, nil)
+	fc := &FirecrackerVM{}
+
+	pid, err := fc.GetPID(socket)
+	if err != nil {
+		t.Fatalf("get PID: %v", err)
+	}
+	if int(pid) != os.Getpid() {
+		t.Errorf("expected PID %d, got %d", os.Getpid(), pid)
+	}
+}
@@ -0,0 +1,8 @@
+package flags
+
+import "github.com/cedana/cedana/pkg/flags"
+
+var (
+	VmSocketFlag = flags.Flag{Full: "socket"}
+	DiffFlag     = flags.Flag{Full: "diff"}
+)
@@ -92,6 +92,15 @@ var QueryCmd = &cobra.Command{
 					utils.Runtime(pod),
 				})
 			}
+			for _, container := range pod.Runc {
+				tableWriter.AppendRow(table.Row{
+					pod.ID,
+					pod.Name,
+					pod.Namespace,
+					container.ID,
+					utils.Runtime(pod),
+				})
+			}
 		}
 
 		output = tableWriter.Render()
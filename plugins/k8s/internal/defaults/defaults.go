@@ -1,7 +1,24 @@
 package defaults
 
+import (
+	"os"
+
+	"github.com/cedana/cedana/pkg/utils"
+)
+
 const (
-	RUNTIME              = "containerd"
+	RUNTIME              = "containerd" // used if the runtime of the node cannot be detected
 	CONTAINERD_NAMESPACE = "k8s.io"
 	CRIO_NAMESPACE       = "k8s.io"
+
+	// Default runtime root of CRI-O's runc, where its container state lives
+	CRIO_RUNC_ROOT = "/run/runc"
+
+	// Default location of the kubelet config, if not specified with --config
+	KUBELET_CONFIG = "/var/lib/kubelet/config.yaml"
+)
+
+var (
+	CONTAINERD_ADDRESS = utils.Getenv(os.Environ(), "CONTAINERD_ADDRESS", "/run/containerd/containerd.sock")
+	CRIO_ADDRESS       = utils.Getenv(os.Environ(), "CRIO_ADDRESS", "/var/run/crio/crio.sock")
 )
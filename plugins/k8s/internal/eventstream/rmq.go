@@ -23,6 +23,7 @@ import (
 	"github.com/cedana/cedana/pkg/config"
 	"github.com/cedana/cedana/pkg/features"
 	"github.com/cedana/cedana/pkg/profiling"
+	k8s_utils "github.com/cedana/cedana/plugins/k8s/pkg/utils"
 	"github.com/cedana/cedana/plugins/runc/pkg/runc"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -295,20 +296,30 @@ func (es *EventStream) checkpointHandler(ctx context.Context) rabbitmq.Handler {
 			log.Debug().Msg("no pods found for checkpoint request")
 			return rabbitmq.Ack
 		}
-		containers := queryResp.K8S.Pods[0].Containerd
+		pod := queryResp.K8S.Pods[0]
+		containers, err := podContainers(pod)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to get containers of pod for checkpoint request")
+			return rabbitmq.Ack
+		}
 		if len(containers) == 0 {
 			log.Trace().Msg("no containers found in pod for checkpoint request")
 			return rabbitmq.Ack
 		}
+		log = log.With().Str("runtime", k8s_utils.Runtime(pod)).Logger()
 		log.Info().Int("containers", len(containers)).Msg("found container(s) in pod to checkpoint")
 
+		if strings.HasPrefix(req.Kind, "rootfs") && len(pod.Containerd) == 0 {
+			log.Error().Msg("rootfs checkpoints are only supported for pods on containerd")
+			return rabbitmq.Ack
+		}
+
 		checkpointIdMap := make(map[int]string)
 		specMap := make(map[int]*specs.Spec)
-		imageMap := make(map[int]string)
 
 		// Initialize spec, checkpoints for all containers
 		for i, container := range containers {
-			spec, err := runc.LoadSpec(filepath.Join("/host", container.Runc.Bundle, "config.json"))
+			spec, err := runc.LoadSpec(filepath.Join("/host", container.bundle, "config.json"))
 			if err != nil {
 				log.Error().Err(err).Msg("failed to load spec for container")
 				return rabbitmq.Ack
@@ -335,8 +346,11 @@ func (es *EventStream) checkpointHandler(ctx context.Context) rabbitmq.Handler {
 			}
 		}
 
-		for i, container := range containers {
-			imageMap[i] = container.Image.GetName()
+		for i, c := range containers {
+			container := c.details.Containerd
+			if container == nil {
+				continue
+			}
 			container.Address = es.containerdAddress
 			if rootfs {
 				// NOTE: Currently we store all containers in the same image repository (with separate tags)
@@ -355,12 +369,10 @@ func (es *EventStream) checkpointHandler(ctx context.Context) rabbitmq.Handler {
 		var dumpReqs []*daemon.DumpReq
 		for i, container := range containers {
 			dumpReq := &daemon.DumpReq{
-				Name: checkpointIdMap[i],
-				Type: "containerd",
-				Criu: defaultDumpOpts,
-				Details: &daemon.Details{
-					Containerd: container,
-				},
+				Name:    checkpointIdMap[i],
+				Type:    container.typ,
+				Criu:    defaultDumpOpts,
+				Details: container.details,
 			}
 			if req.Overrides != nil {
 				criuOpts := &criu.CriuOpts{}
@@ -375,7 +387,7 @@ func (es *EventStream) checkpointHandler(ctx context.Context) rabbitmq.Handler {
 				dumpReq.Streams = int32(req.Overrides.Streams)
 				dumpReq.Async = req.Overrides.Async
 			}
-			log.Debug().Str("container", container.ID).Interface("req", dumpReq).Msg("prepared dump request for container")
+			log.Debug().Str("container", container.id).Interface("req", dumpReq).Msg("prepared dump request for container")
 			dumpReqs = append(dumpReqs, dumpReq)
 		}
 
@@ -384,7 +396,7 @@ func (es *EventStream) checkpointHandler(ctx context.Context) rabbitmq.Handler {
 		wg.Add(len(dumpReqs))
 
 		for i, dumpReq := range dumpReqs {
-			log := log.With().Int("container_order", i).Str("container", containers[i].ID).Logger()
+			log := log.With().Int("container_order", i).Str("container", containers[i].id).Logger()
 			go func() {
 				defer wg.Done()
 				_, _, err = es.cedana.Freeze(ctx, dumpReq)
@@ -405,7 +417,7 @@ func (es *EventStream) checkpointHandler(ctx context.Context) rabbitmq.Handler {
 
 		if len(errMap) > 0 {
 			for i, err := range errMap {
-				log := log.With().Int("container_order", i).Str("container", containers[i].ID).Logger()
+				log := log.With().Int("container_order", i).Str("container", containers[i].id).Logger()
 				if err != nil {
 					log.Error().Err(err).Msg("failed to freeze container")
 				}
@@ -460,6 +472,45 @@ func (es *EventStream) checkpointHandler(ctx context.Context) rabbitmq.Handler {
 	}
 }
 
+// A container of a pod to checkpoint, dumped through the runtime the pod is on
+type podContainer struct {
+	id      string
+	bundle  string
+	typ     string // dump type
+	details *daemon.Details
+}
+
+// Returns the containers of the pod to checkpoint. Containers of pods on CRI-O are
+// dumped directly as runc containers, as CRI-O runs them with runc.
+func podContainers(pod *k8s.Pod) ([]podContainer, error) {
+	var containers []podContainer
+
+	switch runtime := k8s_utils.Runtime(pod); runtime {
+	case "containerd":
+		for _, container := range pod.Containerd {
+			containers = append(containers, podContainer{
+				id:      container.ID,
+				bundle:  container.GetRunc().GetBundle(),
+				typ:     "containerd",
+				details: &daemon.Details{Containerd: container},
+			})
+		}
+	case "crio":
+		for _, container := range pod.Runc {
+			containers = append(containers, podContainer{
+				id:      container.ID,
+				bundle:  container.GetBundle(),
+				typ:     "runc",
+				details: &daemon.Details{Runc: container},
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported runtime %s", runtime)
+	}
+
+	return containers, nil
+}
+
 func (es *EventStream) publishCheckpoint(
 	ctx context.Context,
 	podId string,
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"testing"
 
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/plugins/containerd"
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/plugins/k8s"
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/plugins/runc"
 	"github.com/cedana/cedana/pkg/profiling"
 )
 
@@ -56,3 +59,59 @@ func TestCheckpointErrorDoesNotReplaceActionID(t *testing.T) {
 		t.Fatalf("terminal error missing from payload: %q", decoded.Info.Error)
 	}
 }
+
+func TestPodContainersContainerd(t *testing.T) {
+	pod := &k8s.Pod{
+		Containerd: []*containerd.Containerd{
+			{ID: "app", Runc: &runc.Runc{ID: "app", Bundle: "/run/containerd/app"}},
+		},
+	}
+
+	containers, err := podContainers(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 1 {
+		t.Fatalf("expected 1 container, got %d", len(containers))
+	}
+	if containers[0].typ != "containerd" || containers[0].details.GetContainerd().GetID() != "app" {
+		t.Errorf("expected containerd dump of app, got %s dump of %v", containers[0].typ, containers[0].details)
+	}
+	if containers[0].bundle != "/run/containerd/app" {
+		t.Errorf("expected bundle of the runc container, got %q", containers[0].bundle)
+	}
+}
+
+func TestPodContainersCrio(t *testing.T) {
+	pod := &k8s.Pod{
+		Runc: []*runc.Runc{
+			{ID: "app", Root: "/run/runc", Bundle: "/run/containers/storage/app/userdata"},
+			{ID: "sidecar", Root: "/run/runc", Bundle: "/run/containers/storage/sidecar/userdata"},
+		},
+	}
+
+	containers, err := podContainers(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 2 {
+		t.Fatalf("expected 2 containers, got %d", len(containers))
+	}
+	for i, container := range containers {
+		if container.typ != "runc" {
+			t.Errorf("expected runc dump for CRI-O container, got %s", container.typ)
+		}
+		if container.details.GetContainerd() != nil || container.details.GetRunc() != pod.Runc[i] {
+			t.Errorf("expected details of the runc container, got %v", container.details)
+		}
+		if container.bundle != pod.Runc[i].Bundle {
+			t.Errorf("expected bundle %q, got %q", pod.Runc[i].Bundle, container.bundle)
+		}
+	}
+}
+
+func TestPodContainersUnsupported(t *testing.T) {
+	if _, err := podContainers(&k8s.Pod{}); err == nil {
+		t.Error("expected error for pod on unsupported runtime")
+	}
+}
@@ -0,0 +1,159 @@
+package kube
+
+// Detection of the container runtime used by the kubelet on this node.
+// See https://kubernetes.io/docs/tasks/administer-cluster/migrating-from-dockershim/find-out-runtime-you-use/
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cedana/cedana/plugins/k8s/internal/defaults"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+const DETECT_TIMEOUT = 5 * time.Second
+
+// DetectRuntime returns the runtime (containerd, crio) used by the kubelet on this node,
+// along with the address of its CRI socket. The runtime endpoint is taken from the kubelet's
+// flags or config. If the kubelet is not found, the default runtime sockets are probed.
+func DetectRuntime() (runtime string, address string) {
+	endpoint := kubeletRuntimeEndpoint()
+
+	if endpoint != "" {
+		address = strings.TrimPrefix(endpoint, "unix://")
+		runtime = runtimeFromEndpoint(address)
+		if runtime == "" {
+			runtime = runtimeFromVersion(address)
+		}
+		if runtime != "" {
+			log.Debug().Str("runtime", runtime).Str("address", address).Msg("detected k8s runtime from kubelet")
+			return runtime, address
+		}
+		log.Warn().Str("endpoint", endpoint).Msg("failed to detect k8s runtime from kubelet endpoint")
+	}
+
+	switch {
+	case exists(defaults.CRIO_ADDRESS):
+		runtime, address = "crio", defaults.CRIO_ADDRESS
+	case exists(defaults.CONTAINERD_ADDRESS):
+		runtime, address = "containerd", defaults.CONTAINERD_ADDRESS
+	default:
+		log.Warn().Str("runtime", defaults.RUNTIME).Msg("failed to detect k8s runtime, using default")
+		return defaults.RUNTIME, ""
+	}
+
+	log.Debug().Str("runtime", runtime).Str("address", address).Msg("detected k8s runtime from socket")
+
+	return runtime, address
+}
+
+///////////////
+/// Helpers ///
+///////////////
+
+// Returns the CRI endpoint of the running kubelet, from its flags or its config file.
+func kubeletRuntimeEndpoint() string {
+	args := kubeletArgs()
+	if args == nil {
+		return ""
+	}
+
+	config := defaults.KUBELET_CONFIG
+
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !hasValue && i+1 < len(args) {
+			value = args[i+1]
+		}
+		switch name {
+		case "--container-runtime-endpoint":
+			return value
+		case "--config":
+			config = value
+		}
+	}
+
+	data, err := os.ReadFile(config)
+	if err != nil {
+		return ""
+	}
+
+	var kubeletConfig struct {
+		ContainerRuntimeEndpoint string `yaml:"containerRuntimeEndpoint"`
+	}
+	if err := yaml.Unmarshal(data, &kubeletConfig); err != nil {
+		return ""
+	}
+
+	return kubeletConfig.ContainerRuntimeEndpoint
+}
+
+// Returns the command-line arguments of the running kubelet, or nil if not found.
+func kubeletArgs() []string {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		cmdline, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "cmdline"))
+		if err != nil || len(cmdline) == 0 {
+			continue
+		}
+		args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+		if filepath.Base(args[0]) == "kubelet" {
+			return args[1:]
+		}
+	}
+
+	return nil
+}
+
+func runtimeFromEndpoint(address string) string {
+	switch {
+	case strings.Contains(address, "crio"):
+		return "crio"
+	case strings.Contains(address, "containerd"):
+		return "containerd"
+	}
+	return ""
+}
+
+// Asks the runtime behind the CRI socket for its name.
+func runtimeFromVersion(address string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), DETECT_TIMEOUT)
+	defer cancel()
+
+	conn, err := dialCRI(address)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	version, err := runtimeapi.NewRuntimeServiceClient(conn).Version(ctx, &runtimeapi.VersionRequest{})
+	if err != nil {
+		return ""
+	}
+
+	switch version.GetRuntimeName() {
+	case "cri-o":
+		return "crio"
+	case "containerd":
+		return "containerd"
+	}
+	return ""
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -3,9 +3,9 @@ package kube
 import (
 	"context"
 	"errors"
+	"sync"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
-	"github.com/cedana/cedana/plugins/k8s/internal/defaults"
 )
 
 // RuntimeClient is the interface that K8s runtime clients must implement
@@ -14,18 +14,19 @@ type RuntimeClient interface {
 	Query(context.Context, *daemon.QueryReq) (*daemon.QueryResp, error)
 }
 
+// The node's runtime does not change while we are running, so detect it only once
+var detectRuntime = sync.OnceValues(DetectRuntime)
+
 // CurrentRuntimeClient returns the current K8s runtime client in use
 // on this host.
 func CurrentRuntimeClient() (RuntimeClient, error) {
-	// TODO: Dynamically determine this from the system.
-	// See https://kubernetes.io/docs/tasks/administer-cluster/migrating-from-dockershim/find-out-runtime-you-use/
-	runtime := defaults.RUNTIME
+	runtime, address := detectRuntime()
 
 	switch runtime {
 	case "containerd":
 		return NewContainerdClient()
 	case "crio":
-		return NewCrioClient()
+		return NewCrioClient(address)
 	default:
 		return nil, errors.New("unsupported runtime: " + runtime)
 	}
@@ -1,13 +1,172 @@
 package kube
 
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/plugins/k8s"
+	runc_proto "buf.build/gen/go/cedana/cedana/protocolbuffers/go/plugins/runc"
+	"github.com/cedana/cedana/pkg/features"
+	"github.com/cedana/cedana/pkg/types"
+	"github.com/cedana/cedana/plugins/k8s/internal/defaults"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// Implements the K8s runtime client interface for CRI-O, by talking CRI over its socket.
+// CRI-O runs its containers with runc, so the containers are returned as runc containers.
 type CrioClient struct {
-	RuntimeClientUnimplemented
+	address string
 }
 
-func NewCrioClient() (*CrioClient, error) {
-	return &CrioClient{}, nil
+func NewCrioClient(address string) (*CrioClient, error) {
+	if address == "" {
+		address = defaults.CRIO_ADDRESS
+	}
+	return &CrioClient{address: address}, nil
 }
 
 func (c *CrioClient) String() string {
 	return "crio"
 }
+
+func (c *CrioClient) Query(ctx context.Context, req *daemon.QueryReq) (resp *daemon.QueryResp, err error) {
+	var query types.Query
+
+	err = features.QueryHandler.IfAvailable(func(_ string, runcQuery types.Query) error {
+		query = runcQuery
+		return nil
+	}, "runc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get runc query handler: %v", err)
+	}
+
+	conn, err := dialCRI(c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to crio: %v", err)
+	}
+	defer conn.Close()
+
+	cri := runtimeapi.NewRuntimeServiceClient(conn)
+
+	sandboxResp, err := cri.ListPodSandbox(ctx, &runtimeapi.ListPodSandboxRequest{
+		Filter: &runtimeapi.PodSandboxFilter{
+			State: &runtimeapi.PodSandboxStateValue{State: runtimeapi.PodSandboxState_SANDBOX_READY},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list crio pod sandboxes: %v", err)
+	}
+
+	containerResp, err := cri.ListContainers(ctx, &runtimeapi.ListContainersRequest{
+		Filter: &runtimeapi.ContainerFilter{
+			State: &runtimeapi.ContainerStateValue{State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list crio containers: %v", err)
+	}
+
+	sandboxes := make(map[string]*runtimeapi.PodSandbox)
+	for _, sandbox := range sandboxResp.GetItems() {
+		sandboxes[sandbox.GetId()] = sandbox
+	}
+
+	// The infra container of a pod shares the ID of its sandbox. It does not
+	// exist if CRI-O is configured to drop infra containers.
+	type entry struct {
+		id      string
+		sandbox *runtimeapi.PodSandbox
+		kind    string
+	}
+	var entries []entry
+	for _, sandbox := range sandboxResp.GetItems() {
+		entries = append(entries, entry{sandbox.GetId(), sandbox, CONTAINER_TYPE_SANDBOX})
+	}
+	for _, container := range containerResp.GetContainers() {
+		sandbox, ok := sandboxes[container.GetPodSandboxId()]
+		if !ok {
+			continue
+		}
+		entries = append(entries, entry{container.GetId(), sandbox, CONTAINER_TYPE_CONTAINER})
+	}
+
+	podMap := make(map[string]*k8s.Pod)
+	states := []*daemon.ProcessState{}
+
+	resp = &daemon.QueryResp{K8S: &k8s.QueryResp{}}
+
+	for _, e := range entries {
+		metadata := e.sandbox.GetMetadata()
+
+		if len(req.K8S.Names) > 0 && !hasAnyPrefix(metadata.GetName(), req.K8S.Names) {
+			continue
+		}
+		if req.K8S.Namespace != "" && req.K8S.Namespace != metadata.GetNamespace() {
+			continue
+		}
+		if req.K8S.ContainerType != "" && req.K8S.ContainerType != e.kind {
+			continue
+		}
+
+		runcResp, err := query(ctx, &daemon.QueryReq{
+			Type: "runc",
+			Runc: &runc_proto.QueryReq{
+				IDs:  []string{e.id},
+				Root: defaults.CRIO_RUNC_ROOT,
+			},
+		})
+		if err != nil {
+			resp.Messages = append(resp.Messages, fmt.Sprintf("%s: failed to query runc: %v", e.id, err))
+			continue
+		}
+		if len(runcResp.Runc.Containers) == 0 {
+			if e.kind != CONTAINER_TYPE_SANDBOX {
+				resp.Messages = append(resp.Messages, fmt.Sprintf("%s: no runc container found", e.id))
+			}
+			continue
+		}
+		resp.Messages = append(resp.Messages, runcResp.Messages...)
+
+		pod := podMap[e.sandbox.GetId()]
+		if pod == nil {
+			pod = &k8s.Pod{
+				ID:        e.sandbox.GetId(),
+				Name:      metadata.GetName(),
+				Namespace: metadata.GetNamespace(),
+				UID:       metadata.GetUid(),
+			}
+			podMap[e.sandbox.GetId()] = pod
+		}
+
+		pod.Runc = append(pod.Runc, runcResp.Runc.Containers[0])
+		states = append(states, runcResp.States[0])
+	}
+
+	for _, pod := range podMap {
+		resp.K8S.Pods = append(resp.K8S.Pods, pod)
+	}
+	resp.States = states
+
+	return resp, nil
+}
+
+///////////////
+/// Helpers ///
+///////////////
+
+func dialCRI(address string) (*grpc.ClientConn, error) {
+	return grpc.NewClient("unix://"+address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
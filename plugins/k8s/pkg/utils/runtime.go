@@ -6,6 +6,9 @@ func Runtime(pod *k8s.Pod) string {
 	if pod.Containerd != nil {
 		return "containerd"
 	}
+	if pod.Runc != nil {
+		return "crio"
+	}
 	// Add other supported runtimes here as needed
 
 	return "unsupported"
@@ -3,6 +3,8 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/plugins/kata"
@@ -15,7 +17,7 @@ func init() {
 	DumpCmd.Flags().StringP(kata_flags.DirFlag.Full, kata_flags.DirFlag.Short, "", "socket path for full vm snapshot")
 	DumpCmd.MarkFlagRequired(kata_flags.DirFlag.Full)
 
-	DumpCmd.Flags().StringP(kata_flags.VmTypeFlag.Full, kata_flags.VmTypeFlag.Short, "cloud-hypervisor", "vm type for full vm snapshot")
+	DumpCmd.Flags().StringP(kata_flags.VmTypeFlag.Full, kata_flags.VmTypeFlag.Short, "cloud-hypervisor", "vm type for full vm snapshot ("+strings.Join(VmTypes, ", ")+")")
 	DumpCmd.Flags().Uint32P(kata_flags.PortFlag.Full, kata_flags.PortFlag.Short, 8080, "port for cedana daemon")
 
 	DumpCmd.Flags().StringP(kata_flags.VmSocketFlag.Full, kata_flags.VmSocketFlag.Short, "", "socket path for full vm snapshot")
@@ -25,6 +27,9 @@ func init() {
 	DumpCmd.MarkFlagRequired(kata_flags.VmIDFlag.Full)
 }
 
+// VM types that have a snapshot backend plugin
+var VmTypes = []string{"cloud-hypervisor", "firecracker"}
+
 var DumpCmd = &cobra.Command{
 	Use:   "kata",
 	Short: "Dump a kata vm or container (w/o rootfs)",
@@ -41,6 +46,10 @@ var DumpCmd = &cobra.Command{
 		vmSocket, _ := cmd.Flags().GetString(kata_flags.VmSocketFlag.Full)
 		vmID, _ := cmd.Flags().GetString(kata_flags.VmIDFlag.Full)
 
+		if !slices.Contains(VmTypes, vmType) {
+			return fmt.Errorf("unsupported vm type %s, must be one of: %s", vmType, strings.Join(VmTypes, ", "))
+		}
+
 		req.Type = vmType
 		req.Details = &daemon.Details{Kata: &kata.Kata{
 			Dir:      dir,
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/cedana/cedana/pkg/config"
+	"github.com/cedana/cedana/pkg/flags"
+	"github.com/cedana/cedana/plugins/slurm/internal/coordinator"
+	slurm_flags "github.com/cedana/cedana/plugins/slurm/pkg/flags"
+	"github.com/spf13/cobra"
+)
+
+// Port the daemons listen on, when using TCP
+const DEFAULT_DAEMON_PORT = 8080
+
+func init() {
+	checkpointCmd.Flags().StringP(flags.DirFlag.Full, flags.DirFlag.Short, "", "directory shared by all nodes to dump into (default: configured checkpoint dir)")
+	checkpointCmd.Flags().String(flags.CompressionFlag.Full, "", "compression algorithm (none, tar, gzip, lz4, zlib, zstd)")
+	checkpointCmd.Flags().Bool(flags.LeaveRunningFlag.Full, false, "leave the job running after checkpoint")
+	checkpointCmd.Flags().Int(slurm_flags.PortFlag.Full, DEFAULT_DAEMON_PORT, "TCP port of the daemon on each node")
+
+	restoreCmd.Flags().String(slurm_flags.FromFlag.Full, "", "Slurm job id of the checkpoint to restore")
+	restoreCmd.Flags().StringP(flags.DirFlag.Full, flags.DirFlag.Short, "", "directory of the checkpoint (default: configured checkpoint dir)")
+	restoreCmd.Flags().Int(slurm_flags.PortFlag.Full, DEFAULT_DAEMON_PORT, "TCP port of the daemon on each node")
+	restoreCmd.MarkFlagRequired(slurm_flags.FromFlag.Full)
+
+	HelperCmd.AddCommand(checkpointCmd)
+	HelperCmd.AddCommand(restoreCmd)
+}
+
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint <slurm-job-id>",
+	Short: "Checkpoint a Slurm job across all its nodes",
+	Long:  "Checkpoint a Slurm job across all its nodes, through the daemon on each node. All ranks are frozen first, and the checkpoint is only committed (manifest written) if all ranks are dumped. Otherwise, all ranks are unfrozen.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jid, err := parseJobID(args[0])
+		if err != nil {
+			return err
+		}
+
+		dir, _ := cmd.Flags().GetString(flags.DirFlag.Full)
+		compression, _ := cmd.Flags().GetString(flags.CompressionFlag.Full)
+		leaveRunning, _ := cmd.Flags().GetBool(flags.LeaveRunningFlag.Full)
+		port, _ := cmd.Flags().GetInt(slurm_flags.PortFlag.Full)
+
+		if dir == "" {
+			dir = config.Global.Checkpoint.Dir
+		}
+
+		nodes, err := coordinator.JobNodes(cmd.Context(), jid)
+		if err != nil {
+			return fmt.Errorf("failed to get nodes of job %d: %w", jid, err)
+		}
+
+		c, err := coordinator.New(nodes, port)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		manifest, err := c.Checkpoint(cmd.Context(), jid, dir, compression, leaveRunning)
+		if err != nil {
+			return err
+		}
+
+		for _, rank := range manifest.Ranks {
+			for _, path := range rank.Paths {
+				fmt.Printf("Dumped rank on %s to %s\n", rank.Node, path)
+			}
+		}
+		fmt.Printf("Checkpointed job %d across %d nodes\n", jid, len(manifest.Ranks))
+
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <slurm-job-id>",
+	Short: "Restore a checkpoint of a Slurm job across all nodes of a new job",
+	Long:  "Restore a checkpoint of a Slurm job across all nodes of a new job, through the daemon on each node. The new job must have the same number of nodes, and its steps must already be running placeholders (e.g. 'sleep infinity') to keep them alive. All ranks are restored stopped, and only resumed once all are restored. If any rank fails to restore or resume, the new job is cancelled.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jid, err := parseJobID(args[0])
+		if err != nil {
+			return err
+		}
+
+		fromStr, _ := cmd.Flags().GetString(slurm_flags.FromFlag.Full)
+		dir, _ := cmd.Flags().GetString(flags.DirFlag.Full)
+		port, _ := cmd.Flags().GetInt(slurm_flags.PortFlag.Full)
+
+		from, err := parseJobID(fromStr)
+		if err != nil {
+			return err
+		}
+		if dir == "" {
+			dir = config.Global.Checkpoint.Dir
+		}
+
+		manifest, err := coordinator.ReadManifest(dir, from)
+		if err != nil {
+			return fmt.Errorf("failed to read checkpoint of job %d: %w", from, err)
+		}
+
+		nodes, err := coordinator.JobNodes(cmd.Context(), jid)
+		if err != nil {
+			return fmt.Errorf("failed to get nodes of job %d: %w", jid, err)
+		}
+
+		c, err := coordinator.New(nodes, port)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		if err := c.Restore(cmd.Context(), manifest, jid); err != nil {
+			return err
+		}
+
+		fmt.Printf("Restored job %d into job %d across %d nodes\n", from, jid, len(nodes))
+
+		return nil
+	},
+}
+
+func parseJobID(s string) (uint32, error) {
+	jid, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid job id %q: %v", s, err)
+	}
+	return uint32(jid), nil
+}
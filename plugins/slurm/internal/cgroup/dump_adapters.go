@@ -18,13 +18,25 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-func UseCgroupFreezerIfAvailableForDump(next types.Dump) types.Dump {
+// Tells CRIU to use the job's freezer cgroup, but only if the job is already frozen (e.g. by
+// a coordinated checkpoint), as CRIU cannot seize frozen processes otherwise. CRIU leaves the
+// cgroup frozen after the dump.
+func UseCgroupFreezerIfFrozenForDump(next types.Dump) types.Dump {
 	return func(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daemon.DumpReq) (code func() <-chan int, err error) {
+		if req.Action != daemon.DumpAction_DUMP {
+			return next(ctx, opts, resp, req)
+		}
+
 		manager, ok := ctx.Value(slurm_keys.CGROUP_MANAGER_CONTEXT_KEY).(cgroups.Manager)
 		if !ok {
 			return nil, status.Errorf(codes.FailedPrecondition, "failed to get cgroup manager from context")
 		}
 
+		state, err := manager.GetFreezerState()
+		if err != nil || state != cgroups.Frozen {
+			return next(ctx, opts, resp, req)
+		}
+
 		version, err := opts.CRIU.GetCriuVersion(ctx)
 		if err != nil {
 			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get CRIU version: %v", err))
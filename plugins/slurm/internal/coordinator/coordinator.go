@@ -0,0 +1,306 @@
+package coordinator
+
+// Coordinated checkpoint/restore of a SLURM job across all its nodes, through the
+// daemon on each node. Uses a two-phase protocol: every rank is prepared (frozen)
+// first, and the checkpoint is only committed (manifest written) if all ranks dump.
+// A rank may run several process trees (e.g. a task each), each dumped separately.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	slurm_proto "buf.build/gen/go/cedana/cedana/protocolbuffers/go/plugins/slurm"
+	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
+	"github.com/cedana/cedana/pkg/client"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	MANIFEST_FORMAT  = "slurm-%d.json"
+	DUMP_NAME_FORMAT = "slurm-%d-%s-%d-%d"
+)
+
+// Manifest of a coordinated checkpoint, with the checkpoint of each rank (node) of the job
+type Manifest struct {
+	JobID uint32  `json:"job_id"`
+	Time  int64   `json:"time"`
+	Ranks []*Rank `json:"ranks"`
+}
+
+// Rank of the job on a node, with the checkpoint of each of its process trees
+type Rank struct {
+	Node  string   `json:"node"`
+	Paths []string `json:"paths"`
+}
+
+type Coordinator struct {
+	nodes   []string
+	clients []*client.Client
+}
+
+// New connects to the daemon on each of the nodes, listening on TCP at the port.
+func New(nodes []string, port int) (*Coordinator, error) {
+	c := &Coordinator{nodes: nodes}
+
+	for _, node := range nodes {
+		client, err := client.New(net.JoinHostPort(node, strconv.Itoa(port)), "tcp")
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to create client for node %s: %w", node, err)
+		}
+		c.clients = append(c.clients, client)
+	}
+
+	return c, nil
+}
+
+func (c *Coordinator) Close() {
+	for _, client := range c.clients {
+		client.Close()
+	}
+}
+
+// Checkpoint dumps the job on all nodes into the directory, which must be shared by all nodes.
+// If any rank fails to freeze or dump, all ranks are unfrozen and no manifest is written.
+// Otherwise, the manifest is written to the directory and the job is unfrozen, or cancelled
+// if not leaving it running. The daemon on each node locks the network of its rank while
+// dumping it, and as ranks are dumped left running, unlocks it once dumped.
+func (c *Coordinator) Checkpoint(ctx context.Context, jid uint32, dir string, compression string, leaveRunning bool) (*Manifest, error) {
+	log := log.With().Uint32("job_id", jid).Int("nodes", len(c.nodes)).Logger()
+
+	// Phase 1: prepare
+
+	log.Info().Msg("freezing all ranks")
+
+	trees := make([][]uint32, len(c.nodes))
+
+	frozen := c.fanOut(func(i int, client *client.Client) error {
+		resp, _, err := client.Freeze(ctx, c.slurmReq(jid))
+		if err != nil {
+			return err
+		}
+		trees[i] = rootPIDs(resp.GetState())
+		return nil
+	})
+	if err := c.failed("freeze", frozen); err != nil {
+		return nil, errors.Join(err, c.unfreeze(ctx, jid, frozen))
+	}
+
+	// Phase 2: dump, and commit only if all ranks succeed
+
+	log.Info().Msg("dumping all ranks")
+
+	now := time.Now()
+	paths := make([][]string, len(c.nodes))
+
+	dumped := c.fanOut(func(i int, client *client.Client) error {
+		for j, pid := range trees[i] {
+			req := c.slurmReq(jid)
+			req.Details.Slurm.PID = pid
+			req.Dir = dir
+			req.Name = fmt.Sprintf(DUMP_NAME_FORMAT, jid, c.nodes[i], now.Unix(), j)
+			req.Compression = compression
+			req.Criu = &criu_proto.CriuOpts{LeaveRunning: proto.Bool(true)}
+
+			resp, _, err := client.Dump(ctx, req)
+			if err != nil {
+				return fmt.Errorf("process tree %d: %w", pid, err)
+			}
+			if len(resp.GetPaths()) == 0 {
+				return fmt.Errorf("no checkpoint path returned for process tree %d", pid)
+			}
+			paths[i] = append(paths[i], resp.GetPaths()[0])
+		}
+		return nil
+	})
+	if err := c.failed("dump", dumped); err != nil {
+		return nil, errors.Join(err, c.unfreeze(ctx, jid, frozen))
+	}
+
+	manifest := &Manifest{JobID: jid, Time: now.UnixMilli()}
+	for i, node := range c.nodes {
+		manifest.Ranks = append(manifest.Ranks, &Rank{Node: node, Paths: paths[i]})
+	}
+
+	if err := WriteManifest(dir, manifest); err != nil {
+		return nil, errors.Join(err, c.unfreeze(ctx, jid, frozen))
+	}
+
+	log.Info().Msg("committed checkpoint")
+
+	if leaveRunning {
+		return manifest, c.unfreeze(ctx, jid, frozen)
+	}
+
+	return manifest, CancelJob(ctx, jid)
+}
+
+// Restore restores each rank of the manifest into the job, on the node at the same position
+// in the job's allocation. The job's steps must already be running on all nodes, as
+// placeholders (e.g. 'sleep infinity') that keep them alive. Each process tree of a rank is
+// restored into a fresh task cgroup of the job on the node, apart from the placeholders
+// (with cgroup v1, which has no task cgroups, into the cgroup of the first step).
+// All ranks are restored stopped, and only resumed once all of them are restored, so no rank
+// talks to a peer that is not restored yet. If any rank fails to restore or resume, the job is
+// cancelled, as already restored ranks cannot be resumed alone.
+func (c *Coordinator) Restore(ctx context.Context, manifest *Manifest, jid uint32) error {
+	if len(manifest.Ranks) != len(c.nodes) {
+		return fmt.Errorf("checkpoint has %d ranks, but job %d has %d nodes", len(manifest.Ranks), jid, len(c.nodes))
+	}
+
+	log := log.With().Uint32("job_id", jid).Uint32("from_job_id", manifest.JobID).Int("nodes", len(c.nodes)).Logger()
+
+	// Phase 1: restore, leaving all ranks stopped
+
+	log.Info().Msg("restoring all ranks")
+
+	pids := make([][]uint32, len(c.nodes))
+
+	restored := c.fanOut(func(i int, client *client.Client) error {
+		for _, path := range manifest.Ranks[i].Paths {
+			resp, _, err := client.Restore(ctx, &daemon.RestoreReq{
+				Type:    "slurm",
+				Path:    path,
+				Details: &daemon.Details{Slurm: &slurm_proto.Slurm{JobID: jid}},
+				Criu:    &criu_proto.CriuOpts{LeaveStopped: proto.Bool(true)},
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			pids[i] = append(pids[i], resp.GetPID())
+		}
+		return nil
+	})
+	if err := c.failed("restore", restored); err != nil {
+		return errors.Join(err, CancelJob(ctx, jid))
+	}
+
+	// Phase 2: resume all ranks, only once all are restored
+
+	log.Info().Msg("resuming all ranks")
+
+	resumed := c.fanOut(func(i int, client *client.Client) error {
+		for _, pid := range pids[i] {
+			_, _, err := client.Unfreeze(ctx, &daemon.DumpReq{
+				Type:    "process",
+				Details: &daemon.Details{Process: &daemon.Process{PID: pid}},
+			})
+			if err != nil {
+				return fmt.Errorf("process tree %d: %w", pid, err)
+			}
+		}
+		return nil
+	})
+	if err := c.failed("resume", resumed); err != nil {
+		return errors.Join(err, CancelJob(ctx, jid))
+	}
+
+	return nil
+}
+
+// Reads the manifest of the job's checkpoint from the directory.
+func ReadManifest(dir string, jid uint32) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf(MANIFEST_FORMAT, jid)))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Writes the manifest to the directory. The write is atomic, so a manifest is
+// either fully written or not at all.
+func WriteManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf(MANIFEST_FORMAT, manifest.JobID))
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+func (c *Coordinator) slurmReq(jid uint32) *daemon.DumpReq {
+	return &daemon.DumpReq{
+		Type:    "slurm",
+		Details: &daemon.Details{Slurm: &slurm_proto.Slurm{JobID: jid}},
+	}
+}
+
+// Returns the roots of the process trees frozen on a node. With several, the
+// state has no PID and a child per tree.
+func rootPIDs(state *daemon.ProcessState) []uint32 {
+	if state.GetPID() != 0 {
+		return []uint32{state.GetPID()}
+	}
+	var pids []uint32
+	for _, child := range state.GetChildren() {
+		pids = append(pids, child.GetPID())
+	}
+	return pids
+}
+
+// Runs the function for all nodes in parallel, returning the error for each node.
+func (c *Coordinator) fanOut(f func(i int, client *client.Client) error) []error {
+	errs := make([]error, len(c.clients))
+
+	var wg sync.WaitGroup
+	for i, client := range c.clients {
+		wg.Go(func() {
+			errs[i] = f(i, client)
+		})
+	}
+	wg.Wait()
+
+	return errs
+}
+
+func (c *Coordinator) failed(phase string, errs []error) error {
+	var failed []error
+	for i, err := range errs {
+		if err != nil {
+			log.Error().Err(err).Str("node", c.nodes[i]).Msgf("%s failed", phase)
+			failed = append(failed, fmt.Errorf("%s failed on node %s: %w", phase, c.nodes[i], err))
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// Unfreezes the ranks that were frozen, i.e. those with no freeze error.
+func (c *Coordinator) unfreeze(ctx context.Context, jid uint32, frozen []error) error {
+	errs := c.fanOut(func(i int, client *client.Client) error {
+		if frozen[i] != nil {
+			return nil
+		}
+		_, _, err := client.Unfreeze(ctx, c.slurmReq(jid))
+		return err
+	})
+	return c.failed("unfreeze", errs)
+}
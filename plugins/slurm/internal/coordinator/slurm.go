@@ -0,0 +1,57 @@
+package coordinator
+
+// Queries and control of SLURM jobs through the SLURM CLI
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Returns the hostnames of the nodes allocated to the job, in allocation order.
+func JobNodes(ctx context.Context, jid uint32) ([]string, error) {
+	out, err := slurmCommand(ctx, "scontrol", "show", "job", "--oneliner", fmt.Sprint(jid))
+	if err != nil {
+		return nil, err
+	}
+
+	var nodeList string
+	for field := range strings.FieldsSeq(out) {
+		if list, ok := strings.CutPrefix(field, "NodeList="); ok {
+			nodeList = list
+			break
+		}
+	}
+	if nodeList == "" || nodeList == "(null)" {
+		return nil, fmt.Errorf("job %d has no allocated nodes", jid)
+	}
+
+	out, err = slurmCommand(ctx, "scontrol", "show", "hostnames", nodeList)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(out), nil
+}
+
+// Cancels the job, along with all its steps.
+func CancelJob(ctx context.Context, jid uint32) error {
+	_, err := slurmCommand(ctx, "scancel", fmt.Sprint(jid))
+	return err
+}
+
+func slurmCommand(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
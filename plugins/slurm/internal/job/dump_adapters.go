@@ -22,7 +22,7 @@ const SLURM_SCRIPT_FILE = "slurm_script"
 func GetSlurmJobForDump(next types.Dump) types.Dump {
 	return func(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daemon.DumpReq) (code func() <-chan int, err error) {
 		jid := req.GetDetails().GetSlurm().GetJobID()
-		pid := req.GetDetails().GetSlurm().GetPID()
+		pid := resp.GetState().GetPID() // set by SetPIDForDump, even if only the job ID is known
 
 		path, err := ResolveJobCgroupPath(jid, pid)
 		if err != nil {
@@ -44,6 +44,10 @@ func GetSlurmJobForDump(next types.Dump) types.Dump {
 	}
 }
 
+// Sets the PID to dump. If only the job ID is known, e.g. when coordinated across nodes, uses
+// the root of the job's process tree on this node. As each task of the job is a separate process
+// tree, a job with several can only be frozen or unfrozen as a whole, and each tree must be dumped
+// by its PID.
 func SetPIDForDump(next types.Dump) types.Dump {
 	return func(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daemon.DumpReq) (code func() <-chan int, err error) {
 		if resp.State == nil {
@@ -53,7 +57,18 @@ func SetPIDForDump(next types.Dump) types.Dump {
 		if resp.GetState().GetPID() == 0 {
 			pid := req.GetDetails().GetSlurm().GetPID()
 			if pid == 0 {
-				return nil, status.Errorf(codes.NotFound, "failed to get PID from slurm details")
+				jid := req.GetDetails().GetSlurm().GetJobID()
+				roots, err := JobRootPIDs(jid)
+				if err != nil {
+					return nil, status.Errorf(codes.NotFound, "failed to get PID for slurm job %d: %v", jid, err)
+				}
+				if len(roots) > 1 {
+					if req.Action == daemon.DumpAction_DUMP {
+						return nil, status.Errorf(codes.FailedPrecondition, "slurm job %d has %d process trees on this node (PIDs %v), dump each by its PID", jid, len(roots), roots)
+					}
+					return forEachProcessTree(ctx, opts, resp, req, roots, next)
+				}
+				pid = roots[0]
 			}
 			resp.State.PID = pid
 		}
@@ -106,3 +121,57 @@ func DumpSlurmScript(next types.Dump) types.Dump {
 		return next(ctx, opts, resp, req)
 	}
 }
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Freezes or unfreezes each of the job's process trees, once per task cgroup. The state
+// in the response has no PID, with the state of each tree as a child. If freezing any
+// fails, the ones already frozen are unfrozen.
+func forEachProcessTree(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daemon.DumpReq, roots []uint32, next types.Dump) (code func() <-chan int, err error) {
+	jid := req.GetDetails().GetSlurm().GetJobID()
+	done := make(map[string]bool)
+
+	defer func() {
+		if err == nil || req.Action != daemon.DumpAction_FREEZE_ONLY {
+			return
+		}
+		for path := range done {
+			manager, managerErr := cgroupsManager.New(&cgroups.Cgroup{Path: path, Resources: &cgroups.Resources{}})
+			if managerErr == nil {
+				managerErr = manager.Freeze(cgroups.Thawed)
+			}
+			if managerErr != nil {
+				log.Warn().Err(managerErr).Str("path", path).Uint32("job_id", jid).Msg("failed to unfreeze cgroup after failed freeze")
+			}
+		}
+	}()
+
+	for _, root := range roots {
+		tree := &daemon.DumpResp{State: &daemon.ProcessState{PID: root}}
+
+		err = utils.FillProcessState(ctx, root, tree.State, true)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to fill process state: %v", err)
+		}
+
+		var path string
+		path, err = ResolveJobCgroupPath(jid, root)
+		if err != nil {
+			return nil, err
+		}
+		if !done[path] {
+			_, err = next(ctx, opts, tree, req)
+			if err != nil {
+				return nil, err
+			}
+			done[path] = true
+		}
+
+		resp.State.Children = append(resp.State.Children, tree.State)
+		resp.Messages = append(resp.Messages, tree.Messages...)
+	}
+
+	return nil, nil
+}
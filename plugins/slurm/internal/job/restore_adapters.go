@@ -38,6 +38,13 @@ func GetSlurmJobForRestore(next types.Restore) types.Restore {
 			return nil, err
 		}
 
+		// Without a process of the job to restore next to, restore into a fresh task
+		// cgroup, apart from the processes already running in the job's tasks
+		if pid == 0 && cgroups.IsCgroup2UnifiedMode() {
+			path = NewTaskCgroupPath(path)
+			log.Debug().Str("path", path).Uint32("job_id", jid).Msg("restoring into new task cgroup")
+		}
+
 		config := &cgroups.Cgroup{
 			Path:      path,
 			Resources: &cgroups.Resources{},
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +19,8 @@ const (
 	cgroupRetryInterval = 500 * time.Millisecond
 )
 
+// Returns the cgroup of the job's process with the PID, or if not known, the first of the
+// job's task cgroups on this node (see JobCgroupPaths).
 func ResolveJobCgroupPath(jid uint32, pid uint32) (string, error) {
 	if pid > 0 {
 		if path, err := cgroupPathFromProc(pid); err == nil {
@@ -29,34 +33,88 @@ func ResolveJobCgroupPath(jid uint32, pid uint32) (string, error) {
 			log.Debug().Err(err).Uint32("job_id", jid).Uint32("pid", pid).Msg("could not resolve cgroup from /proc, falling back to job-scoped lookup")
 		}
 	}
-	if path, err := getJobCgroupPathV2(jid); err == nil {
-		return path, nil
+	paths, err := JobCgroupPaths(jid)
+	if err != nil {
+		return "", err
+	}
+	return paths[0], nil
+}
+
+// Returns the path of a new task cgroup next to the given one (cgroup v2), for restoring a
+// process tree into. Being a task cgroup of the same step, it's found like any other by
+// JobCgroupPaths. The cgroup is only created when a process is first added to it.
+func NewTaskCgroupPath(path string) string {
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf("task_cedana_%d", time.Now().UnixNano()))
+}
+
+// Returns the cgroups of all the job's tasks on this node, across all its steps, sorted.
+func JobCgroupPaths(jid uint32) ([]string, error) {
+	if paths, err := getJobCgroupPathsV2(jid); err == nil {
+		return paths, nil
+	}
+	return getJobCgroupPathsV1(jid)
+}
+
+// Returns the root PIDs of all the job's process trees on this node, sorted. Each task of
+// the job is a separate process tree, and a task cgroup may hold more than one (e.g. if restored).
+func JobRootPIDs(jid uint32) ([]uint32, error) {
+	paths, err := JobCgroupPaths(jid)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []uint32
+	for _, path := range paths {
+		pids, err := RootPIDsInCgroup(path)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, pids...)
 	}
-	return getJobCgroupPathV1(jid)
+
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no processes in cgroups of slurm job %d", jid)
+	}
+
+	slices.Sort(roots)
+
+	return roots, nil
 }
 
-func getJobCgroupPathV2(jid uint32) (string, error) {
+func getJobCgroupPathsV2(jid uint32) ([]string, error) {
 	const root = "/sys/fs/cgroup"
-	leaf := fmt.Sprintf("job_%d/step_batch/user/task_special", jid)
-	patterns := []string{
-		fmt.Sprintf("%s/system.slice/*slurmstepd*.scope/%s", root, leaf),
-		fmt.Sprintf("%s/system.slice/*.scope/system.slice/*slurmstepd*.scope/%s", root, leaf),
+	// Nodes other than the batch host only run the job's numbered steps
+	leaves := []string{
+		fmt.Sprintf("job_%d/step_batch/user/task_*", jid),
+		fmt.Sprintf("job_%d/step_[0-9]*/user/task_*", jid),
+	}
+	var patterns []string
+	for _, leaf := range leaves {
+		patterns = append(patterns,
+			fmt.Sprintf("%s/system.slice/*slurmstepd*.scope/%s", root, leaf),
+			fmt.Sprintf("%s/system.slice/*.scope/system.slice/*slurmstepd*.scope/%s", root, leaf),
+		)
 	}
 
 	for attempt := range cgroupRetryAttempts {
+		var paths []string
 		for _, pattern := range patterns {
-			if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
-				path := matches[0][len(root):]
-				log.Debug().Str("path", path).Uint32("job_id", jid).Int("attempt", attempt).Msg("found cgroup path (v2 by job id)")
-				return path, nil
+			matches, _ := filepath.Glob(pattern)
+			for _, match := range matches {
+				paths = append(paths, match[len(root):])
 			}
 		}
+		if len(paths) > 0 {
+			slices.Sort(paths)
+			log.Debug().Strs("paths", paths).Uint32("job_id", jid).Int("attempt", attempt).Msg("found cgroup paths (v2 by job id)")
+			return paths, nil
+		}
 		if attempt < cgroupRetryAttempts-1 {
 			time.Sleep(cgroupRetryInterval)
 		}
 	}
 
-	return "", status.Errorf(codes.NotFound, "cgroup v2 path for slurm job %d not found", jid)
+	return nil, status.Errorf(codes.NotFound, "cgroup v2 paths for slurm job %d not found", jid)
 }
 
 func selfInJobCgroup(pid, jid uint32) bool {
@@ -85,25 +143,87 @@ func cgroupPathFromProc(pid uint32) (string, error) {
 	return "", fmt.Errorf("no cgroup v2 entry for process %d", pid)
 }
 
-func getJobCgroupPathV1(jid uint32) (string, error) {
-	v1Pattern := fmt.Sprintf("/sys/fs/cgroup/freezer/slurm*/uid_*/job_%d/step_batch", jid)
+func getJobCgroupPathsV1(jid uint32) ([]string, error) {
+	const root = "/sys/fs/cgroup"
+	// The v1 freezer hierarchy has no cgroup per task, only per step
+	patterns := []string{
+		fmt.Sprintf("%s/freezer/slurm*/uid_*/job_%d/step_batch", root, jid),
+		fmt.Sprintf("%s/freezer/slurm*/uid_*/job_%d/step_[0-9]*", root, jid),
+	}
 
 	for attempt := range cgroupRetryAttempts {
-		matches, err := filepath.Glob(v1Pattern)
-		if err != nil {
-			return "", status.Errorf(codes.Internal, "failed to glob cgroup paths for slurm job %d with pattern %s: %v", jid, v1Pattern, err)
+		var paths []string
+		for _, pattern := range patterns {
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to glob cgroup paths for slurm job %d with pattern %s: %v", jid, pattern, err)
+			}
+			for _, match := range matches {
+				paths = append(paths, match[len(root):])
+			}
 		}
-		if len(matches) > 0 {
-			path := matches[0][len("/sys/fs/cgroup"):]
-			log.Debug().Str("path", path).Uint32("job_id", jid).Int("attempt", attempt).Msg("found cgroup path (v1)")
-			return path, nil
+		if len(paths) > 0 {
+			slices.Sort(paths)
+			log.Debug().Strs("paths", paths).Uint32("job_id", jid).Int("attempt", attempt).Msg("found cgroup paths (v1)")
+			return paths, nil
 		}
 
 		if attempt < cgroupRetryAttempts-1 {
-			log.Debug().Uint32("job_id", jid).Int("attempt", attempt).Msg("cgroup path not found, retrying")
+			log.Debug().Uint32("job_id", jid).Int("attempt", attempt).Msg("cgroup paths not found, retrying")
 			time.Sleep(cgroupRetryInterval)
 		}
 	}
 
-	return "", status.Errorf(codes.NotFound, "cgroup path for slurm job %d does not exist after %d attempts", jid, cgroupRetryAttempts)
+	return nil, status.Errorf(codes.NotFound, "cgroup paths for slurm job %d do not exist after %d attempts", jid, cgroupRetryAttempts)
+}
+
+// Returns the roots of the process trees in the cgroup, i.e. the processes whose
+// parent is not in the cgroup, sorted. Returns none if the cgroup is empty.
+func RootPIDsInCgroup(path string) ([]uint32, error) {
+	data, err := os.ReadFile(filepath.Join("/sys/fs/cgroup", path, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make(map[uint32]bool)
+	for field := range strings.FieldsSeq(string(data)) {
+		pid, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pid %q in cgroup %s", field, path)
+		}
+		pids[uint32(pid)] = true
+	}
+
+	var roots []uint32
+	for pid := range pids {
+		ppid, err := parentPID(pid)
+		if err != nil {
+			continue // exited
+		}
+		if !pids[ppid] {
+			roots = append(roots, pid)
+		}
+	}
+
+	slices.Sort(roots)
+
+	return roots, nil
+}
+
+func parentPID(pid uint32) (uint32, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// The command name may contain spaces, so parse after its closing paren
+	_, rest, ok := strings.Cut(string(data), ") ")
+	if !ok {
+		return 0, fmt.Errorf("invalid stat for process %d", pid)
+	}
+	fields := strings.Fields(rest)
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("invalid stat for process %d", pid)
+	}
+	ppid, err := strconv.ParseUint(fields[1], 10, 32)
+	return uint32(ppid), err
 }
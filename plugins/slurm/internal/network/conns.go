@@ -0,0 +1,199 @@
+package network
+
+// TCP connections of a SLURM job, as seen live in /proc (like ss) or as saved in the
+// inet socket images of its checkpoint.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cedana/go-criu/v7/crit"
+	"github.com/cedana/go-criu/v7/crit/images/fdinfo"
+	"github.com/spf13/afero"
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/999f6631/include/net/tcp_states.h#L12
+const TCP_LISTEN = 0x0a
+
+// Conn is a TCP socket of the job. Listening sockets have no remote address.
+type Conn struct {
+	Local  netip.AddrPort
+	Remote netip.AddrPort
+}
+
+func (c Conn) IsListening() bool {
+	return !c.Remote.IsValid()
+}
+
+// Connections returns the TCP sockets open by any of the processes, read from the
+// socket tables of their network namespaces.
+func Connections(pids []int) ([]Conn, error) {
+	inodes := make(map[uint64]bool)
+	namespaces := make(map[string]int) // network namespace -> a PID in it
+
+	for _, pid := range pids {
+		ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/net", pid))
+		if err != nil {
+			continue // process exited
+		}
+		namespaces[ns] = pid
+
+		fds, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fmt.Sprintf("/proc/%d/fd", pid), fd.Name()))
+			if err != nil {
+				continue
+			}
+			var inode uint64
+			if _, err := fmt.Sscanf(link, "socket:[%d]", &inode); err == nil {
+				inodes[inode] = true
+			}
+		}
+	}
+
+	var conns []Conn
+	for _, pid := range namespaces {
+		for _, table := range []string{"tcp", "tcp6"} {
+			file, err := os.Open(fmt.Sprintf("/proc/%d/net/%s", pid, table))
+			if os.IsNotExist(err) {
+				continue // IPv6 disabled
+			}
+			if err != nil {
+				return nil, err
+			}
+			found, err := parseSocketTable(file, inodes)
+			file.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s socket table: %w", table, err)
+			}
+			conns = append(conns, found...)
+		}
+	}
+
+	return conns, nil
+}
+
+// ConnectionsFromImages returns the TCP sockets saved in the images of a checkpoint.
+func ConnectionsFromImages(fs afero.Fs) ([]Conn, error) {
+	file, err := fs.Open("files.img")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := crit.New(file, nil, "", false, true).Decode(&fdinfo.FileEntry{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode files image: %w", err)
+	}
+
+	var conns []Conn
+	for _, entry := range img.Entries {
+		isk := entry.Message.(*fdinfo.FileEntry).GetIsk()
+		if isk == nil || isk.GetProto() != unix.IPPROTO_TCP {
+			continue
+		}
+		local, ok := imageAddr(isk.GetSrcAddr(), isk.GetSrcPort())
+		if !ok {
+			continue
+		}
+		conn := Conn{Local: local}
+		if isk.GetState() != TCP_LISTEN {
+			if conn.Remote, ok = imageAddr(isk.GetDstAddr(), isk.GetDstPort()); !ok {
+				continue
+			}
+		}
+		conns = append(conns, conn)
+	}
+
+	return conns, nil
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+// Parses a /proc/net/tcp{,6} table, returning the sockets with the given inodes.
+func parseSocketTable(r io.Reader, inodes map[uint64]bool) (conns []Conn, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil || !inodes[inode] {
+			continue
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state '%s'", fields[3])
+		}
+		local, err := tableAddr(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		conn := Conn{Local: local}
+		if state != TCP_LISTEN {
+			if conn.Remote, err = tableAddr(fields[2]); err != nil {
+				return nil, err
+			}
+		}
+		conns = append(conns, conn)
+	}
+
+	return conns, scanner.Err()
+}
+
+// Parses an address of a /proc/net socket table, e.g. "0100007F:1F90", where the address
+// is made of 32-bit words in host byte order.
+func tableAddr(s string) (netip.AddrPort, error) {
+	addrHex, portHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return netip.AddrPort{}, fmt.Errorf("invalid address '%s'", s)
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("invalid port in '%s'", s)
+	}
+	raw, err := hex.DecodeString(addrHex)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return netip.AddrPort{}, fmt.Errorf("invalid address '%s'", s)
+	}
+
+	words := make([]uint32, len(raw)/4)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint32(raw[i*4:])
+	}
+	addr, ok := imageAddr(words, uint32(port))
+	if !ok {
+		return netip.AddrPort{}, fmt.Errorf("invalid address '%s'", s)
+	}
+	return addr, nil
+}
+
+// Converts an address saved by CRIU, as 32-bit words in host byte order.
+func imageAddr(words []uint32, port uint32) (netip.AddrPort, bool) {
+	raw := make([]byte, 4*len(words))
+	for i, word := range words {
+		binary.NativeEndian.PutUint32(raw[i*4:], word)
+	}
+	addr, ok := netip.AddrFromSlice(raw)
+	if !ok {
+		return netip.AddrPort{}, false
+	}
+	return netip.AddrPortFrom(addr.Unmap(), uint16(port)), true
+}
@@ -0,0 +1,60 @@
+package network
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestParseSocketTable(t *testing.T) {
+	table := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 111 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:A2C4 0100007F:1F90 01 00000000:00000000 00:00000000 00000000  1000        0 222 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:1F90 0100007F:A2C4 01 00000000:00000000 00:00000000 00000000  1000        0 333 1 0000000000000000 20 4 30 10 -1
+`
+	conns, err := parseSocketTable(strings.NewReader(table), map[uint64]bool{111: true, 222: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Conn{
+		{Local: netip.MustParseAddrPort("0.0.0.0:8080")},
+		{Local: netip.MustParseAddrPort("127.0.0.1:41668"), Remote: netip.MustParseAddrPort("127.0.0.1:8080")},
+	}
+	if len(conns) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, conns)
+	}
+	for i := range expected {
+		if conns[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], conns[i])
+		}
+	}
+	if !conns[0].IsListening() || conns[1].IsListening() {
+		t.Errorf("unexpected listening sockets: %v", conns)
+	}
+}
+
+func TestParseSocketTable6(t *testing.T) {
+	table := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0000000000000000FFFF00000100007F:1F90 0000000000000000FFFF00000200007F:C350 01 00000000:00000000 00:00000000 00000000  1000        0 444 1 0000000000000000 20 4 30 10 -1
+   1: 00000000000000000000000001000000:1F90 00000000000000000000000001000000:C350 01 00000000:00000000 00:00000000 00000000  1000        0 555 1 0000000000000000 20 4 30 10 -1
+`
+	conns, err := parseSocketTable(strings.NewReader(table), map[uint64]bool{444: true, 555: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Conn{
+		// IPv4-mapped, as the packets are matched as IPv4
+		{Local: netip.MustParseAddrPort("127.0.0.1:8080"), Remote: netip.MustParseAddrPort("127.0.0.2:50000")},
+		{Local: netip.MustParseAddrPort("[::1]:8080"), Remote: netip.MustParseAddrPort("[::1]:50000")},
+	}
+	if len(conns) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, conns)
+	}
+	for i := range expected {
+		if conns[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], conns[i])
+		}
+	}
+}
@@ -2,21 +2,46 @@ package network
 
 import (
 	"context"
+	"fmt"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
 	"github.com/cedana/cedana/pkg/criu"
 	"github.com/cedana/cedana/pkg/types"
+	slurm_keys "github.com/cedana/cedana/plugins/slurm/pkg/keys"
+	"github.com/opencontainers/cgroups"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// Locks the network of the job while it's being dumped, so peers of its TCP connections
+// see no change in state. CRIU unlocks it if the job is left running, or if the dump fails.
+// Otherwise, the lock is kept until the job is restored, like for runc containers.
 func LockNetworkBeforeDump(next types.Dump) types.Dump {
 	return func(ctx context.Context, opts types.Opts, resp *daemon.DumpResp, req *daemon.DumpReq) (code func() <-chan int, err error) {
+		jid := req.GetDetails().GetSlurm().GetJobID()
+
+		manager, ok := ctx.Value(slurm_keys.CGROUP_MANAGER_CONTEXT_KEY).(cgroups.Manager)
+		if !ok {
+			return nil, status.Errorf(codes.FailedPrecondition, "failed to get cgroup manager from context")
+		}
+
 		callback := &criu.NotifyCallback{
 			NetworkLockFunc: func(ctx context.Context) error {
-				// Not implemented, yet
-				// see: libcontainer/criu_linux.go -> lockNetwork
-				log.Warn().Msg("not locking network - not implemented")
-				return nil
+				pids, err := manager.GetAllPids()
+				if err != nil {
+					return fmt.Errorf("failed to get PIDs of job: %w", err)
+				}
+				conns, err := Connections(pids)
+				if err != nil {
+					return fmt.Errorf("failed to get TCP connections of job: %w", err)
+				}
+				log.Debug().Uint32("job_id", jid).Int("sockets", len(conns)).Msg("locking network")
+				return Lock(ctx, jid, conns)
+			},
+			NetworkUnlockFunc: func(ctx context.Context) error {
+				log.Debug().Uint32("job_id", jid).Msg("unlocking network")
+				return Unlock(ctx, jid)
 			},
 		}
 		opts.CRIUCallback.Include(callback)
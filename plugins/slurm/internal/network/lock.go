@@ -0,0 +1,228 @@
+package network
+
+// Network locking of a SLURM job, by dropping all packets of its TCP connections and
+// to its listening ports. As SLURM jobs share the network namespace of the node, these
+// are matched by address, so they can be dropped even when the job's sockets don't
+// exist, i.e. after the job is killed by a dump and until it's restored.
+// Uses nftables if available, otherwise iptables.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// nftables table, or iptables chain prefix, holding the lock rules of a job
+	LOCK_NAME_FORMAT = "CEDANA_SLURM_%d"
+
+	// Before conntrack and any other filter, so nothing leaks through
+	NFT_PRIORITY = -300
+)
+
+// Locks the network of the job, given its TCP sockets. Locking an already locked job
+// replaces its rules.
+func Lock(ctx context.Context, jid uint32, conns []Conn) error {
+	name := fmt.Sprintf(LOCK_NAME_FORMAT, jid)
+
+	if _, err := exec.LookPath("nft"); err == nil {
+		return nftLock(ctx, name, conns)
+	}
+	if _, err := exec.LookPath("iptables"); err == nil {
+		return iptablesLock(ctx, name, conns)
+	}
+
+	return fmt.Errorf("neither nft nor iptables is available")
+}
+
+// Unlocks the network of the job. Unlocking a job that is not locked is a no-op.
+func Unlock(ctx context.Context, jid uint32) error {
+	name := fmt.Sprintf(LOCK_NAME_FORMAT, jid)
+
+	var errs []error
+	if _, err := exec.LookPath("nft"); err == nil {
+		errs = append(errs, nftUnlock(ctx, name))
+	}
+	if _, err := exec.LookPath("iptables"); err == nil {
+		errs = append(errs, iptablesUnlock(ctx, name))
+	}
+
+	return errors.Join(errs...)
+}
+
+//////////////////////////
+//// Helper Functions ////
+//////////////////////////
+
+func nftLock(ctx context.Context, name string, conns []Conn) error {
+	var conns4, conns6, ports []string
+	for _, conn := range conns {
+		if conn.IsListening() {
+			ports = append(ports, fmt.Sprintf("%d", conn.Local.Port()))
+			continue
+		}
+		element := fmt.Sprintf("%s . %d . %s . %d", conn.Local.Addr(), conn.Local.Port(), conn.Remote.Addr(), conn.Remote.Port())
+		if conn.Local.Addr().Is4() {
+			conns4 = append(conns4, element)
+		} else {
+			conns6 = append(conns6, element)
+		}
+	}
+
+	sets := []struct {
+		name, typ string
+		elements  []string
+	}{
+		{"conns4", "ipv4_addr . inet_service . ipv4_addr . inet_service", conns4},
+		{"conns6", "ipv6_addr . inet_service . ipv6_addr . inet_service", conns6},
+		{"ports", "inet_service", ports},
+	}
+	rules := map[string][]string{
+		"input": {
+			"ip daddr . tcp dport . ip saddr . tcp sport @conns4 drop",
+			"ip6 daddr . tcp dport . ip6 saddr . tcp sport @conns6 drop",
+			"tcp dport @ports drop",
+		},
+		"output": {
+			"ip saddr . tcp sport . ip daddr . tcp dport @conns4 drop",
+			"ip6 saddr . tcp sport . ip6 daddr . tcp dport @conns6 drop",
+		},
+	}
+
+	// The table is added before being deleted so the deletion never fails, and the
+	// whole script is applied atomically
+	var script strings.Builder
+	fmt.Fprintf(&script, "add table inet %s\n", name)
+	fmt.Fprintf(&script, "delete table inet %s\n", name)
+	fmt.Fprintf(&script, "table inet %s {\n", name)
+	for _, set := range sets {
+		fmt.Fprintf(&script, "\tset %s {\n", set.name)
+		fmt.Fprintf(&script, "\t\ttype %s\n", set.typ)
+		if len(set.elements) > 0 {
+			fmt.Fprintf(&script, "\t\telements = { %s }\n", strings.Join(set.elements, ", "))
+		}
+		fmt.Fprintf(&script, "\t}\n")
+	}
+	for _, hook := range []string{"input", "output"} {
+		fmt.Fprintf(&script, "\tchain %s {\n", hook)
+		fmt.Fprintf(&script, "\t\ttype filter hook %s priority %d; policy accept;\n", hook, NFT_PRIORITY)
+		for _, rule := range rules[hook] {
+			fmt.Fprintf(&script, "\t\t%s\n", rule)
+		}
+		fmt.Fprintf(&script, "\t}\n")
+	}
+	fmt.Fprintf(&script, "}\n")
+
+	return run(ctx, script.String(), "nft", "-f", "-")
+}
+
+func nftUnlock(ctx context.Context, name string) error {
+	script := fmt.Sprintf("add table inet %[1]s\ndelete table inet %[1]s\n", name)
+	return run(ctx, script, "nft", "-f", "-")
+}
+
+func iptablesLock(ctx context.Context, name string, conns []Conn) (err error) {
+	if err := iptablesUnlock(ctx, name); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, iptablesUnlock(ctx, name))
+		}
+	}()
+
+	for _, iptables := range iptablesBinaries() {
+		for hook, chain := range iptablesChains(name) {
+			if err := run(ctx, "", iptables, "-w", "-N", chain); err != nil {
+				return err
+			}
+			for _, conn := range conns {
+				if conn.Local.Addr().Is4() != (iptables == "iptables") {
+					continue
+				}
+				for _, rule := range iptablesRules(hook, conn) {
+					if err := run(ctx, "", iptables, append([]string{"-w", "-A", chain}, rule...)...); err != nil {
+						return err
+					}
+				}
+			}
+			if err := run(ctx, "", iptables, "-w", "-I", hook, "-j", chain); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func iptablesUnlock(ctx context.Context, name string) error {
+	var errs []error
+	for _, iptables := range iptablesBinaries() {
+		for hook, chain := range iptablesChains(name) {
+			// Nothing to do if the chain does not exist
+			if run(ctx, "", iptables, "-w", "-n", "-L", chain) != nil {
+				continue
+			}
+			for run(ctx, "", iptables, "-w", "-C", hook, "-j", chain) == nil {
+				if err := run(ctx, "", iptables, "-w", "-D", hook, "-j", chain); err != nil {
+					errs = append(errs, err)
+					break
+				}
+			}
+			errs = append(errs, run(ctx, "", iptables, "-w", "-F", chain))
+			errs = append(errs, run(ctx, "", iptables, "-w", "-X", chain))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Chains of the lock, by the built-in chain they are jumped to from
+func iptablesChains(name string) map[string]string {
+	return map[string]string{
+		"INPUT":  name + "_IN",
+		"OUTPUT": name + "_OUT",
+	}
+}
+
+func iptablesRules(hook string, conn Conn) [][]string {
+	if conn.IsListening() {
+		if hook != "INPUT" {
+			return nil
+		}
+		return [][]string{{"-p", "tcp", "--dport", fmt.Sprint(conn.Local.Port()), "-j", "DROP"}}
+	}
+	src, dst := conn.Local, conn.Remote
+	if hook == "INPUT" {
+		src, dst = dst, src
+	}
+	return [][]string{{
+		"-p", "tcp",
+		"-s", src.Addr().String(), "--sport", fmt.Sprint(src.Port()),
+		"-d", dst.Addr().String(), "--dport", fmt.Sprint(dst.Port()),
+		"-j", "DROP",
+	}}
+}
+
+func iptablesBinaries() []string {
+	binaries := []string{"iptables"}
+	if _, err := exec.LookPath("ip6tables"); err == nil {
+		binaries = append(binaries, "ip6tables")
+	}
+	return binaries
+}
+
+func run(ctx context.Context, stdin string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -2,20 +2,43 @@ package network
 
 import (
 	"context"
+	"fmt"
 
 	"buf.build/gen/go/cedana/cedana/protocolbuffers/go/daemon"
+	criu_proto "buf.build/gen/go/cedana/criu/protocolbuffers/go/criu"
 	"github.com/cedana/cedana/pkg/criu"
 	"github.com/cedana/cedana/pkg/types"
 	"github.com/rs/zerolog/log"
 )
 
+// Keeps the network of the job locked while it's being restored, until CRIU unlocks it
+// just before resuming. The TCP connections to lock are read from the images, as the
+// job's sockets don't exist yet. This replaces any lock kept since the dump. The lock
+// is removed if the restore fails.
 func UnlockNetworkAfterRestore(next types.Restore) types.Restore {
 	return func(ctx context.Context, opts types.Opts, resp *daemon.RestoreResp, req *daemon.RestoreReq) (code func() <-chan int, err error) {
+		jid := req.GetDetails().GetSlurm().GetJobID()
+
 		callback := &criu.NotifyCallback{
+			NetworkLockFunc: func(ctx context.Context) error {
+				if opts.DumpFs == nil {
+					return fmt.Errorf("dump filesystem not available")
+				}
+				conns, err := ConnectionsFromImages(opts.DumpFs)
+				if err != nil {
+					return fmt.Errorf("failed to get TCP connections from images: %w", err)
+				}
+				log.Debug().Uint32("job_id", jid).Int("sockets", len(conns)).Msg("locking network")
+				return Lock(ctx, jid, conns)
+			},
 			NetworkUnlockFunc: func(ctx context.Context) error {
-				// Not implemented, yet
-				// see: libcontainer/criu_linux.go -> unlockNetwork
-				log.Warn().Msg("not unlocking network - not implemented")
+				log.Debug().Uint32("job_id", jid).Msg("unlocking network")
+				return Unlock(ctx, jid)
+			},
+			FinalizeRestoreFunc: func(ctx context.Context, opts *criu_proto.CriuOpts, err error) error {
+				if err != nil {
+					return Unlock(ctx, jid)
+				}
 				return nil
 			},
 		}
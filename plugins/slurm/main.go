@@ -38,9 +38,7 @@ var (
 		job.SetPIDForDump,
 		job.GetSlurmJobForDump,
 
-		// TODO: this needs to be smarter (and not always modify CRIU opts)
-		// Otherwise it causes `operation failed (msg:Error (compel/src/lib/infect.c:262): Unseizable non-zombie 2443832 found`
-		// cgroup.UseCgroupFreezerIfAvailableForDump,
+		cgroup.UseCgroupFreezerIfFrozenForDump,
 
 		// TODO: this needs to be smarter (and not always modify CRIU opts)
 		// Otherwise it causes `operation failed (msg:Error (criu/cr-restore.c:1163): Unable to find an external pidns: extRootPIDNS`
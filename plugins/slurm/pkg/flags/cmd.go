@@ -6,6 +6,8 @@ type Flag struct {
 }
 
 var (
-	JidFlag = Flag{Full: "jid"}
-	PidFlag = Flag{Full: "pid"}
+	JidFlag  = Flag{Full: "jid"}
+	PidFlag  = Flag{Full: "pid"}
+	PortFlag = Flag{Full: "port"}
+	FromFlag = Flag{Full: "from"}
 )
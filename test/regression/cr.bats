@@ -110,6 +110,19 @@ teardown_file() {
     run kill $pid
 }
 
+# bats test_tags=dump
+@test "dump process (zstd compression)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression zstd
+
+    assert_exists "/tmp/$name.tar.zst"
+
+    run kill $pid
+}
+
 # bats test_tags=dump
 @test "dump process (zlib compression)" {
     "$WORKLOADS"/date-loop.sh &
@@ -213,6 +226,47 @@ teardown_file() {
     run cedana job kill "$jid"
 }
 
+# bats test_tags=dump
+@test "dump process (pre-dump)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression none --pre-dump 2
+
+    assert_exists "/tmp/$name"
+    assert_exists "/tmp/$name/pre-1"
+    assert_exists "/tmp/$name/pre-2"
+
+    run kill $pid
+}
+
+# bats test_tags=dump
+@test "dump process (invalid pre-dump)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+
+    run cedana dump process $pid --pre-dump -1
+    assert_failure
+
+    run kill $pid
+}
+
+# bats test_tags=dump
+@test "dump process (manifest)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression none
+
+    assert_exists "/tmp/$name/manifest.json"
+    run grep -q "process_state.json" "/tmp/$name/manifest.json"
+    assert_success
+
+    run kill $pid
+}
+
 # bats test_tags=dump
 @test "dump non-existent process" {
     id=$(unix_nano)
@@ -256,6 +310,26 @@ teardown_file() {
     run kill $pid
 }
 
+# bats test_tags=restore,tty
+@test "restore process (tty)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid" --tty
+
+    run cedana dump job "$jid"
+    assert_success
+
+    run cedana restore job "$jid"
+    assert_success
+
+    run cedana ps
+    assert_success
+    assert_output --partial "$jid"
+    assert_output --partial "[Attachable]"
+
+    run cedana job kill "$jid"
+}
+
 # bats test_tags=restore
 @test "restore process (tar compression)" {
     "$WORKLOADS"/date-loop.sh &
@@ -313,6 +387,25 @@ teardown_file() {
     run kill $pid
 }
 
+# bats test_tags=restore
+@test "restore process (zstd compression)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression zstd
+
+    assert_exists "/tmp/$name.tar.zst"
+
+    cedana restore process --path "/tmp/$name.tar.zst"
+
+    run ps --pid $pid
+    assert_success
+    assert_output --partial "$pid"
+
+    run kill $pid
+}
+
 # bats test_tags=restore
 @test "restore process (zlib compression)" {
     "$WORKLOADS"/date-loop.sh &
@@ -332,6 +425,67 @@ teardown_file() {
     run kill $pid
 }
 
+# bats test_tags=restore
+@test "restore process (pre-dump)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression gzip --pre-dump 2
+
+    assert_exists "/tmp/$name.tar.gz"
+
+    cedana restore process --path "/tmp/$name.tar.gz"
+
+    run ps --pid $pid
+    assert_success
+    assert_output --partial "$pid"
+
+    run kill $pid
+}
+
+# bats test_tags=restore,lazy
+@test "restore process (lazy)" {
+    if ! criu check --feature uffd-noncoop &>/dev/null; then
+        skip "userfaultfd not supported"
+    fi
+
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression none
+
+    assert_exists "/tmp/$name"
+
+    cedana restore process --path "/tmp/$name" --lazy
+
+    run ps --pid $pid
+    assert_success
+    assert_output --partial "$pid"
+
+    run kill $pid
+}
+
+# bats test_tags=restore,lazy
+@test "restore process (lazy, gzip compression)" {
+    if ! criu check --feature uffd-noncoop &>/dev/null; then
+        skip "userfaultfd not supported"
+    fi
+
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression gzip
+
+    assert_exists "/tmp/$name.tar.gz"
+
+    run cedana restore process --path "/tmp/$name.tar.gz" --lazy
+    assert_failure
+    assert_output --partial "uncompressed local checkpoint"
+}
+
 # bats test_tags=restore
 @test "restore process (compression invalid)" {
     "$WORKLOADS"/date-loop.sh &
@@ -348,6 +502,39 @@ teardown_file() {
     run kill $pid
 }
 
+# bats test_tags=restore
+@test "restore process (corrupted)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression none
+
+    img=$(find "/tmp/$name" -name "*.img" | head -n 1)
+    echo "corrupted" >> "$img"
+
+    run cedana restore process --path "/tmp/$name"
+    assert_failure
+    assert_output --partial "checksum mismatch"
+}
+
+# bats test_tags=restore
+@test "restore process (corrupted, gzip compression)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression none
+
+    img=$(find "/tmp/$name" -name "*.img" | head -n 1)
+    rm "$img"
+    tar -czf "/tmp/$name.tar.gz" -C "/tmp/$name" .
+
+    run cedana restore process --path "/tmp/$name.tar.gz"
+    assert_failure
+    assert_output --partial "missing"
+}
+
 # bats test_tags=restore
 @test "restore process (new job)" {
     jid=$(unix_nano)
@@ -368,6 +555,30 @@ teardown_file() {
     run cedana job kill "$jid"
 }
 
+# bats test_tags=restore
+@test "restore process (resource limits)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid" --cpus 0.5 --memory 64M --pids-limit 32
+
+    run cedana dump job "$jid"
+    assert_success
+    dump_file=$(echo "$output" | tail -n 1 | awk '{print $NF}')
+    assert_exists "$dump_file"
+
+    cedana restore job "$jid"
+
+    cgroup="/sys/fs/cgroup/cedana/$jid"
+    pid=$(pid_for_jid "$jid")
+
+    assert_file_contains "/proc/$pid/cgroup" "/cedana/$jid"
+    assert_equal "$(cat "$cgroup/cpu.max")" "50000 100000"
+    assert_equal "$(cat "$cgroup/memory.max")" "$((64 * 1024 * 1024))"
+    assert_equal "$(cat "$cgroup/pids.max")" "32"
+
+    run cedana job kill "$jid"
+}
+
 # bats test_tags=restore,manage
 @test "restore process (manage existing job)" {
     jid=$(unix_nano)
@@ -533,3 +744,467 @@ teardown_file() {
     run cedana restore job 999999999
     assert_failure
 }
+
+###############
+### Migrate ###
+###############
+
+# bats test_tags=migrate
+@test "migrate non-existent job" {
+    run cedana migrate job 999999999 --to 127.0.0.1:8080
+    assert_failure
+}
+
+# bats test_tags=migrate
+@test "migrate job (unreachable destination)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana migrate job "$jid" --to 127.0.0.1:1
+    assert_failure
+
+    # job must be left untouched
+    run cedana ps
+    assert_success
+    assert_output --partial "$jid"
+    assert_output --partial "running"
+
+    run cedana job kill "$jid"
+}
+
+################
+### Schedule ###
+################
+
+# bats test_tags=dump,schedule
+@test "schedule job" {
+    jid=$(unix_nano)
+    dir=/tmp/schedule-"$jid"
+    mkdir -p "$dir"
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana job schedule "$jid" --every 2s --keep 2 --dir "$dir" --compression gzip
+    assert_success
+
+    run cedana job schedules
+    assert_success
+    assert_output --partial "$jid"
+
+    cedana dump job "$jid" --dir "$dir" --leave-running
+
+    sleep 9
+
+    cedana job unschedule "$jid"
+    sleep 1
+
+    # older scheduled checkpoints must have been pruned, and the job left running
+    [[ $(ls "$dir" | grep -c "^scheduled-") -eq 2 ]]
+    [[ $(ls "$dir" | grep -vc "^scheduled-") -eq 1 ]]
+    run cedana ps
+    assert_output --partial "running"
+
+    run cedana job kill "$jid"
+    rm -rf "$dir"
+}
+
+# bats test_tags=dump,schedule
+@test "schedule job (invalid interval)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana job schedule "$jid" --every 0s
+    assert_failure
+
+    run cedana job kill "$jid"
+}
+
+# bats test_tags=dump,schedule
+@test "schedule non-existent job" {
+    run cedana job schedule 999999999 --every 10m
+    assert_failure
+}
+
+##########
+### GC ###
+##########
+
+# bats test_tags=dump,gc
+@test "checkpoint gc (dry run)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --leave-running --compression gzip
+    assert_success
+    dump_file=$(echo "$output" | tail -n 1 | awk '{print $NF}')
+
+    run cedana checkpoint gc "$jid" --max-age 1ms --dry-run
+    assert_success
+    assert_output --partial "$dump_file"
+
+    # nothing must be deleted
+    assert_exists "$dump_file"
+    run cedana checkpoints "$jid"
+    assert_output --partial "$dump_file"
+
+    run cedana job kill "$jid"
+}
+
+# bats test_tags=dump,gc
+@test "checkpoint gc (max count)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --leave-running --compression gzip
+    assert_success
+    dump_file=$(echo "$output" | tail -n 1 | awk '{print $NF}')
+
+    sleep 1
+
+    run cedana dump job "$jid" --leave-running --compression gzip
+    assert_success
+    dump_file2=$(echo "$output" | tail -n 1 | awk '{print $NF}')
+
+    run cedana checkpoint gc "$jid" --max-count 1
+    assert_success
+
+    # only the latest checkpoint must be kept
+    assert_not_exists "$dump_file"
+    assert_exists "$dump_file2"
+    run cedana checkpoints "$jid"
+    refute_output --partial "$dump_file"
+    assert_output --partial "$dump_file2"
+
+    run cedana job kill "$jid"
+}
+
+# bats test_tags=dump,gc
+@test "checkpoints kept when job deleted" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --compression gzip
+    assert_success
+    dump_file=$(echo "$output" | tail -n 1 | awk '{print $NF}')
+    assert_exists "$dump_file"
+
+    run cedana job delete "$jid"
+    assert_success
+
+    assert_exists "$dump_file"
+}
+
+# bats test_tags=gc
+@test "checkpoint gc (no policy)" {
+    run cedana checkpoint gc
+    assert_failure
+}
+
+###############
+### Inspect ###
+###############
+
+# bats test_tags=dump,inspect
+@test "inspect checkpoint (directory)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --leave-running --compression none
+    assert_success
+
+    id=$(cedana checkpoints "$jid" | grep -oE '[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}' | head -n 1)
+
+    run cedana checkpoint inspect "$id" --type fd
+    assert_success
+    assert_output --partial "date-loop.sh"
+
+    run cedana job kill "$jid"
+}
+
+# bats test_tags=dump,inspect
+@test "inspect checkpoint (compressed)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --leave-running --compression lz4
+    assert_success
+
+    id=$(cedana checkpoints "$jid" | grep -oE '[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}' | head -n 1)
+
+    run cedana checkpoint inspect "$id" --json
+    assert_success
+    assert_output --partial '"ps"'
+    assert_output --partial '"fd"'
+    assert_output --partial '"state"'
+    assert_output --partial "date-loop.sh"
+
+    run cedana checkpoint inspect "$id" --type ps --yaml
+    assert_success
+    assert_output --partial "views:"
+    refute_output --partial "fd:"
+
+    run cedana job kill "$jid"
+}
+
+# bats test_tags=inspect
+@test "inspect checkpoint (invalid type)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --leave-running --compression gzip
+    assert_success
+
+    id=$(cedana checkpoints "$jid" | grep -oE '[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}' | head -n 1)
+
+    run cedana checkpoint inspect "$id" --type invalid
+    assert_failure
+
+    run cedana job kill "$jid"
+}
+
+# bats test_tags=inspect
+@test "inspect non-existent checkpoint" {
+    run cedana checkpoint inspect non-existent
+    assert_failure
+}
+
+# bats test_tags=dump,inspect,diff
+@test "diff checkpoints" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --leave-running --compression none
+    assert_success
+
+    sleep 1
+
+    run cedana dump job "$jid" --leave-running --compression lz4
+    assert_success
+
+    ids=$(cedana checkpoints "$jid" | grep -oE '[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}')
+    id1=$(echo "$ids" | sed -n 1p)
+    id2=$(echo "$ids" | sed -n 2p)
+
+    run cedana checkpoint diff "$id1" "$id2"
+    assert_success
+    assert_output --partial "TOTAL"
+    assert_output --partial "pages:"
+
+    run cedana checkpoint diff "$id1" "$id2" --json
+    assert_success
+    assert_output --partial '"total_pages"'
+
+    run cedana job kill "$jid"
+}
+
+# bats test_tags=dump,inspect,diff
+@test "diff checkpoints (different jobs)" {
+    jid1=$(unix_nano)
+    jid2=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid1"
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid2"
+
+    run cedana dump job "$jid1" --leave-running --compression none
+    assert_success
+    run cedana dump job "$jid2" --leave-running --compression none
+    assert_success
+
+    id1=$(cedana checkpoints "$jid1" | grep -oE '[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}' | head -n 1)
+    id2=$(cedana checkpoints "$jid2" | grep -oE '[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}' | head -n 1)
+
+    run cedana checkpoint diff "$id1" "$id2"
+    assert_failure
+    assert_output --partial "different jobs"
+
+    run cedana job kill "$jid1"
+    run cedana job kill "$jid2"
+}
+
+#####################
+### Export/Import ###
+#####################
+
+# bats test_tags=dump,oci
+@test "export checkpoint (OCI layout)" {
+    jid=$(unix_nano)
+    layout=/tmp/oci-$jid
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --compression gzip
+    assert_success
+
+    id=$(cedana checkpoints "$jid" | grep -oE '[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}' | head -n 1)
+
+    run cedana checkpoint export "$id" --oci "$layout"
+    assert_success
+    assert_output --partial "sha256:"
+
+    assert_exists "$layout/oci-layout"
+    assert_exists "$layout/index.json"
+    run cat "$layout/index.json"
+    assert_output --partial "application/vnd.cedana.checkpoint.v1"
+    assert_output --partial "$id"
+}
+
+# bats test_tags=restore,oci
+@test "import checkpoint (OCI layout)" {
+    jid=$(unix_nano)
+    jid2=$(unix_nano)
+    layout=/tmp/oci-$jid
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --compression gzip
+    assert_success
+
+    id=$(cedana checkpoints "$jid" | grep -oE '[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}' | head -n 1)
+
+    cedana checkpoint export "$id" --oci "$layout"
+
+    run cedana checkpoint import --oci "$layout" --jid "$jid2"
+    assert_success
+    assert_output --partial "Created job $jid2"
+
+    cedana restore job "$jid2"
+
+    run cedana ps
+    assert_success
+    assert_output --partial "$jid2"
+
+    run cedana job kill "$jid2"
+}
+
+# bats test_tags=restore,oci
+@test "import checkpoint (OCI layout, tampered)" {
+    jid=$(unix_nano)
+    layout=/tmp/oci-$jid
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --compression gzip
+    assert_success
+
+    id=$(cedana checkpoints "$jid" | grep -oE '[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}' | head -n 1)
+
+    cedana checkpoint export "$id" --oci "$layout"
+
+    # corrupt the largest blob, i.e. the images layer
+    blob=$(ls -S "$layout"/blobs/sha256/* | head -n 1)
+    truncate -s -1 "$blob"
+
+    run cedana checkpoint import --oci "$layout" --jid "$(unix_nano)"
+    assert_failure
+    assert_output --partial "mismatch"
+}
+
+# bats test_tags=dump,oci
+@test "export non-existent checkpoint" {
+    run cedana checkpoint export non-existent --oci /tmp/oci-non-existent
+    assert_failure
+}
+
+############################
+### Checkpoint on signal ###
+############################
+
+# bats test_tags=dump,signal
+@test "checkpoint on signal" {
+    jid=$(unix_nano)
+    dir=/tmp/signal-"$jid"
+    mkdir -p "$dir"
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid" --checkpoint-on-signal SIGTERM --dir "$dir"
+
+    run cedana job kill "$jid" --signal TERM
+    assert_success
+    assert_output --partial "Checkpointed"
+
+    [[ $(ls "$dir" | wc -l) -eq 1 ]]
+
+    run cedana checkpoints "$jid"
+    assert_success
+    assert_output --partial "$dir"
+
+    rm -rf "$dir"
+}
+
+# bats test_tags=dump,signal
+@test "checkpoint on signal (other signal)" {
+    jid=$(unix_nano)
+    dir=/tmp/signal-"$jid"
+    mkdir -p "$dir"
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid" --checkpoint-on-signal SIGTERM --dir "$dir"
+
+    run cedana job kill "$jid" --signal SIGINT
+    assert_success
+    refute_output --partial "Checkpointed"
+
+    [[ $(ls "$dir" | wc -l) -eq 0 ]]
+
+    rm -rf "$dir"
+}
+
+# bats test_tags=dump,signal
+@test "checkpoint on signal (invalid signal)" {
+    run cedana run process "$WORKLOADS/date-loop.sh" --checkpoint-on-signal SIGKILL
+    assert_failure
+}
+
+########################
+### Restart policies ###
+########################
+
+# bats test_tags=dump,restore,restart
+@test "restart job from checkpoint" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" 4 1 --jid "$jid" --restart on-failure:1
+
+    run cedana dump job "$jid" --dir /tmp --leave-running
+    assert_success
+
+    sleep 6 # exits after 4s, restored after 1s backoff
+
+    run cedana ps
+    assert_success
+    assert_output --regexp "$jid.*running"
+
+    run cedana job kill "$jid"
+}
+
+##################
+### Job events ###
+##################
+
+# bats test_tags=dump,restore,events
+@test "job events (checkpoint/restore)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+
+    run cedana dump job "$jid" --dir /tmp
+    assert_success
+
+    run cedana restore job "$jid"
+    assert_success
+
+    run cedana events "$jid"
+    assert_success
+    assert_output --partial "checkpoint-started"
+    assert_output --partial "checkpoint-completed"
+    assert_output --partial "restored"
+
+    run cedana job kill "$jid"
+}
@@ -0,0 +1,117 @@
+#!/usr/bin/env bats
+
+# This file assumes its being run from the same directory as the Makefile
+# bats file_tags=base,dedup
+
+load ../helpers/utils
+load ../helpers/daemon
+
+load_lib support
+load_lib assert
+load_lib file
+
+export CEDANA_CHECKPOINT_DEDUP=true
+
+setup_file() {
+    setup_file_daemon
+}
+
+setup() {
+    setup_daemon
+}
+
+teardown() {
+    teardown_daemon
+}
+
+teardown_file() {
+    teardown_file_daemon
+}
+
+############
+### Dump ###
+############
+
+# bats test_tags=dump
+@test "dump process (dedup)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+    dir=/tmp/dedup-$name
+    mkdir -p "$dir"
+
+    cedana dump process $pid --name "$name" --dir "$dir" --compression tar
+
+    assert_exists "$dir/$name.tar"
+    assert_exists "$dir/.chunks"
+
+    run head -c 8 "$dir/$name.tar"
+    assert_output "CEDANAD1"
+
+    run kill $pid
+}
+
+# bats test_tags=dump
+@test "dump process (dedup, repeated)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+    name2=$(unix_nano)
+    dir=/tmp/dedup-$name
+    mkdir -p "$dir"
+
+    cedana dump process $pid --name "$name" --dir "$dir" --compression tar --leave-running
+    chunks=$(find "$dir/.chunks" -maxdepth 1 -type f | wc -l)
+
+    cedana dump process $pid --name "$name2" --dir "$dir" --compression tar
+
+    chunks2=$(find "$dir/.chunks" -maxdepth 1 -type f | wc -l)
+    [ "$chunks2" -lt $((chunks * 2)) ]
+
+    run kill $pid
+}
+
+###############
+### Restore ###
+###############
+
+# bats test_tags=restore
+@test "restore process (dedup)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+    dir=/tmp/dedup-$name
+    mkdir -p "$dir"
+
+    cedana dump process $pid --name "$name" --dir "$dir" --compression tar
+
+    assert_exists "$dir/$name.tar"
+
+    cedana restore process --path "$dir/$name.tar"
+
+    run ps --pid $pid
+    assert_success
+    assert_output --partial "$pid"
+
+    run kill $pid
+}
+
+# bats test_tags=restore
+@test "restore process (dedup, missing chunk)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+    dir=/tmp/dedup-$name
+    mkdir -p "$dir"
+
+    cedana dump process $pid --name "$name" --dir "$dir" --compression tar
+
+    chunk=$(find "$dir/.chunks" -maxdepth 1 -type f | head -n 1)
+    rm "$chunk"
+
+    run cedana restore process --path "$dir/$name.tar"
+    assert_failure
+    assert_output --partial "chunk"
+
+    run kill $pid
+}
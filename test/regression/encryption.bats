@@ -0,0 +1,151 @@
+#!/usr/bin/env bats
+
+# This file assumes its being run from the same directory as the Makefile
+# bats file_tags=base,encryption
+
+load ../helpers/utils
+load ../helpers/daemon
+
+load_lib support
+load_lib assert
+load_lib file
+
+setup_file() {
+    KEY_FILE=/tmp/cedana-key-$(unix_nano)
+    head -c 32 /dev/urandom | base64 > "$KEY_FILE"
+    export KEY_FILE
+    export CEDANA_CHECKPOINT_ENCRYPTION_KEY="file://$KEY_FILE"
+
+    setup_file_daemon
+}
+
+setup() {
+    setup_daemon
+}
+
+teardown() {
+    teardown_daemon
+}
+
+teardown_file() {
+    teardown_file_daemon
+    rm -f "$KEY_FILE"
+}
+
+############
+### Dump ###
+############
+
+# bats test_tags=dump
+@test "dump process (encrypted, gzip compression)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression gzip
+
+    assert_exists "/tmp/$name.tar.gz"
+
+    run head -c 8 "/tmp/$name.tar.gz"
+    assert_output "CEDANAE1"
+
+    run tar -tzf "/tmp/$name.tar.gz"
+    assert_failure
+
+    run kill $pid
+}
+
+# bats test_tags=dump
+@test "dump process (encrypted, no compression)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression none
+
+    assert_exists "/tmp/$name"
+
+    run grep "not encrypted" "$(daemon_log_file "$SOCK")"
+    assert_success
+
+    run kill $pid
+}
+
+###############
+### Restore ###
+###############
+
+# bats test_tags=restore
+@test "restore process (encrypted, gzip compression)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression gzip
+
+    assert_exists "/tmp/$name.tar.gz"
+
+    cedana restore process --path "/tmp/$name.tar.gz"
+
+    run ps --pid $pid
+    assert_success
+    assert_output --partial "$pid"
+
+    run kill $pid
+}
+
+# bats test_tags=restore
+@test "restore process (encrypted, tar compression)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression tar
+
+    assert_exists "/tmp/$name.tar"
+
+    cedana restore process --path "/tmp/$name.tar"
+
+    run ps --pid $pid
+    assert_success
+    assert_output --partial "$pid"
+
+    run kill $pid
+}
+
+# bats test_tags=restore
+@test "restore process (encrypted, tampered)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression gzip
+
+    assert_exists "/tmp/$name.tar.gz"
+
+    printf '\xff' | dd of="/tmp/$name.tar.gz" bs=1 seek=100 conv=notrunc
+
+    run cedana restore process --path "/tmp/$name.tar.gz"
+    assert_failure
+    assert_output --partial "decrypt"
+
+    run kill $pid
+}
+
+# bats test_tags=restore
+@test "restore process (unencrypted)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    cedana dump process $pid --name "$name" --dir /tmp --compression none
+    tar -czf "/tmp/$name.tar.gz" -C "/tmp/$name" .
+
+    cedana restore process --path "/tmp/$name.tar.gz"
+
+    run ps --pid $pid
+    assert_success
+    assert_output --partial "$pid"
+
+    run kill $pid
+}
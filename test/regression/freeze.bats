@@ -0,0 +1,113 @@
+#!/usr/bin/env bats
+
+# This file assumes its being run from the same directory as the Makefile
+# bats file_tags=base,freeze
+
+load ../helpers/utils
+load ../helpers/daemon
+
+load_lib support
+load_lib assert
+load_lib file
+
+setup_file() {
+    setup_file_daemon
+}
+
+setup() {
+    setup_daemon
+}
+
+teardown() {
+    teardown_daemon
+}
+
+teardown_file() {
+    teardown_file_daemon
+}
+
+##############
+### Freeze ###
+##############
+
+# bats test_tags=freeze
+@test "freeze process" {
+    log=/tmp/freeze-$(unix_nano).log
+
+    "$WORKLOADS"/date-loop.sh &> "$log" < /dev/null &
+    pid=$!
+    sleep 2
+
+    run cedana freeze process $pid
+    assert_success
+
+    # the process must not make progress while frozen
+    lines=$(wc -l < "$log")
+    sleep 3
+    assert_equal "$(wc -l < "$log")" "$lines"
+
+    run cedana unfreeze process $pid
+    assert_success
+
+    sleep 3
+    [ "$(wc -l < "$log")" -gt "$lines" ]
+
+    run kill $pid
+}
+
+# bats test_tags=freeze
+@test "freeze process (shared cgroup)" {
+    log=/tmp/freeze-$(unix_nano).log
+
+    # another process in the same cgroup must not be frozen
+    "$WORKLOADS"/date-loop.sh &> "$log" < /dev/null &
+    pid=$!
+    "$WORKLOADS"/date-loop.sh &> "$log.other" < /dev/null &
+    pid2=$!
+    sleep 2
+
+    run cedana freeze process $pid
+    assert_success
+
+    lines=$(wc -l < "$log.other")
+    sleep 3
+    [ "$(wc -l < "$log.other")" -gt "$lines" ]
+
+    run cedana unfreeze process $pid
+    assert_success
+
+    # must be back in its original cgroup
+    assert_equal "$(cat /proc/$pid/cgroup)" "$(cat /proc/$pid2/cgroup)"
+
+    run kill $pid $pid2
+}
+
+# bats test_tags=freeze
+@test "freeze job" {
+    jid=$(unix_nano)
+    log=/var/log/cedana-output-$jid.log
+
+    cedana run process "$WORKLOADS/date-loop.sh" --jid "$jid"
+    sleep 2
+
+    run cedana freeze job "$jid"
+    assert_success
+
+    lines=$(wc -l < "$log")
+    sleep 3
+    assert_equal "$(wc -l < "$log")" "$lines"
+
+    run cedana unfreeze job "$jid"
+    assert_success
+
+    sleep 3
+    [ "$(wc -l < "$log")" -gt "$lines" ]
+
+    run cedana job kill "$jid"
+}
+
+# bats test_tags=freeze
+@test "freeze non-existent process" {
+    run cedana freeze process 999999999
+    assert_failure
+}
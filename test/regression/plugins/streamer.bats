@@ -176,6 +176,21 @@ teardown_file() {
     run kill $pid
 }
 
+# bats test_tags=dump
+@test "stream dump process (zstd compression)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    run cedana dump process $pid --name "$name" --dir /tmp --streams 2 --compression zstd
+    assert_success
+    assert_exists "/tmp/$name"
+    assert_exists "/tmp/$name/img-0.zst"
+    assert_exists "/tmp/$name/img-1.zst"
+
+    run kill $pid
+}
+
 # bats test_tags=dump
 @test "stream dump process (invalid compression)" {
     "$WORKLOADS"/date-loop.sh &
@@ -411,3 +426,21 @@ teardown_file() {
 
     run kill $pid
 }
+
+# bats test_tags=restore
+@test "stream restore process (zstd compression)" {
+    "$WORKLOADS"/date-loop.sh &
+    pid=$!
+    name=$(unix_nano)
+
+    run cedana dump process $pid --name "$name" --dir /tmp --streams 2 --compression zstd
+    assert_success
+    dump_file="/tmp/$name"
+    assert_exists "$dump_file"
+    assert_exists "$dump_file/img-0.zst"
+    assert_exists "$dump_file/img-1.zst"
+
+    cedana restore process --path "$dump_file"
+
+    run kill $pid
+}
@@ -97,6 +97,139 @@ teardown_file() {
     assert_file_contains "$log_file" "hello"
 }
 
+@test "run process (resource limits)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS"/date-loop.sh --jid "$jid" --cpus 0.5 --memory 64M --pids-limit 32 --io-weight 50
+
+    cgroup="/sys/fs/cgroup/cedana/$jid"
+    pid=$(pid_for_jid "$jid")
+
+    assert_file_contains "/proc/$pid/cgroup" "/cedana/$jid"
+    assert_equal "$(cat "$cgroup/cpu.max")" "50000 100000"
+    assert_equal "$(cat "$cgroup/memory.max")" "$((64 * 1024 * 1024))"
+    assert_equal "$(cat "$cgroup/pids.max")" "32"
+
+    run cedana job kill "$jid"
+}
+
+@test "run process (resource limits, invalid)" {
+    jid=$(unix_nano)
+
+    run cedana run process "$WORKLOADS"/date-loop.sh --jid "$jid" --memory 64X
+    assert_failure
+}
+
+# bats test_tags=restart
+@test "run process (restart on failure)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS"/date-loop.sh 2 1 --jid "$jid" --restart on-failure:1
+
+    pid=$(pid_for_jid "$jid")
+
+    sleep 4 # exits after 2s, restarted after 1s backoff
+
+    run cedana ps
+    assert_success
+    assert_output --regexp "$jid.*running"
+    refute_equal "$(pid_for_jid "$jid")" "$pid"
+
+    sleep 4 # exits again, but reached max restarts
+
+    run cedana ps
+    assert_success
+    refute_output --regexp "$jid.*running"
+}
+
+# bats test_tags=restart
+@test "run process (restart on failure, success)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS"/date-loop.sh 1 0 --jid "$jid" --restart on-failure
+
+    sleep 3
+
+    run cedana ps
+    assert_success
+    refute_output --regexp "$jid.*running"
+}
+
+# bats test_tags=restart
+@test "run process (restart always, killed)" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS"/date-loop.sh --jid "$jid" --restart always
+
+    run cedana job kill "$jid"
+    assert_success
+
+    sleep 3 # killed through the daemon, so not restarted
+
+    run cedana ps
+    assert_success
+    refute_output --regexp "$jid.*running"
+}
+
+# bats test_tags=restart
+@test "run process (restart, invalid)" {
+    run cedana run process "$WORKLOADS"/date-loop.sh --restart always:3
+    assert_failure
+
+    run cedana run process "$WORKLOADS"/date-loop.sh --restart sometimes
+    assert_failure
+}
+
+# bats test_tags=events
+@test "job events" {
+    jid=$(unix_nano)
+
+    cedana run process "$WORKLOADS"/date-loop.sh 1 3 --jid "$jid"
+
+    sleep 2
+
+    run cedana events "$jid"
+    assert_success
+    assert_output --partial "created"
+    assert_output --partial "started"
+    assert_output --regexp "exited.*code 3"
+
+    run cedana events "$jid" --type exited --json
+    assert_success
+    assert_output --partial "$jid"
+    refute_output --partial "started"
+}
+
+# bats test_tags=events
+@test "job events (follow)" {
+    jid=$(unix_nano)
+    events_file="/tmp/events-$jid.json"
+
+    cedana events "$jid" --follow --json > "$events_file" &
+    follow_pid=$!
+
+    cedana run process "$WORKLOADS"/date-loop.sh --jid "$jid"
+
+    run cedana job kill "$jid"
+    assert_success
+
+    sleep 1
+
+    kill "$follow_pid"
+
+    assert_file_contains "$events_file" "created"
+    assert_file_contains "$events_file" "started"
+    assert_file_contains "$events_file" "exited"
+
+    rm -f "$events_file"
+}
+
+# bats test_tags=events
+@test "job events (invalid type)" {
+    run cedana events --type sometimes
+    assert_failure
+}
+
 # bats test_tags=attach
 @test "attach" {
     jid=$(unix_nano)
@@ -138,3 +271,72 @@ teardown_file() {
     run cedana job attach "$jid"
     assert_equal $status $code
 }
+
+# bats test_tags=attach
+@test "attach (read-only)" {
+    jid=$(unix_nano)
+    code=42
+
+    cedana run process "$WORKLOADS"/date-loop.sh 5 "$code" --jid "$jid" --attachable
+
+    cedana job attach "$jid" < /dev/null > /dev/null &
+    sleep 1
+
+    run cedana job attach "$jid"
+    assert_failure
+    assert_output --partial "read-only"
+
+    run cedana job attach "$jid" --read-only
+    assert_equal $status $code
+    assert_output --partial "$code"
+
+    wait
+}
+
+# bats test_tags=attach
+@test "attach (scrollback)" {
+    jid=$(unix_nano)
+    code=42
+
+    cedana run process "$WORKLOADS"/date-loop.sh 5 "$code" --jid "$jid" --attachable
+
+    sleep 3
+
+    run cedana job attach "$jid"
+    assert_equal $status $code
+    assert_equal "$(echo "$output" | wc -l)" 6 # all dates and the code
+}
+
+# bats test_tags=attach
+@test "attach (scrollback, tail)" {
+    jid=$(unix_nano)
+    code=42
+
+    cedana run process "$WORKLOADS"/date-loop.sh 5 "$code" --jid "$jid" --attachable
+
+    sleep 3
+
+    run cedana job attach "$jid" --tail=-1
+    assert_equal $status $code
+    assert [ "$(echo "$output" | wc -l)" -lt 6 ]
+}
+
+# bats test_tags=attach,tty
+@test "attach (tty)" {
+    jid=$(unix_nano)
+
+    run cedana run process tty --jid "$jid" --tty --attach
+    assert_success
+    assert_output --partial "/dev/pts/"
+}
+
+# bats test_tags=attach,tty
+@test "attach (tty, exit code)" {
+    jid=$(unix_nano)
+    code=42
+
+    cedana run process "$WORKLOADS"/date-loop.sh 3 "$code" --jid "$jid" --tty
+
+    run cedana job attach "$jid"
+    assert_equal $status $code
+}